@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/puregrade-group/sso/internal/app"
 	"github.com/puregrade-group/sso/internal/config"
@@ -15,26 +18,90 @@ const (
 	envLocal = "local"
 	envDev   = "dev"
 	envProd  = "prod"
+
+	shutdownTimeout = 10 * time.Second
 )
 
 func main() {
+	// Parsed by config.MustLoad together with the config path
+	migrateOnStart := flag.Bool("migrate-on-start", false, "apply migrations to the storage before start")
+	rotateSigningKey := flag.Bool(
+		"rotate-signing-key", false,
+		"generate the new signing key, which starts signing after jwt.promotion_delay, and exit",
+	)
+	grantAdmin := flag.Uint64("grant-admin", 0, "grant the admin role to the user with the id and exit")
+
 	cfg := config.MustLoad()
 
 	log := setupLogger(cfg.Env)
 
 	log.Info("starting application", slog.Any("config", cfg))
 
+	if *migrateOnStart {
+		app.MustMigrate(
+			log,
+			cfg.Storage.Driver, cfg.Storage.Path,
+			cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.Database,
+			cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.SSLMode,
+		)
+	}
+
+	if *rotateSigningKey {
+		app.MustRotateSigningKey(
+			log,
+			cfg.Storage.Driver, cfg.Storage.Path,
+			cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.Database,
+			cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.SSLMode,
+			cfg.JWT.Algorithm, cfg.JWT.MasterKey,
+			cfg.JWT.PromotionDelay,
+			cfg.AccessTokenTTL,
+		)
+
+		return
+	}
+
+	if *grantAdmin != 0 {
+		app.MustGrantAdmin(
+			log,
+			cfg.Storage.Driver, cfg.Storage.Path,
+			cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.Database,
+			cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.SSLMode,
+			*grantAdmin,
+		)
+
+		return
+	}
+
 	application := app.New(
 		log,
+		cfg.Storage.Driver, cfg.Storage.Path,
 		cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.Database,
 		cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.SSLMode,
 		cfg.GRPC.Host, cfg.GRPC.Port,
+		cfg.HTTP.Host, cfg.HTTP.Port,
+		cfg.JWT.Algorithm, cfg.JWT.KeyId, cfg.JWT.PrivateKeyPath, cfg.JWT.MasterKey,
+		cfg.JWT.RotationPeriod, cfg.JWT.PromotionDelay, cfg.JWT.RefreshInterval,
+		cfg.JWT.Issuer, cfg.JWT.Audience, cfg.JWT.Leeway,
 		cfg.App.NodeID,
 		cfg.AccessTokenTTL, []byte(cfg.AccessTokenSecret),
 		cfg.RefreshTokenTTL, cfg.RefreshTokenLength,
+		cfg.RefreshTokenReuseGrace,
+		cfg.RevocationRefreshInterval,
+		cfg.AuthorizationCodeTTL,
+		cfg.DeviceCodeTTL, cfg.DeviceCodeInterval,
+		cfg.DeviceAttemptsPerIP,
+		cfg.Notifier.Driver, cfg.Notifier.Path,
+		cfg.PasswordResetTTL,
 	)
 
 	go application.GRPCServer.MustRun()
+	go application.HTTPServer.MustRun()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go application.KeyRing.Run(ctx)
+	go application.Denylist.Run(ctx)
 
 	// Graceful shutdown
 
@@ -47,6 +114,18 @@ func main() {
 
 	application.GRPCServer.Stop()
 
+	cancel()
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer stopCancel()
+
+	if err := application.HTTPServer.Stop(stopCtx); err != nil {
+		log.Error(err.Error())
+	}
+
+	// The password reset tokens requested before the stop are still sent
+	application.Auth.WaitPasswordResets()
+
 	log.Info("gracefully stopped")
 }
 
@@ -1,60 +1,449 @@
 package app
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
 	"log/slog"
+	"os"
 	"time"
 
 	grpcapp "github.com/puregrade-group/sso/internal/app/grpc"
+	httpapp "github.com/puregrade-group/sso/internal/app/http"
+	"github.com/puregrade-group/sso/internal/migrator"
+	"github.com/puregrade-group/sso/internal/notifier"
+	"github.com/puregrade-group/sso/internal/service/acs"
 	"github.com/puregrade-group/sso/internal/service/auth"
+	"github.com/puregrade-group/sso/internal/service/keyring"
+	"github.com/puregrade-group/sso/internal/service/profile"
+	"github.com/puregrade-group/sso/internal/service/revocation"
+	"github.com/puregrade-group/sso/internal/storage/memory"
 	"github.com/puregrade-group/sso/internal/storage/postgres"
+	"github.com/puregrade-group/sso/internal/storage/sqlite"
+	"github.com/puregrade-group/sso/internal/transport/http/oidc"
+	"github.com/puregrade-group/sso/migrations"
+	"github.com/puregrade-group/sso/pkg/jwt"
 	"github.com/puregrade-group/sso/pkg/snowflake"
 )
 
+const (
+	driverPostgres = "postgres"
+	driverSQLite   = "sqlite"
+	driverMemory   = "memory"
+
+	notifierLog    = "log"
+	notifierOutbox = "outbox"
+)
+
 type App struct {
 	GRPCServer *grpcapp.App
+	HTTPServer *httpapp.App
+	KeyRing    *keyring.KeyRing
+	Denylist   *revocation.Denylist
+	Auth       *auth.Auth
+
+	storage dataStorage
+}
+
+// dataStorage interface must be implemented by every storage driver
+type dataStorage interface {
+	auth.UserSaver
+	auth.UserProvider
+	auth.RefreshTokenProvider
+	acs.PermissionsSaver
+	acs.PermissionsProvider
+	acs.PermissionRemover
+	acs.RoleSaver
+	acs.RoleProvider
+	acs.RoleRemover
+	acs.AppSaver
+	acs.AppProvider
+	acs.AppRemover
+	profile.Provider
+	auth.SecurityEventSaver
+	auth.AppProvider
+	adminGranter
+	auth.RoleProvider
+	auth.AuthorizationCodeProvider
+	auth.DeviceCodeProvider
+	auth.GrantProvider
+	auth.PasswordResetTokenProvider
+	keyring.SigningKeySaver
+	keyring.SigningKeyProvider
+	revocation.RevocationSaver
+	revocation.RevocationProvider
+}
+
+// adminGranter interface must be implemented by every storage driver
+type adminGranter interface {
+	// GrantAdminRole assigns the admin role created by the migrations to the user.
+	GrantAdminRole(ctx context.Context,
+		userId uint64,
+	) (err error)
 }
 
 func New(
 	log *slog.Logger,
+	// Storage configuration
+	storageDriver string, storagePath string,
 	// Postgres configuration
 	postgresHost string, postgresPort uint16, postgresDatabase,
 	postgresUser, postgresPassword, postgresSSLMode string,
 	// GRPC server configuration
 	grpcHost string, grpcPort uint16,
+	// HTTP server configuration
+	httpHost string, httpPort uint16,
+	// Key ring configuration
+	jwtAlgorithm, jwtKeyId, jwtPrivateKeyPath, jwtMasterKey string,
+	jwtRotationPeriod, jwtPromotionDelay, jwtRefreshInterval time.Duration,
+	jwtIssuer string, jwtAudience []string, jwtLeeway time.Duration,
 	// AuthService configuration
 	nodeID uint16,
 	accessTokenTTL time.Duration, accessTokenSecret []byte,
 	refreshTokenTTL time.Duration, refreshTokenLength uint,
+	refreshTokenReuseGrace time.Duration,
+	revocationRefreshInterval time.Duration,
+	authorizationCodeTTL time.Duration,
+	deviceCodeTTL, deviceCodeInterval time.Duration,
+	deviceAttemptsPerIP uint,
+	// Notifier configuration
+	notifierDriver, notifierPath string,
+	passwordResetTTL time.Duration,
 ) *App {
-	storage, err := postgres.New(
-		postgres.Config{
-			Host:     postgresHost,
-			Port:     postgresPort,
-			Database: postgresDatabase,
-			User:     postgresUser,
-			Password: postgresPassword,
-			SSLMode:  postgresSSLMode,
-		},
+	storage := mustStorage(
+		storageDriver, storagePath,
+		postgresHost, postgresPort, postgresDatabase,
+		postgresUser, postgresPassword, postgresSSLMode,
 	)
+
+	sf, err := snowflake.NewSnowflake(nodeID)
 	if err != nil {
 		panic(err)
 	}
 
-	sf, err := snowflake.NewSnowflake(nodeID)
+	keyRing := keyring.New(
+		log,
+		storage, storage,
+		jwtAlgorithm, mustMasterKey(jwtMasterKey),
+		accessTokenTTL,
+		jwtRotationPeriod, jwtPromotionDelay, jwtRefreshInterval,
+	)
+
+	err = keyRing.Bootstrap(
+		context.Background(),
+		initialSigningKey(log, jwtAlgorithm, jwtKeyId, jwtPrivateKeyPath, accessTokenSecret),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	denylist := revocation.New(log, storage, storage, revocationRefreshInterval)
+
+	err = denylist.Load(context.Background())
 	if err != nil {
 		panic(err)
 	}
 
 	authService := auth.New(
 		log, sf,
-		storage, storage, storage,
-		accessTokenTTL, accessTokenSecret,
+		storage, storage, storage, storage, storage, storage, storage, storage, storage, storage,
+		keyRing, denylist,
+		mustNotifier(log, notifierDriver, notifierPath),
+		accessTokenTTL,
+		jwtIssuer, jwtAudience, jwtLeeway,
 		refreshTokenTTL, refreshTokenLength,
+		refreshTokenReuseGrace,
+		authorizationCodeTTL,
+		deviceCodeTTL, deviceCodeInterval,
+		deviceAttemptsPerIP,
+		passwordResetTTL,
+		jwt.Asymmetric(jwtAlgorithm),
+	)
+
+	acsService := acs.New(
+		log,
+		storage, storage, storage,
+		storage, storage, storage,
+		storage, storage, storage,
+		keyRing, denylist,
+		jwtIssuer, jwtAudience, jwtLeeway,
 	)
 
-	grpcApp := grpcapp.New(log, authService, grpcPort, grpcHost)
+	profileService := profile.New(log, sf, storage, acsService)
+
+	grpcApp := grpcapp.New(log, authService, acsService, profileService, grpcPort, grpcHost)
+
+	httpApp := httpapp.New(
+		log, keyRing, authService, authService, authService,
+		oidc.Provider{Issuer: jwtIssuer, SigningAlgorithm: jwtAlgorithm},
+		httpPort, httpHost,
+	)
 
 	return &App{
 		GRPCServer: grpcApp,
+		HTTPServer: httpApp,
+		KeyRing:    keyRing,
+		Denylist:   denylist,
+		Auth:       authService,
+		storage:    storage,
+	}
+}
+
+// GrantAdminRole grants the admin role to the existing user.
+// The in-process instances of the memory storage, e.g. of the tests, bootstrap the admins with it.
+func (a *App) GrantAdminRole(ctx context.Context, userId uint64) error {
+	return grantAdminRole(ctx, a.storage, userId)
+}
+
+// MustGrantAdmin grants the admin role to the existing user and panics if any error occurs.
+// The operators bootstrap the first admins with it, the admins grant the roles to the others through ACS.
+func MustGrantAdmin(
+	log *slog.Logger,
+	// Storage configuration
+	storageDriver string, storagePath string,
+	// Postgres configuration
+	postgresHost string, postgresPort uint16, postgresDatabase,
+	postgresUser, postgresPassword, postgresSSLMode string,
+	userId uint64,
+) {
+	const op = "app.MustGrantAdmin"
+
+	log = log.With(slog.String("op", op))
+
+	if storageDriver == driverMemory {
+		panic("the admins of the memory storage can not be granted by another process")
+	}
+
+	storage := mustStorage(
+		storageDriver, storagePath,
+		postgresHost, postgresPort, postgresDatabase,
+		postgresUser, postgresPassword, postgresSSLMode,
+	)
+
+	if err := grantAdminRole(context.Background(), storage, userId); err != nil {
+		panic(err)
+	}
+
+	log.Info("admin role is granted", slog.Uint64("userId", userId))
+}
+
+// grantAdminRole grants the admin role to the user if the user exists.
+func grantAdminRole(ctx context.Context, storage dataStorage, userId uint64) error {
+	exists, err := storage.UserExists(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("user %d does not exist", userId)
+	}
+
+	return storage.GrantAdminRole(ctx, userId)
+}
+
+// MustRotateSigningKey generates the new key of the key ring and panics if any error occurs.
+// The running instances load the key on their next refresh and it starts signing after the promotion delay.
+func MustRotateSigningKey(
+	log *slog.Logger,
+	// Storage configuration
+	storageDriver string, storagePath string,
+	// Postgres configuration
+	postgresHost string, postgresPort uint16, postgresDatabase,
+	postgresUser, postgresPassword, postgresSSLMode string,
+	// Key ring configuration
+	jwtAlgorithm, jwtMasterKey string,
+	jwtPromotionDelay time.Duration,
+	accessTokenTTL time.Duration,
+) {
+	const op = "app.MustRotateSigningKey"
+
+	log = log.With(slog.String("op", op))
+
+	if storageDriver == driverMemory {
+		panic("the keys of the memory storage can not be rotated by another process")
+	}
+
+	storage := mustStorage(
+		storageDriver, storagePath,
+		postgresHost, postgresPort, postgresDatabase,
+		postgresUser, postgresPassword, postgresSSLMode,
+	)
+
+	keyRing := keyring.New(
+		log,
+		storage, storage,
+		jwtAlgorithm, mustMasterKey(jwtMasterKey),
+		accessTokenTTL,
+		0, jwtPromotionDelay, 0,
+	)
+
+	kid, err := keyRing.Rotate(context.Background())
+	if err != nil {
+		panic(err)
 	}
+
+	log.Info("signing key is rotated", slog.String("kid", kid))
+}
+
+// mustStorage connects to the storage selected by the driver and panics if any error occurs.
+func mustStorage(
+	// Storage configuration
+	storageDriver string, storagePath string,
+	// Postgres configuration
+	postgresHost string, postgresPort uint16, postgresDatabase,
+	postgresUser, postgresPassword, postgresSSLMode string,
+) dataStorage {
+	var (
+		storage dataStorage
+		err     error
+	)
+
+	switch storageDriver {
+	case driverPostgres:
+		storage, err = postgres.New(
+			postgres.Config{
+				Host:     postgresHost,
+				Port:     postgresPort,
+				Database: postgresDatabase,
+				User:     postgresUser,
+				Password: postgresPassword,
+				SSLMode:  postgresSSLMode,
+			},
+		)
+	case driverSQLite:
+		storage, err = sqlite.New(storagePath)
+	case driverMemory:
+		storage = memory.New()
+	default:
+		err = fmt.Errorf("unknown storage driver: %q", storageDriver)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return storage
+}
+
+// mustNotifier creates the notifier selected by the driver and panics if any error occurs.
+func mustNotifier(log *slog.Logger, notifierDriver, notifierPath string) auth.Notifier {
+	switch notifierDriver {
+	case notifierLog:
+		log.Warn("notifications are written to the log, use the outbox beyond local development")
+
+		return notifier.NewLog(log)
+	case notifierOutbox:
+		outbox, err := notifier.NewOutbox(notifierPath)
+		if err != nil {
+			panic(err)
+		}
+
+		return outbox
+	default:
+		panic(fmt.Errorf("unknown notifier driver: %q", notifierDriver))
+	}
+}
+
+// mustMasterKey decodes the base64 encoded master key of the key ring and panics if it is malformed.
+// The empty key means that the private keys are stored unencrypted.
+func mustMasterKey(masterKey string) []byte {
+	if masterKey == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		panic(fmt.Errorf("master key is not base64 encoded: %w", err))
+	}
+
+	if len(key) != keyring.MasterKeyLen {
+		panic(fmt.Errorf("master key must be %d bytes long", keyring.MasterKeyLen))
+	}
+
+	return key
+}
+
+// initialSigningKey returns the function that loads the initial key of the key ring.
+// HS256 uses the shared secret, the asymmetric algorithms use the private key from the PEM file.
+func initialSigningKey(
+	log *slog.Logger,
+	algorithm, keyId, privateKeyPath string,
+	secret []byte,
+) func() (*jwt.Key, error) {
+	const op = "app.initialSigningKey"
+
+	log = log.With(
+		slog.String("op", op),
+		slog.String("algorithm", algorithm),
+		slog.String("kid", keyId),
+	)
+
+	return func() (*jwt.Key, error) {
+		switch {
+		case algorithm == jwt.AlgHS256:
+			return jwt.NewKey(keyId, algorithm, secret)
+		case privateKeyPath == "":
+			log.Warn("private key is not configured, the generated one is used")
+
+			return jwt.GenerateKey(keyId, algorithm)
+		default:
+			data, err := os.ReadFile(privateKeyPath)
+			if err != nil {
+				return nil, err
+			}
+
+			return jwt.NewKey(keyId, algorithm, data)
+		}
+	}
+}
+
+// MustMigrate applies the migrations embedded in the binary to the configured storage
+// and panics if any error occurs. Storages without a schema are skipped.
+func MustMigrate(
+	log *slog.Logger,
+	// Storage configuration
+	storageDriver string, storagePath string,
+	// Postgres configuration
+	postgresHost string, postgresPort uint16, postgresDatabase,
+	postgresUser, postgresPassword, postgresSSLMode string,
+) {
+	const op = "app.MustMigrate"
+
+	log = log.With(
+		slog.String("op", op),
+		slog.String("driver", storageDriver),
+	)
+
+	var databaseURL string
+
+	switch storageDriver {
+	case driverPostgres:
+		databaseURL = migrator.PostgresURL(
+			postgresHost, postgresPort, postgresDatabase,
+			postgresUser, postgresPassword, postgresSSLMode,
+		)
+	case driverSQLite:
+		databaseURL = migrator.SQLiteURL(storagePath)
+	default:
+		log.Info("storage has no migrations")
+
+		return
+	}
+
+	m, err := migrator.NewFromFS(migrations.FS, storageDriver, databaseURL, migrator.DefaultTable)
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = m.Close() }()
+
+	applied, err := m.Up()
+	if err != nil {
+		panic(err)
+	}
+
+	version, _, err := m.Version()
+	if err != nil {
+		panic(err)
+	}
+
+	log.Info("storage is migrated", slog.Bool("applied", applied), slog.Uint64("version", uint64(version)))
 }
@@ -6,7 +6,9 @@ import (
 	"net"
 	"strconv"
 
+	"github.com/puregrade-group/sso/internal/transport/grpc/acs"
 	"github.com/puregrade-group/sso/internal/transport/grpc/auth"
+	"github.com/puregrade-group/sso/internal/transport/grpc/profile"
 	"google.golang.org/grpc"
 )
 
@@ -21,12 +23,16 @@ type App struct {
 func New(
 	log *slog.Logger,
 	authService auth.Auth,
+	acsService acs.ACS,
+	profileService profile.Profile,
 	port uint16,
 	host string,
 ) *App {
 	gRPCServer := grpc.NewServer()
 
 	auth.Register(gRPCServer, authService)
+	acs.Register(gRPCServer, acsService)
+	profile.Register(gRPCServer, profileService)
 
 	return &App{
 		log:        log,
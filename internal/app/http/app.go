@@ -2,11 +2,18 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/puregrade-group/sso/internal/transport/http/introspect"
+	"github.com/puregrade-group/sso/internal/transport/http/jwks"
+	"github.com/puregrade-group/sso/internal/transport/http/oauth"
+	"github.com/puregrade-group/sso/internal/transport/http/oidc"
 )
 
 type App struct {
@@ -14,12 +21,29 @@ type App struct {
 	srv *http.Server
 }
 
-func New(host, port string, log *slog.Logger) *App {
+// New creates new http server app.
+func New(
+	log *slog.Logger,
+	keys jwks.KeySet,
+	introspector introspect.Introspector,
+	oauthService oauth.OAuth,
+	userInfoProvider oidc.UserInfoProvider,
+	provider oidc.Provider,
+	port uint16,
+	host string,
+) *App {
+	mux := http.NewServeMux()
+
+	jwks.Register(mux, log, keys)
+	introspect.Register(mux, log, introspector)
+	oauth.Register(mux, log, oauthService, provider.Issuer)
+	oidc.Register(mux, log, userInfoProvider, provider)
+
 	return &App{
 		log: log,
 		srv: &http.Server{
-			Addr:           net.JoinHostPort(host, port),
-			Handler:        nil,
+			Addr:           net.JoinHostPort(host, strconv.Itoa(int(port))),
+			Handler:        mux,
 			MaxHeaderBytes: 1 << 20,
 			ReadTimeout:    time.Second * 5,
 			WriteTimeout:   time.Second * 5,
@@ -27,6 +51,7 @@ func New(host, port string, log *slog.Logger) *App {
 	}
 }
 
+// MustRun runs http server and panics if any error occurs.
 func (a *App) MustRun() {
 	if err := a.Run(); err != nil {
 		panic(err)
@@ -44,8 +69,11 @@ func (a *App) Run() error {
 	log.Info("http server is running")
 
 	err := a.srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	return fmt.Errorf("%s: %w", op, err)
+	return nil
 }
 
 // Stop stops http server
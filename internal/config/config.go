@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"log/slog"
 	"os"
 	"time"
 
@@ -15,12 +16,31 @@ type (
 
 		App      AppConfig      `yaml:"app"`
 		GRPC     GRPCConfig     `yaml:"grpc"`
+		HTTP     HTTPConfig     `yaml:"http"`
+		JWT      JWTConfig      `yaml:"jwt"`
+		Storage  StorageConfig  `yaml:"storage"`
 		Postgres PostgresConfig `yaml:"postgres"`
+		Notifier NotifierConfig `yaml:"notifier"`
 
 		AccessTokenTTL     time.Duration `yaml:"access_token_ttl" env-default:"1h"`
 		AccessTokenSecret  string        `yaml:"access_token_secret"`
 		RefreshTokenTTL    time.Duration `yaml:"refresh_token_ttl"`
 		RefreshTokenLength uint          `yaml:"refresh_token_length"`
+		// Time after the rotation during which the same client may present the old refresh token
+		// without the session being revoked, e.g. when it sent two refresh requests concurrently.
+		RefreshTokenReuseGrace time.Duration `yaml:"refresh_token_reuse_grace" env-default:"10s"`
+		// How often the access tokens revoked by other instances are loaded from the storage.
+		RevocationRefreshInterval time.Duration `yaml:"revocation_refresh_interval" env-default:"5s"`
+		// Lifetime of the codes issued by the authorization endpoint, RFC 6749 recommends at most 10 minutes.
+		AuthorizationCodeTTL time.Duration `yaml:"authorization_code_ttl" env-default:"1m"`
+		// Lifetime of the device codes, the user has this long to approve the device.
+		DeviceCodeTTL time.Duration `yaml:"device_code_ttl" env-default:"10m"`
+		// Minimal interval between the polls of the token endpoint by the device, it is rounded down to seconds.
+		DeviceCodeInterval time.Duration `yaml:"device_code_interval" env-default:"5s"`
+		// Failed sign-ins on the device page allowed from the IP address within the lifetime of the device codes.
+		DeviceAttemptsPerIP uint `yaml:"device_attempts_per_ip" env-default:"20"`
+		// Lifetime of the password reset tokens, the user has this long to set the new password.
+		PasswordResetTTL time.Duration `yaml:"password_reset_ttl" env-default:"30m"`
 	}
 
 	// AppConfig -.
@@ -37,6 +57,53 @@ type (
 		Timeout time.Duration `yaml:"timeout" env-default:"5s"`
 	}
 
+	// HTTPConfig -.
+	HTTPConfig struct {
+		Host string `yaml:"host" env-default:""`
+		Port uint16 `yaml:"port" env-default:"8080"`
+	}
+
+	// JWTConfig -.
+	JWTConfig struct {
+		Algorithm string `yaml:"algorithm" env-default:"HS256"` // of the new keys: "HS256" | "RS256" | "ES256" | "EdDSA"
+		KeyId     string `yaml:"key_id" env-default:"default"`  // "kid" of the initial key
+		// The initial key of the key ring, it is used only if the storage has no keys yet.
+		// PEM encoded private key of the asymmetric algorithms, HS256 uses access_token_secret.
+		// If it is empty, the key is generated.
+		PrivateKeyPath string `yaml:"private_key_path"`
+		// Base64 encoded 32 bytes AES key the private keys are encrypted with in the storage.
+		// If it is empty, the keys are stored as is.
+		MasterKey string `yaml:"master_key" env:"JWT_MASTER_KEY"`
+		// New key is generated every rotation period, 0 disables the scheduled rotation.
+		RotationPeriod time.Duration `yaml:"rotation_period"`
+		// The rotated key is published in JWKS this long before it starts signing,
+		// it must exceed both the refresh interval and the JWKS cache lifetime of the resource servers.
+		PromotionDelay time.Duration `yaml:"promotion_delay" env-default:"10m"`
+		// How often the keys rotated by other instances are loaded from the storage.
+		RefreshInterval time.Duration `yaml:"refresh_interval" env-default:"1m"`
+		// "iss" claim of the tokens, usually the URL of the service.
+		Issuer string `yaml:"issuer" env-default:"sso"`
+		// "aud" claim of the tokens issued without an app, the service accepts the tokens of this audience.
+		// The tokens issued for an app are intended for the app.
+		Audience []string `yaml:"audience" env-default:"sso"`
+		// Clock skew allowed when "exp", "nbf" and "iat" of the tokens are checked.
+		Leeway time.Duration `yaml:"leeway" env-default:"30s"`
+	}
+
+	// StorageConfig -.
+	StorageConfig struct {
+		Driver string `yaml:"driver" env-default:"postgres"`       // "postgres" | "sqlite" | "memory"
+		Path   string `yaml:"path" env-default:"./storage/sso.db"` // SQLite database file
+	}
+
+	// NotifierConfig -.
+	NotifierConfig struct {
+		// "outbox" appends the messages to the file as JSON lines for the delivery service.
+		// "log" writes them to the log, it is allowed only in the local env, since the tokens leak to the log.
+		Driver string `yaml:"driver" env-default:"outbox"`
+		Path   string `yaml:"path" env-default:"./storage/outbox.jsonl"` // outbox file
+	}
+
 	PostgresConfig struct {
 		Host     string
 		Port     uint16
@@ -47,6 +114,35 @@ type (
 	}
 )
 
+// redacted replaces the secrets of the config when it is logged.
+const redacted = "[REDACTED]"
+
+const (
+	envLocal = "local"
+	// notifierLog is the notifier driver that writes the tokens to the log
+	notifierLog = "log"
+)
+
+// LogValue hides the secrets, so the config can be logged on start.
+func (c Config) LogValue() slog.Value {
+	// The type has no methods, so the value is not resolved again
+	type config Config
+
+	if c.AccessTokenSecret != "" {
+		c.AccessTokenSecret = redacted
+	}
+
+	if c.JWT.MasterKey != "" {
+		c.JWT.MasterKey = redacted
+	}
+
+	if c.Postgres.Password != "" {
+		c.Postgres.Password = redacted
+	}
+
+	return slog.AnyValue(config(c))
+}
+
 func MustLoad() *Config {
 	configPath := fetchConfigPath()
 	if configPath == "" {
@@ -68,6 +164,10 @@ func MustLoadPath(configPath string) *Config {
 		panic("cannot read config: " + err.Error())
 	}
 
+	if cfg.Notifier.Driver == notifierLog && cfg.Env != envLocal {
+		panic("notifier driver \"log\" is allowed only in the \"local\" env, use \"outbox\"")
+	}
+
 	return &cfg
 }
 
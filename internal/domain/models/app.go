@@ -1,8 +1,52 @@
 package models
 
+import "time"
+
+// Grant types the app may be allowed to obtain the tokens with.
+const (
+	GrantPassword          = "password"           // Auth.Login with the credentials of the user
+	GrantRefreshToken      = "refresh_token"      // Auth.Refresh and the token endpoint
+	GrantAuthorizationCode = "authorization_code" // the authorization endpoint with PKCE
+	GrantClientCredentials = "client_credentials" // the token endpoint with the secret of the app, no user
+	// the device authorization endpoint and the verification page (RFC 8628)
+	GrantDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+	// the token endpoint with the access token of the user and the secret of the app (RFC 8693)
+	GrantTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// App is the client the tokens are issued for.
+// The secret is known only when it is generated, the storage keeps its hash.
+// The first-party apps belong to the owners of the service, so the users do not consent to their scopes.
 type App struct {
-	Id          int32
-	Name        string
-	Secret      string
-	Permissions []Permission
+	Id           int32
+	Name         string
+	Secret       string
+	SecretHash   []byte
+	FirstParty   bool
+	RedirectURIs []string
+	GrantTypes   []string
+	Permissions  []Permission
+	CreatedAt    time.Time
+}
+
+// AllowsGrant reports whether the app may obtain the tokens with the grant type.
+func (a App) AllowsGrant(grantType string) bool {
+	for _, g := range a.GrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission reports whether the app itself is granted the permission.
+func (a App) HasPermission(resource, action string) bool {
+	for _, p := range a.Permissions {
+		if p.Resource == resource && p.Action == action {
+			return true
+		}
+	}
+
+	return false
 }
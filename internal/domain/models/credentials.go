@@ -16,12 +16,52 @@ type UserCredentials struct {
 	PasswordHash []byte `db:"pass_hash"`
 }
 
+// RefreshToken belongs to the session of the user on a device.
+// Each refresh replaces the token with the new one and marks it as used,
+// so the tokens of the session form a family.
 type RefreshToken struct {
-	Value     string
+	Value      string
+	UserId     uint64
+	SessionId  uint64
+	AppId      int32    // 0 if the token was issued without an app
+	Scopes     []string // granted to the app, they stay the same after the rotation
+	DeviceName string
+	UserAgent  string
+	ExpiresIn  time.Time
+	CreatedBy  net.IP
+	CreatedAt  time.Time
+	UsedBy     net.IP
+	UsedAt     time.Time
+	RevokedBy  net.IP
+	RevokedAt  time.Time
+}
+
+// Device describes the client that logs in.
+type Device struct {
+	Name      string
+	UserAgent string
+	IP        net.IP
+}
+
+// Session is the login of the user on a device.
+// IP and RefreshedAt are taken from the last login or refresh.
+type Session struct {
+	Id          uint64
+	AppId       int32 // 0 means no app
+	DeviceName  string
+	UserAgent   string
+	IP          net.IP
+	RefreshedAt time.Time
+	ExpiresIn   time.Time
+	Current     bool
+}
+
+// PasswordResetToken lets the user who forgot the password set the new one.
+// The token is sent to the email of the user, only its SHA-256 hash is stored.
+type PasswordResetToken struct {
+	TokenHash string // hex encoded
 	UserId    uint64
-	ExpiresIn time.Time
 	CreatedBy net.IP
 	CreatedAt time.Time
-	RevokedBy net.IP
-	RevokedAt time.Time
+	ExpiresAt time.Time
 }
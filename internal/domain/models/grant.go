@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Grant is the consent of the user to grant the scopes to the third-party app.
+// The scopes the user has consented to once are not asked for again.
+type Grant struct {
+	UserId    uint64
+	AppId     int32
+	AppName   string
+	Scopes    []string
+	CreatedAt time.Time
+	UpdatedAt time.Time // when the last scopes were added
+}
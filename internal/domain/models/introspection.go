@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Types of the introspected tokens (RFC 7662)
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// Introspection is the state of the token presented by the resource server.
+// Nothing but Active is set for the inactive token.
+type Introspection struct {
+	Active    bool
+	TokenType string
+	TokenId   string // "jti" of the access token
+	Issuer    string
+	Audience  []string
+	UserId    uint64 // 0 for the token of the app itself
+	AppId     int32
+	SessionId uint64
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"net"
+	"time"
+)
+
+// AuthorizationRequest is the request of the app to authorize the user (RFC 6749, section 4.1.1).
+// The code challenge of PKCE (RFC 7636) is mandatory.
+type AuthorizationRequest struct {
+	AppId         int32
+	RedirectURI   string
+	CodeChallenge string // S256 challenge of the code verifier
+	Scopes        []string
+	Nonce         string // put in the ID token as it is (OpenID Connect Core 1.0, section 3.1.2.1)
+}
+
+// AuthorizationCode is issued to the app that authorized the user and is exchanged for the tokens only once.
+// The session the tokens are issued for is started on the device the user authorized the app from.
+// The code of the third-party app is pending until the user consents to the scopes, it can not be exchanged till then.
+type AuthorizationCode struct {
+	Code           string
+	AppId          int32
+	UserId         uint64
+	RedirectURI    string
+	CodeChallenge  string
+	Scopes         []string // the supported ones of the requested scopes
+	Nonce          string
+	ConsentPending bool
+	UserAgent      string
+	CreatedBy      net.IP
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+// Authorization is the result of the authorization of the app by the user.
+// If the consent is required, the code is pending and the user is asked to grant the scopes to the app.
+type Authorization struct {
+	Code            string
+	ConsentRequired bool
+	AppName         string
+	Scopes          []string
+}
+
+// DeviceCode is issued to the device that can not show the login form (RFC 8628, section 3.2).
+// The user approves it on another device by the user code, then the device exchanges the device code for the tokens.
+// The session the tokens are issued for is started on the device that requested the code.
+type DeviceCode struct {
+	DeviceCode string
+	UserCode   string // without the separator, e.g. "BCDFGHJK"
+	AppId      int32
+	Scopes     []string // the supported ones of the requested scopes
+	UserId     uint64   // 0 until the user approves the device
+	ReviewedBy uint64   // the user that signed in to approve the device last
+	ReviewCode string   // binds the approval to the sign-in of the user that reviewed the device
+	Interval   time.Duration
+	UserAgent  string
+	CreatedBy  net.IP
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	PolledAt   time.Time
+	ApprovedAt time.Time
+}
+
+// ClientCredentials authenticate the app at the token endpoint.
+// The secret is left empty only by the app which has none.
+type ClientCredentials struct {
+	AppId  int32
+	Secret string
+}
+
+// Requester is the resource server calling the service,
+// it presents either the credentials of its app or the access token of the app or of the user.
+type Requester struct {
+	Client ClientCredentials
+	Token  string
+}
+
+// TokenExchange is the request of the app to exchange the access token of the user (RFC 8693, section 2.1).
+// The app impersonates the user if there is no actor token, otherwise the actor acts on behalf of the user.
+type TokenExchange struct {
+	SubjectToken string
+	ActorToken   string
+	Audience     []string // the services the new token is intended for
+	Scopes       []string // empty means the scopes of the subject token
+}
+
+// Tokens are issued by the token endpoint (RFC 6749, section 5.1).
+// The ID token is issued only if the openid scope is granted.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	IDToken      string
+	Scopes       []string
+	ExpiresIn    time.Duration // lifetime of the access token
+}
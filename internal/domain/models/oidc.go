@@ -0,0 +1,32 @@
+package models
+
+// Scopes of OpenID Connect the service supports (OpenID Connect Core 1.0, section 5.4).
+const (
+	ScopeOpenID  = "openid"  // the ID token is issued and the userinfo endpoint is available
+	ScopeProfile = "profile" // given_name, family_name and birthdate claims
+	ScopeEmail   = "email"   // email claim
+)
+
+// BirthdateLayout is the format of the birthdate claim (OpenID Connect Core 1.0, section 5.1)
+const BirthdateLayout = "2006-01-02"
+
+// SupportedScopes are the scopes the app may be granted, the other requested ones are ignored.
+var SupportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// UserInfo is the user the claims of the ID token and of the userinfo endpoint are about.
+type UserInfo struct {
+	UserId uint64
+	Email  string
+	BriefProfile
+}
+
+// HasScope reports whether the scope is among the scopes.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
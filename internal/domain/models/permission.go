@@ -1,5 +1,7 @@
 package models
 
+import "strings"
+
 type Permission struct {
 	Id       int32
 	Resource string
@@ -7,3 +9,15 @@ type Permission struct {
 	// Type        string // "base" | "custom"
 	Description string
 }
+
+// Scope returns the scope the permission is put in the access token as, e.g. "app:read".
+func (p Permission) Scope() string {
+	return p.Resource + ":" + p.Action
+}
+
+// IsPermissionScope reports whether the scope has the "resource:action" form of the permission.
+func IsPermissionScope(scope string) bool {
+	resource, action, ok := strings.Cut(scope, ":")
+
+	return ok && resource != "" && action != ""
+}
@@ -9,5 +9,17 @@ type BriefProfile struct {
 }
 
 type Profile struct {
+	Id         uint64
+	Username   string
+	AvatarHash string
+	Idp        string
+	AccountId  string
 	BriefProfile
 }
+
+// ProfileUpdate contains the profile fields to update.
+// Nil fields stay unchanged.
+type ProfileUpdate struct {
+	Username   *string
+	AvatarHash *string
+}
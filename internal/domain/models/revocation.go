@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Revocation denies the access tokens before they expire.
+// It revokes the single token if TokenId is set, otherwise all the tokens of the session.
+type Revocation struct {
+	TokenId   string // "jti" of the token
+	SessionId uint64
+	UserId    uint64
+	RevokedAt time.Time
+	// The revoked tokens expire by then, so the revocation is not needed anymore
+	ExpiresAt time.Time
+}
@@ -1,5 +1,8 @@
 package models
 
+// RoleAdmin is the name of the role the migrations create with every base permission.
+const RoleAdmin = "admin"
+
 type Role struct {
 	Id          int32
 	Name        string
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"net"
+	"time"
+)
+
+// Types of the security events.
+const (
+	// SecurityEventRefreshTokenReuse is emitted when the already used refresh token is presented again,
+	// which means that it could have been stolen. The session of the token is revoked.
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	// SecurityEventTokenImpersonation is emitted when the app exchanges the token of the user
+	// for the one it acts as the user with.
+	SecurityEventTokenImpersonation = "token_impersonation"
+	// SecurityEventTokenDelegation is emitted when the app exchanges the token of the user
+	// for the one the actor acts on behalf of the user with.
+	SecurityEventTokenDelegation = "token_delegation"
+	// SecurityEventPasswordReset is emitted when the user sets the new password with the reset token.
+	// All the sessions of the user are revoked.
+	SecurityEventPasswordReset = "password_reset"
+)
+
+type SecurityEvent struct {
+	Type      string
+	UserId    uint64
+	SessionId uint64
+	AppId     int32  // the app that caused the event, 0 means none
+	Actor     string // "sub" of the actor of the delegation
+	IP        net.IP
+	CreatedAt time.Time
+}
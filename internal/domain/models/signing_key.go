@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SigningKey is the key of the key ring the access tokens are signed with.
+type SigningKey struct {
+	Id        string // "kid" header of the tokens
+	Algorithm string
+	// HMAC secret or PEM encoded private key, sealed with the master key if Encrypted is set
+	PrivateKey  []byte
+	Encrypted   bool
+	CreatedAt   time.Time
+	ActivatesAt time.Time // The newest activated key signs the tokens
+	ExpiresAt   time.Time // The key verifies the tokens until then, zero while the key is not retired
+}
@@ -0,0 +1,173 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres" // init postgres driver
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"  // init sqlite3 driver
+	_ "github.com/golang-migrate/migrate/v4/source/file"       // init file source
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// DefaultTable is the table where the version of the schema migrations is kept.
+const DefaultTable = "migrations"
+
+var ErrDirty = errors.New("database is dirty: fix the failed migration by hand and force the version")
+
+// Migrator applies NNNN_name.up.sql and rolls back NNNN_name.down.sql migrations
+// and keeps the current version in the migrations table.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New creates migrator for the migrations from the migrationsPath directory.
+func New(databaseURL, migrationsPath, migrationsTable string) (*Migrator, error) {
+	const op = "migrator.New"
+
+	m, err := migrate.New("file://"+migrationsPath, withTable(databaseURL, migrationsTable))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{m: m}, nil
+}
+
+// NewFromFS creates migrator for the migrations from the dir directory of fsys.
+func NewFromFS(fsys fs.FS, dir, databaseURL, migrationsTable string) (*Migrator, error) {
+	const op = "migrator.NewFromFS"
+
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, withTable(databaseURL, migrationsTable))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up applies all migrations that are not applied yet.
+// Returns false if there was nothing to apply.
+func (m *Migrator) Up() (applied bool, err error) {
+	const op = "migrator.Up"
+
+	if err := m.checkDirty(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = m.m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// Down rolls back the given number of the last applied migrations.
+func (m *Migrator) Down(steps uint) error {
+	const op = "migrator.Down"
+
+	if err := m.checkDirty(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.m.Steps(-int(steps)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Force sets the version and clears the dirty flag without running any migration.
+func (m *Migrator) Force(version int) error {
+	const op = "migrator.Force"
+
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Version returns the current version of the schema.
+// Zero version means that no migration has been applied.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	const op = "migrator.Version"
+
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, dirty, nil
+}
+
+// Close closes the source and the database.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+
+	return errors.Join(srcErr, dbErr)
+}
+
+func (m *Migrator) checkDirty() error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("%w (version %d)", ErrDirty, version)
+	}
+
+	return nil
+}
+
+// PostgresURL builds the database url of the Postgres storage.
+func PostgresURL(host string, port uint16, database, user, password, sslMode string) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(user, password),
+		Host:   net.JoinHostPort(host, strconv.Itoa(int(port))),
+		Path:   database,
+	}
+
+	if sslMode != "" {
+		u.RawQuery = url.Values{"sslmode": {sslMode}}.Encode()
+	}
+
+	return u.String()
+}
+
+// SQLiteURL builds the database url of the SQLite storage.
+func SQLiteURL(storagePath string) string {
+	return "sqlite3://" + storagePath
+}
+
+func withTable(databaseURL, migrationsTable string) string {
+	if migrationsTable == "" {
+		return databaseURL
+	}
+
+	sep := "?"
+	if strings.Contains(databaseURL, "?") {
+		sep = "&"
+	}
+
+	return databaseURL + sep + "x-migrations-table=" + url.QueryEscape(migrationsTable)
+}
@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Types of the messages.
+const (
+	// TypePasswordReset is the message with the token the user resets the password with.
+	TypePasswordReset = "password_reset"
+)
+
+// Message is the notification to the user.
+type Message struct {
+	Type      string    `json:"type"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Log writes the messages to the log instead of delivering them.
+// It is intended for local development: anyone who reads the log can use the tokens.
+type Log struct {
+	log *slog.Logger
+}
+
+// NewLog creates the notifier that writes the messages to the log.
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+// SendPasswordReset writes the password reset token to the log.
+func (n *Log) SendPasswordReset(_ context.Context, email, token string, expiresAt time.Time) error {
+	const op = "notifier.Log.SendPasswordReset"
+
+	n.log.Warn(
+		"password reset token is not delivered, it is logged",
+		slog.String("op", op),
+		slog.String("email", email),
+		slog.String("token", token),
+		slog.Time("expiresAt", expiresAt),
+	)
+
+	return nil
+}
+
+// Outbox appends the messages to the file as JSON lines,
+// the delivery service (or the tests) picks them up from there.
+type Outbox struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewOutbox creates the notifier that appends the messages to the file at the path.
+// The directory of the file is created if it does not exist.
+func NewOutbox(path string) (*Outbox, error) {
+	const op = "notifier.NewOutbox"
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Outbox{path: path}, nil
+}
+
+// SendPasswordReset appends the message with the password reset token to the outbox.
+func (n *Outbox) SendPasswordReset(_ context.Context, email, token string, expiresAt time.Time) error {
+	return n.append(
+		Message{
+			Type:      TypePasswordReset,
+			Email:     email,
+			Token:     token,
+			ExpiresAt: expiresAt.UTC(),
+		},
+	)
+}
+
+func (n *Outbox) append(msg Message) error {
+	const op = "notifier.Outbox.append"
+
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -2,9 +2,12 @@ package acs
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"time"
 
-	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/acs"
+	"github.com/puregrade-group/sso/pkg/jwt"
 )
 
 type ACS struct {
@@ -15,12 +18,23 @@ type ACS struct {
 	roleSaver    RoleSaver
 	roleProvider RoleProvider
 	roleRemover  RoleRemover
+	appSaver     AppSaver
 	appProvider  AppProvider
+	appRemover   AppRemover
+	keyProvider  KeyProvider
+	denylist     Denylist
+	// Service configs
+	tokenValidation jwt.Validation
 }
 
-type AppProvider interface {
-	GetSecret(ctx context.Context, appId int32) (string, error)
-	GetApp(ctx context.Context, appId int32) (models.App, error)
+// KeyProvider interface must be implemented by the key ring
+type KeyProvider interface {
+	VerificationKey(ctx context.Context, kid string) (key *jwt.Key, err error)
+}
+
+// Denylist interface must be implemented by the access token denylist
+type Denylist interface {
+	IsRevoked(tokenId string, sessionId uint64) (revoked bool)
 }
 
 func New(
@@ -31,7 +45,14 @@ func New(
 	roleSaver RoleSaver,
 	roleProvider RoleProvider,
 	roleRemover RoleRemover,
+	appSaver AppSaver,
 	appProvider AppProvider,
+	appRemover AppRemover,
+	keyProvider KeyProvider,
+	denylist Denylist,
+	accessTokenIssuer string,
+	accessTokenAudience []string,
+	accessTokenLeeway time.Duration,
 ) *ACS {
 	return &ACS{
 		log:          log,
@@ -41,6 +62,172 @@ func New(
 		roleSaver:    roleSaver,
 		roleProvider: roleProvider,
 		roleRemover:  roleRemover,
+		appSaver:     appSaver,
 		appProvider:  appProvider,
+		appRemover:   appRemover,
+		keyProvider:  keyProvider,
+		denylist:     denylist,
+		tokenValidation: jwt.Validation{
+			Issuer:   accessTokenIssuer,
+			Audience: accessTokenAudience,
+			Leeway:   accessTokenLeeway,
+		},
+	}
+}
+
+// Authorize checks that the requester is granted the permission on the resource
+// for the services that keep their permissions in ACS.
+func (a *ACS) Authorize(ctx context.Context,
+	requesterToken string,
+	resource, action string,
+) error {
+	const op = "ACS.Authorize"
+
+	log := a.log.With(slog.String("op", op))
+
+	return a.authorize(ctx, log, requesterToken, resource, action)
+}
+
+// authorize checks that the requester token is valid
+// and that the requester has permission to perform the action on the resource.
+// The requester is either the user or the app that obtained the token with the client credentials grant.
+func (a *ACS) authorize(ctx context.Context,
+	log *slog.Logger,
+	requesterToken,
+	resource, action string,
+) error {
+	claims, err := a.parseToken(log, requesterToken)
+	if err != nil {
+		return err
+	}
+
+	if claims.IsClient() {
+		return a.authorizeApp(ctx, log, claims.AppId, resource, action)
+	}
+
+	if claims.UID == 0 {
+		log.Error("UID field in token claims is empty")
+
+		return acs.ErrTokenNotValid
+	}
+
+	hasPerm, err := a.permProvider.CheckUserPermission(ctx, claims.UID, resource, action)
+	if err != nil {
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return storageError(err)
+	}
+
+	if !hasPerm {
+		log.Warn(
+			"user does not have permission to execute this request",
+			slog.String("permission", resource+":"+action),
+		)
+
+		return acs.ErrNotEnoughPermissions
+	}
+
+	return nil
+}
+
+// authorizeApp checks that the app has permission to perform the action on the resource.
+// The permissions are read on every request, so the updated ones take effect at once,
+// and the tokens of the deleted app are not valid.
+func (a *ACS) authorizeApp(ctx context.Context,
+	log *slog.Logger,
+	appId int32,
+	resource, action string,
+) error {
+	log = log.With(slog.Int("appId", int(appId)))
+
+	app, err := a.appProvider.GetApp(ctx, appId)
+	if err != nil {
+		if errors.Is(err, ErrAppNotFound) {
+			log.Warn("app of the token does not exist")
+
+			return acs.ErrTokenNotValid
+		}
+
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return storageError(err)
+	}
+
+	if !app.HasPermission(resource, action) {
+		log.Warn(
+			"app does not have permission to execute this request",
+			slog.String("permission", resource+":"+action),
+		)
+
+		return acs.ErrNotEnoughPermissions
+	}
+
+	return nil
+}
+
+// parseToken checks the validity of the requester token and returns its claims.
+// The token must be intended for the service or for the app it was issued for.
+// The revoked tokens are not valid.
+func (a *ACS) parseToken(
+	log *slog.Logger,
+	token string,
+) (*jwt.DefaultClaims, error) {
+	t, err := jwt.ParseToken(token, a.keyProvider.VerificationKey, a.tokenValidation)
+	if err != nil {
+		log.Error(
+			"token is not valid", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return &jwt.DefaultClaims{}, acs.ErrTokenNotValid
+	}
+
+	claims, ok := t.Claims.(*jwt.DefaultClaims)
+	if !ok {
+		log.Error("token claims have the wrong type")
+
+		return &jwt.DefaultClaims{}, acs.ErrTokenNotValid
+	}
+
+	if a.denylist.IsRevoked(claims.ID, claims.SessionId) {
+		log.Warn("token has been revoked", slog.String("jti", claims.ID))
+
+		return &jwt.DefaultClaims{}, acs.ErrTokenNotValid
+	}
+
+	return claims, nil
+}
+
+// storageError maps the error of the storage to the error of the transport layer.
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, ErrPermissionNotFound):
+		return acs.ErrPermissionNotFound
+	case errors.Is(err, ErrPermissionAlreadyExists):
+		return acs.ErrPermissionAlreadyExists
+	case errors.Is(err, ErrRoleNotFound):
+		return acs.ErrRoleNotFound
+	case errors.Is(err, ErrRoleAlreadyExists):
+		return acs.ErrRoleAlreadyExists
+	case errors.Is(err, ErrAppNotFound):
+		return acs.ErrAppNotFound
+	case errors.Is(err, ErrAppAlreadyExists):
+		return acs.ErrAppAlreadyExists
+	case errors.Is(err, ErrInternal):
+		return acs.ErrInternal
+	default:
+		return acs.ErrUnknown
 	}
 }
@@ -0,0 +1,281 @@
+package acs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/acs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// appSecretLen is the number of random bytes in the secret of the app
+const appSecretLen = 32
+
+var (
+	ErrAppNotFound      = errors.New("app not found")
+	ErrAppAlreadyExists = errors.New("app is already exists")
+)
+
+type AppSaver interface {
+	SaveApp(ctx context.Context,
+		app models.App,
+	) (appId int32, err error)
+	// UpdateApp replaces the name, the redirect URIs, the grant types and the permissions of the app.
+	UpdateApp(ctx context.Context,
+		app models.App,
+	) (err error)
+	UpdateAppSecret(ctx context.Context,
+		appId int32,
+		secretHash []byte,
+	) (err error)
+}
+
+type AppProvider interface {
+	GetApp(ctx context.Context,
+		appId int32,
+	) (app models.App, err error)
+	GetApps(ctx context.Context) (apps []models.App, err error)
+}
+
+type AppRemover interface {
+	DeleteApp(ctx context.Context,
+		appId int32,
+	) (err error)
+}
+
+// CreateApp registers new app and returns its id and secret.
+// The secret is not kept, so it can not be seen again, only reset.
+func (a *ACS) CreateApp(ctx context.Context,
+	requesterToken string,
+	app models.App,
+) (appId int32, secret string, err error) {
+	const op = "ACS.CreateApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("appName", app.Name),
+	)
+
+	log.Info("attempting to create new app")
+
+	if err := a.authorize(ctx, log, requesterToken, "app", "create"); err != nil {
+		return 0, "", err
+	}
+
+	secret, app.SecretHash, err = newAppSecret()
+	if err != nil {
+		log.Error(err.Error())
+
+		return 0, "", acs.ErrInternal
+	}
+
+	appId, err = a.appSaver.SaveApp(ctx, app)
+	if err != nil {
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return 0, "", storageError(err)
+	}
+
+	log.Info("app has been created", slog.Int("appId", int(appId)))
+
+	return appId, secret, nil
+}
+
+func (a *ACS) GetApp(ctx context.Context,
+	requesterToken string,
+	appId int32,
+) (app models.App, err error) {
+	const op = "ACS.GetApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(appId)),
+	)
+
+	log.Info("attempting to get app")
+
+	if err := a.authorize(ctx, log, requesterToken, "app", "read"); err != nil {
+		return models.App{}, err
+	}
+
+	app, err = a.appProvider.GetApp(ctx, appId)
+	if err != nil {
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return models.App{}, storageError(err)
+	}
+
+	return app, nil
+}
+
+func (a *ACS) ListApps(ctx context.Context,
+	requesterToken string,
+) (apps []models.App, err error) {
+	const op = "ACS.ListApps"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Info("attempting to list apps")
+
+	if err := a.authorize(ctx, log, requesterToken, "app", "read"); err != nil {
+		return nil, err
+	}
+
+	apps, err = a.appProvider.GetApps(ctx)
+	if err != nil {
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return nil, storageError(err)
+	}
+
+	return apps, nil
+}
+
+// UpdateApp replaces the name, the redirect URIs, the grant types and the permissions of the app.
+// The secret is kept, see ResetAppSecret.
+func (a *ACS) UpdateApp(ctx context.Context,
+	requesterToken string,
+	app models.App,
+) (err error) {
+	const op = "ACS.UpdateApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(app.Id)),
+	)
+
+	log.Info("attempting to update app")
+
+	if err := a.authorize(ctx, log, requesterToken, "app", "update"); err != nil {
+		return err
+	}
+
+	err = a.appSaver.UpdateApp(ctx, app)
+	if err != nil {
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return storageError(err)
+	}
+
+	return nil
+}
+
+func (a *ACS) DeleteApp(ctx context.Context,
+	requesterToken string,
+	appId int32,
+) (err error) {
+	const op = "ACS.DeleteApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(appId)),
+	)
+
+	log.Info("attempting to delete app")
+
+	if err := a.authorize(ctx, log, requesterToken, "app", "delete"); err != nil {
+		return err
+	}
+
+	err = a.appRemover.DeleteApp(ctx, appId)
+	if err != nil {
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return storageError(err)
+	}
+
+	log.Info("app has been deleted")
+
+	return nil
+}
+
+// ResetAppSecret replaces the secret of the app with the new one and returns it.
+// The old secret stops working at once.
+func (a *ACS) ResetAppSecret(ctx context.Context,
+	requesterToken string,
+	appId int32,
+) (secret string, err error) {
+	const op = "ACS.ResetAppSecret"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(appId)),
+	)
+
+	log.Info("attempting to reset app secret")
+
+	if err := a.authorize(ctx, log, requesterToken, "app", "update"); err != nil {
+		return "", err
+	}
+
+	secret, secretHash, err := newAppSecret()
+	if err != nil {
+		log.Error(err.Error())
+
+		return "", acs.ErrInternal
+	}
+
+	err = a.appSaver.UpdateAppSecret(ctx, appId, secretHash)
+	if err != nil {
+		log.Error(
+			"internal", slog.Attr{
+				Key:   "error",
+				Value: slog.StringValue(err.Error()),
+			},
+		)
+
+		return "", storageError(err)
+	}
+
+	log.Info("app secret has been reset")
+
+	return secret, nil
+}
+
+// newAppSecret generates the random secret of the app and its hash.
+func newAppSecret() (secret string, secretHash []byte, err error) {
+	b := make([]byte, appSecretLen)
+	if _, err = rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	secret = base64.RawURLEncoding.EncodeToString(b)
+
+	secretHash, err = bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, secretHash, nil
+}
@@ -3,17 +3,15 @@ package acs
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log/slog"
 
-	"github.com/google/uuid"
 	"github.com/puregrade-group/sso/internal/domain/models"
-	"github.com/puregrade-group/sso/pkg/jwt"
 )
 
 var (
-	ErrNotEnoughPermissions = errors.New("user do not have enough permissions")
-	ErrTokenNotValid        = errors.New("requester token in not valid")
+	ErrPermissionNotFound      = errors.New("permission not found")
+	ErrPermissionAlreadyExists = errors.New("permission is already exists")
+	ErrInternal                = errors.New("internal error")
 )
 
 type PermissionsSaver interface {
@@ -28,7 +26,7 @@ type PermissionsSaver interface {
 
 type PermissionsProvider interface {
 	CheckUserPermission(ctx context.Context,
-		userId [16]byte,
+		userId uint64,
 		resource, action string,
 	) (hasPermission bool, err error)
 	GetPermissionByName(ctx context.Context,
@@ -65,47 +63,10 @@ func (a *ACS) CreatePermission(ctx context.Context,
 
 	log.Info("attempting to create new permission")
 
-	claims, err := parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
+	if err := a.authorize(ctx, log, requesterToken, "permission", "create"); err != nil {
 		return 0, err
 	}
 
-	// cast string userId to [16]byte uuid
-	parsedId, err := uuid.Parse(claims.UID)
-	if err != nil {
-		log.Error(
-			"UID field in token claims is wrong", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return 0, fmt.Errorf("userId is wrong")
-	}
-
-	hasPerm, err := a.permProvider.CheckUserPermission(ctx, parsedId, "permission", "create")
-	if err != nil {
-		log.Error(
-			"internal", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
-
-	if !hasPerm {
-		log.Warn(
-			"user does not have permission to execute this request", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return 0, fmt.Errorf("%s: %w", op, ErrNotEnoughPermissions)
-	}
-
 	p := models.Permission{
 		Id:          0,
 		Resource:    resource,
@@ -114,7 +75,6 @@ func (a *ACS) CreatePermission(ctx context.Context,
 	}
 
 	id, err = a.permSaver.SavePermission(ctx, p)
-
 	if err != nil {
 		log.Error(
 			"internal", slog.Attr{
@@ -123,7 +83,7 @@ func (a *ACS) CreatePermission(ctx context.Context,
 			},
 		)
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, storageError(err)
 	}
 
 	return id, nil
@@ -131,7 +91,7 @@ func (a *ACS) CreatePermission(ctx context.Context,
 
 func (a *ACS) CheckUserPermission(ctx context.Context,
 	requesterToken string,
-	userId [16]byte,
+	userId uint64,
 	resource, action string,
 ) (ok bool, err error) {
 	const op = "ACS.CheckUserPermission"
@@ -140,7 +100,7 @@ func (a *ACS) CheckUserPermission(ctx context.Context,
 		slog.String("op", op),
 	)
 
-	_, err = parseToken(log, op, requesterToken, a.appProvider.GetSecret)
+	_, err = a.parseToken(log, requesterToken)
 	if err != nil {
 		return false, err
 	}
@@ -154,7 +114,7 @@ func (a *ACS) CheckUserPermission(ctx context.Context,
 			},
 		)
 
-		return false, err
+		return false, storageError(err)
 	}
 
 	return hasPerm, nil
@@ -171,56 +131,20 @@ func (a *ACS) DeletePermission(ctx context.Context,
 		slog.Int("permId", int(permissionId)),
 	)
 
-	claims, err := parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
-		return err
-	}
-
-	parsedId, err := uuid.Parse(claims.UID)
-	if err != nil {
-		log.Error(
-			"parse token userId failed", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	hasPerm, err := a.permProvider.CheckUserPermission(ctx, parsedId, "permission", "delete")
-	if err != nil {
-		log.Error(
-			"db error", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
+	if err := a.authorize(ctx, log, requesterToken, "permission", "delete"); err != nil {
 		return err
 	}
 
-	if !hasPerm {
-		log.Warn(
-			"not enough permissions", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return ErrNotEnoughPermissions
-	}
-
 	err = a.permRemover.DeletePermissionById(ctx, permissionId)
 	if err != nil {
 		log.Error(
-			"parse token userId failed", slog.Attr{
+			"db error", slog.Attr{
 				Key:   "error",
 				Value: slog.StringValue(err.Error()),
 			},
 		)
 
-		return fmt.Errorf("%s: %w", op, err)
+		return storageError(err)
 	}
 
 	return nil
@@ -238,46 +162,10 @@ func (a *ACS) AddPermission(ctx context.Context,
 		slog.Int64("permissionId", int64(permissionId)),
 	)
 
-	claims, err := parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
+	if err := a.authorize(ctx, log, requesterToken, "permission", "grant"); err != nil {
 		return err
 	}
 
-	parsedId, err := uuid.Parse(claims.UID)
-	if err != nil {
-		log.Error(
-			"parse token userId failed", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	hasPerm, err := a.permProvider.CheckUserPermission(ctx, parsedId, "permission", "grant")
-	if err != nil {
-		log.Error(
-			"db error", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return err
-	}
-
-	if !hasPerm {
-		log.Warn(
-			"not enough permissions", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return ErrNotEnoughPermissions
-	}
-
 	err = a.permSaver.SaveRolePermission(ctx, roleId, permissionId)
 	if err != nil {
 		log.Error(
@@ -287,7 +175,7 @@ func (a *ACS) AddPermission(ctx context.Context,
 			},
 		)
 
-		return err
+		return storageError(err)
 	}
 
 	return nil
@@ -305,46 +193,10 @@ func (a *ACS) RemovePermission(ctx context.Context,
 		slog.Int64("permission_id", int64(permissionId)),
 	)
 
-	claims, err := parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
+	if err := a.authorize(ctx, log, requesterToken, "permission", "revoke"); err != nil {
 		return err
 	}
 
-	parsedId, err := uuid.Parse(claims.UID)
-	if err != nil {
-		log.Error(
-			"parse token userId failed", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	hasPerm, err := a.permProvider.CheckUserPermission(ctx, parsedId, "permission", "revoke")
-	if err != nil {
-		log.Error(
-			"db error", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return err
-	}
-
-	if !hasPerm {
-		log.Warn(
-			"not enough permissions", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return ErrNotEnoughPermissions
-	}
-
 	err = a.permRemover.DeleteRolePermission(ctx, roleId, permissionId)
 	if err != nil {
 		log.Error(
@@ -354,40 +206,8 @@ func (a *ACS) RemovePermission(ctx context.Context,
 			},
 		)
 
-		return err
+		return storageError(err)
 	}
 
 	return nil
 }
-
-func parseToken(
-	log *slog.Logger,
-	op, token string,
-	secret func(ctx context.Context, appId int32) (string, error),
-) (*jwt.DefaultClaims, error) {
-	t, err := jwt.ParseToken(token, secret)
-	if err != nil {
-		log.Error(
-			"token is not valid", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return &jwt.DefaultClaims{}, fmt.Errorf("%s: %w", op, ErrTokenNotValid)
-	}
-
-	claims, ok := t.Claims.(*jwt.DefaultClaims)
-	if !ok {
-		log.Error(
-			"token is not valid", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return &jwt.DefaultClaims{}, fmt.Errorf("%s: %w", op, ErrTokenNotValid)
-	}
-
-	return claims, nil
-}
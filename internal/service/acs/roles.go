@@ -2,26 +2,30 @@ package acs
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
 
-	"github.com/google/uuid"
 	"github.com/puregrade-group/sso/internal/domain/models"
 )
 
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleAlreadyExists = errors.New("role is already exists")
+)
+
 type RoleSaver interface {
 	SaveRole(ctx context.Context,
 		role models.Role,
 	) (roleId int32, err error)
 	SaveUserRole(ctx context.Context,
-		userId [16]byte,
+		userId uint64,
 		roleId int32,
 	) (err error)
 }
 
 type RoleProvider interface {
 	GetUserRoles(ctx context.Context,
-		userId [16]byte,
+		userId uint64,
 	) (roles []models.Role, err error)
 }
 
@@ -30,7 +34,7 @@ type RoleRemover interface {
 		roleName string,
 	) (roleId int32, err error)
 	DeleteUserRole(ctx context.Context,
-		userId [16]byte,
+		userId uint64,
 		roleId int32,
 	) (err error)
 }
@@ -38,7 +42,7 @@ type RoleRemover interface {
 func (a *ACS) CreateRole(ctx context.Context,
 	requesterToken string,
 	role models.Role,
-) (err error) {
+) (roleId int32, err error) {
 	const op = "ACS.CreateRole"
 
 	log := a.log.With(
@@ -48,49 +52,11 @@ func (a *ACS) CreateRole(ctx context.Context,
 
 	log.Info("attempting to create new role")
 
-	claims, err := parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	// cast string userId to [16]byte uuid
-	parsedId, err := uuid.Parse(claims.UID)
-	if err != nil {
-		log.Error(
-			"UID field in token claims is wrong", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	hasPerm, err := a.permProvider.CheckUserPermission(ctx, parsedId, "role", "create")
-	if err != nil {
-		log.Error(
-			"internal", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, err)
+	if err := a.authorize(ctx, log, requesterToken, "role", "create"); err != nil {
+		return 0, err
 	}
 
-	if !hasPerm {
-		log.Warn(
-			"user does not have permission to execute this request", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, ErrNotEnoughPermissions)
-	}
-
-	_, err = a.roleSaver.SaveRole(ctx, role)
-
+	roleId, err = a.roleSaver.SaveRole(ctx, role)
 	if err != nil {
 		log.Error(
 			"internal", slog.Attr{
@@ -99,15 +65,15 @@ func (a *ACS) CreateRole(ctx context.Context,
 			},
 		)
 
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, storageError(err)
 	}
 
-	return nil
+	return roleId, nil
 }
 
 func (a *ACS) GetUserRoles(ctx context.Context,
 	requesterToken string,
-	userId [16]byte,
+	userId uint64,
 ) (roles []models.Role, err error) {
 	const op = "ACS.GetRoles"
 
@@ -117,13 +83,12 @@ func (a *ACS) GetUserRoles(ctx context.Context,
 
 	log.Info("attempting to get user roles")
 
-	_, err = parseToken(log, op, requesterToken, a.appProvider.GetSecret)
+	_, err = a.parseToken(log, requesterToken)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
 
 	roles, err = a.roleProvider.GetUserRoles(ctx, userId)
-
 	if err != nil {
 		log.Error(
 			"internal", slog.Attr{
@@ -132,7 +97,7 @@ func (a *ACS) GetUserRoles(ctx context.Context,
 			},
 		)
 
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, storageError(err)
 	}
 
 	return roles, nil
@@ -151,25 +116,11 @@ func (a *ACS) DeleteRole(ctx context.Context,
 
 	log.Info("attempting to delete role")
 
-	claims, err := parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	// cast string userId to [16]byte uuid
-	parsedId, err := uuid.Parse(claims.UID)
-	if err != nil {
-		log.Error(
-			"UID field in token claims is wrong", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, err)
+	if err := a.authorize(ctx, log, requesterToken, "role", "delete"); err != nil {
+		return err
 	}
 
-	hasPerm, err := a.permProvider.CheckUserPermission(ctx, parsedId, "role", "delete")
+	roleId, err := a.roleRemover.DeleteRole(ctx, roleName)
 	if err != nil {
 		log.Error(
 			"internal", slog.Attr{
@@ -178,22 +129,9 @@ func (a *ACS) DeleteRole(ctx context.Context,
 			},
 		)
 
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	if !hasPerm {
-		log.Warn(
-			"user does not have permission to execute this request", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, ErrNotEnoughPermissions)
+		return storageError(err)
 	}
 
-	roleId, err := a.roleRemover.DeleteRole(ctx, roleName)
-
 	log.Info(
 		"role been deleted", slog.Attr{
 			Key:   "roleId",
@@ -201,23 +139,12 @@ func (a *ACS) DeleteRole(ctx context.Context,
 		},
 	)
 
-	if err != nil {
-		log.Error(
-			"internal", slog.Attr{
-				Key:   "error",
-				Value: slog.StringValue(err.Error()),
-			},
-		)
-
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
 	return nil
 }
 
 func (a *ACS) AddRole(ctx context.Context,
 	requesterToken string,
-	userId [16]byte,
+	userId uint64,
 	roleId int32,
 ) (err error) {
 	const op = "ACS.AddRole"
@@ -228,13 +155,11 @@ func (a *ACS) AddRole(ctx context.Context,
 
 	log.Info("attempting to add role to user")
 
-	_, err = parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if err := a.authorize(ctx, log, requesterToken, "role", "grant"); err != nil {
+		return err
 	}
 
 	err = a.roleSaver.SaveUserRole(ctx, userId, roleId)
-
 	if err != nil {
 		log.Error(
 			"internal", slog.Attr{
@@ -243,7 +168,7 @@ func (a *ACS) AddRole(ctx context.Context,
 			},
 		)
 
-		return fmt.Errorf("%s: %w", op, err)
+		return storageError(err)
 	}
 
 	return nil
@@ -251,7 +176,7 @@ func (a *ACS) AddRole(ctx context.Context,
 
 func (a *ACS) RemoveRole(ctx context.Context,
 	requesterToken string,
-	userId [16]byte,
+	userId uint64,
 	roleId int32,
 ) (err error) {
 	const op = "ACS.RemoveRole"
@@ -260,15 +185,13 @@ func (a *ACS) RemoveRole(ctx context.Context,
 		slog.String("op", op),
 	)
 
-	log.Info("attempting to add role to user")
+	log.Info("attempting to remove role from user")
 
-	_, err = parseToken(log, op, requesterToken, a.appProvider.GetSecret)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if err := a.authorize(ctx, log, requesterToken, "role", "revoke"); err != nil {
+		return err
 	}
 
 	err = a.roleRemover.DeleteUserRole(ctx, userId, roleId)
-
 	if err != nil {
 		log.Error(
 			"internal", slog.Attr{
@@ -277,7 +200,7 @@ func (a *ACS) RemoveRole(ctx context.Context,
 			},
 		)
 
-		return fmt.Errorf("%s: %w", op, err)
+		return storageError(err)
 	}
 
 	return nil
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// attemptsSweepSize is the number of the counted keys the expired ones are deleted at
+const attemptsSweepSize = 1024
+
+// attemptLimiter counts the failed attempts by the key, e.g. the IP address, and refuses the key
+// that has failed the limit times until the window since its first failure passes.
+// The failures are counted by the instance, so every instance of the server limits them on its own.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	failures map[string]attempts
+}
+
+// attempts are the failures of the key since the start of its window.
+type attempts struct {
+	count int
+	start time.Time
+}
+
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:    limit,
+		window:   window,
+		failures: make(map[string]attempts),
+	}
+}
+
+// Allow reports whether the key may be attempted at the time.
+func (l *attemptLimiter) Allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.failures[key]
+
+	return !ok || l.expired(a, now) || a.count < l.limit
+}
+
+// Fail counts the failed attempt of the key at the time.
+func (l *attemptLimiter) Fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.failures) >= attemptsSweepSize {
+		for k, a := range l.failures {
+			if l.expired(a, now) {
+				delete(l.failures, k)
+			}
+		}
+	}
+
+	a, ok := l.failures[key]
+	if !ok || l.expired(a, now) {
+		a = attempts{start: now}
+	}
+
+	a.count++
+	l.failures[key] = a
+}
+
+func (l *attemptLimiter) expired(a attempts, now time.Time) bool {
+	return !now.Before(a.start.Add(l.window))
+}
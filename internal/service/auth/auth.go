@@ -2,9 +2,12 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"log/slog"
-	"math/rand"
+	"math/big"
+	"net"
+	"sync"
 	"time"
 
 	"github.com/puregrade-group/sso/internal/domain/models"
@@ -21,19 +24,55 @@ type Auth struct {
 	usrSaver             UserSaver
 	usrProvider          UserProvider
 	refreshTokenProvider RefreshTokenProvider
+	securityEventSaver   SecurityEventSaver
+	appProvider          AppProvider
+	roleProvider         RoleProvider
+	authCodeProvider     AuthorizationCodeProvider
+	deviceCodeProvider   DeviceCodeProvider
+	grantProvider        GrantProvider
+	resetTokenProvider   PasswordResetTokenProvider
+	keyProvider          KeyProvider
+	denylist             Denylist
+	notifier             Notifier
+	// resets are the password resets being saved and sent after the response
+	resets sync.WaitGroup
+	// deviceAttemptsByIP and deviceAttemptsByCode limit the failed sign-ins on the device page,
+	// so the user codes can not be guessed (RFC 8628, section 5.1)
+	deviceAttemptsByIP   *attemptLimiter
+	deviceAttemptsByCode *attemptLimiter
 	// Service configs
-	accessTokenTTL     time.Duration
-	accessTokenSecret  []byte
-	refreshTokenTTL    time.Duration
-	refreshTokenLength uint
+	accessTokenTTL         time.Duration
+	accessTokenIssuer      string
+	accessTokenAudience    []string
+	accessTokenLeeway      time.Duration
+	refreshTokenTTL        time.Duration
+	refreshTokenLength     uint
+	refreshTokenReuseGrace time.Duration
+	authorizationCodeTTL   time.Duration
+	deviceCodeTTL          time.Duration
+	deviceCodeInterval     time.Duration
+	passwordResetTTL       time.Duration
+	openID                 bool // the openid scope is granted, the keys are asymmetric
 }
 
 var (
-	ErrUserNotFound         = errors.New("user not found")
-	ErrRefreshTokenNotFound = errors.New("token with such content has not been provided to anyone")
-	ErrUserAlreadyExists    = errors.New("user is already exists")
-	ErrInternal             = errors.New("internal error")
-	ErrUnknown              = errors.New("unknown error")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrRefreshTokenNotFound      = errors.New("token with such content has not been provided to anyone")
+	ErrRefreshTokenRevoked       = errors.New("token has been revoked")
+	ErrRefreshTokenUsed          = errors.New("token has been used already")
+	ErrSessionNotFound           = errors.New("session not found")
+	ErrAppNotFound               = errors.New("app not found")
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found")
+	ErrDeviceCodeNotFound        = errors.New("device code not found")
+	ErrUserCodeAlreadyExists     = errors.New("user code is already exists")
+	ErrGrantNotAllowed           = errors.New("app is not allowed to use the grant type")
+	ErrGrantNotFound             = errors.New("grant not found")
+	ErrResetTokenNotFound        = errors.New("password reset token not found")
+	ErrUserAlreadyExists         = errors.New("user is already exists")
+	ErrInternal                  = errors.New("internal error")
+	ErrUnknown                   = errors.New("unknown error")
+
+	errWrongPassword = errors.New("wrong password")
 )
 
 // UserSaver interface must be implemented by the repository layer
@@ -42,6 +81,10 @@ type UserSaver interface {
 		creds models.UserCredentials,
 		profile models.Profile,
 	) (err error)
+	UpdatePassword(ctx context.Context,
+		userId uint64,
+		passHash []byte,
+	) (err error)
 }
 
 // UserProvider interface must be implemented by the repository layer
@@ -49,17 +92,166 @@ type UserProvider interface {
 	GetUserCreds(ctx context.Context,
 		email string,
 	) (models.UserCredentials, error)
+	UserExists(ctx context.Context,
+		userId uint64,
+	) (exists bool, err error)
+	GetUserInfo(ctx context.Context,
+		userId uint64,
+	) (models.UserInfo, error)
 }
 
+// RefreshTokenProvider interface must be implemented by the repository layer.
+// Each session of the user has one unused and unrevoked refresh token.
 type RefreshTokenProvider interface {
-	Upsert(ctx context.Context,
-		userId uint64,
-		token string,
-		expiresIn time.Time,
+	SaveRefreshToken(ctx context.Context,
+		token models.RefreshToken,
 	) (err error)
-	GetUserId(ctx context.Context,
+	GetRefreshToken(ctx context.Context,
 		token string,
-	) (userId uint64, err error)
+	) (models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context,
+		oldToken string,
+		newToken models.RefreshToken,
+	) (err error)
+	RevokeAll(ctx context.Context,
+		userId uint64,
+		revokedBy net.IP,
+	) (err error)
+	GetSessions(ctx context.Context,
+		userId uint64,
+	) (sessions []models.Session, err error)
+	RevokeSession(ctx context.Context,
+		userId uint64,
+		sessionId uint64,
+		revokedBy net.IP,
+	) (err error)
+}
+
+// SecurityEventSaver interface must be implemented by the repository layer
+type SecurityEventSaver interface {
+	SaveSecurityEvent(ctx context.Context,
+		event models.SecurityEvent,
+	) (err error)
+}
+
+// AppProvider interface must be implemented by the repository layer
+type AppProvider interface {
+	GetApp(ctx context.Context,
+		appId int32,
+	) (app models.App, err error)
+}
+
+// RoleProvider interface must be implemented by the repository layer
+type RoleProvider interface {
+	// GetUserRoles returns the roles of the user with their permissions.
+	GetUserRoles(ctx context.Context,
+		userId uint64,
+	) (roles []models.Role, err error)
+}
+
+// AuthorizationCodeProvider interface must be implemented by the repository layer
+type AuthorizationCodeProvider interface {
+	SaveAuthorizationCode(ctx context.Context,
+		code models.AuthorizationCode,
+	) (err error)
+	// ConsumeAuthorizationCode deletes the unexpired code and returns it, so it can be exchanged only once.
+	ConsumeAuthorizationCode(ctx context.Context,
+		code string,
+	) (models.AuthorizationCode, error)
+	// ConsentAuthorizationCode marks the unexpired pending code as consented to and returns it,
+	// ErrAuthorizationCodeNotFound is returned if there is no such code.
+	ConsentAuthorizationCode(ctx context.Context,
+		code string,
+	) (models.AuthorizationCode, error)
+}
+
+// DeviceCodeProvider interface must be implemented by the repository layer
+type DeviceCodeProvider interface {
+	// SaveDeviceCode saves the code, ErrUserCodeAlreadyExists is returned if its user code is taken.
+	SaveDeviceCode(ctx context.Context,
+		code models.DeviceCode,
+	) (err error)
+	GetDeviceCode(ctx context.Context,
+		deviceCode string,
+	) (models.DeviceCode, error)
+	// GetPendingDeviceCode returns the unexpired code of the user code that has not been approved yet.
+	GetPendingDeviceCode(ctx context.Context,
+		userCode string,
+	) (models.DeviceCode, error)
+	// ReviewDeviceCode binds the user that signed in to review the unexpired code that has not been approved yet
+	// and the code of the review, the one of the previous review is replaced.
+	ReviewDeviceCode(ctx context.Context,
+		userCode string,
+		userId uint64,
+		reviewCode string,
+	) (err error)
+	// ApproveDeviceCode binds the user that reviewed the unexpired code that has not been approved yet to it,
+	// ErrDeviceCodeNotFound is returned if the code has been reviewed again since.
+	ApproveDeviceCode(ctx context.Context,
+		userCode string,
+		reviewCode string,
+		approvedAt time.Time,
+	) (err error)
+	UpdateDeviceCodePoll(ctx context.Context,
+		deviceCode string,
+		polledAt time.Time,
+		interval time.Duration,
+	) (err error)
+	// DeleteDeviceCode deletes the code, ErrDeviceCodeNotFound is returned if it has been deleted already.
+	DeleteDeviceCode(ctx context.Context,
+		deviceCode string,
+	) (err error)
+}
+
+// GrantProvider interface must be implemented by the repository layer.
+// The user has at most one grant to each app.
+type GrantProvider interface {
+	// SaveGrant saves the grant or replaces the scopes and the update time of the existing one.
+	SaveGrant(ctx context.Context,
+		grant models.Grant,
+	) (err error)
+	GetGrant(ctx context.Context,
+		userId uint64,
+		appId int32,
+	) (models.Grant, error)
+	GetGrants(ctx context.Context,
+		userId uint64,
+	) (grants []models.Grant, err error)
+	DeleteGrant(ctx context.Context,
+		userId uint64,
+		appId int32,
+	) (err error)
+}
+
+// PasswordResetTokenProvider interface must be implemented by the repository layer.
+// The user has at most one password reset token.
+type PasswordResetTokenProvider interface {
+	// SavePasswordResetToken saves the token and deletes the previous one of the user.
+	SavePasswordResetToken(ctx context.Context,
+		token models.PasswordResetToken,
+	) (err error)
+	// ConsumePasswordResetToken deletes the unexpired token and returns it, so it can be used only once.
+	ConsumePasswordResetToken(ctx context.Context,
+		tokenHash string,
+	) (models.PasswordResetToken, error)
+}
+
+// KeyProvider interface must be implemented by the key ring
+type KeyProvider interface {
+	SigningKey() (key *jwt.Key, err error)
+	VerificationKey(ctx context.Context, kid string) (key *jwt.Key, err error)
+}
+
+// Denylist interface must be implemented by the access token denylist
+type Denylist interface {
+	Revoke(ctx context.Context, revocation models.Revocation) (err error)
+	IsRevoked(tokenId string, sessionId uint64) (revoked bool)
+	Subscribe() (active []models.Revocation, revocations <-chan models.Revocation, unsubscribe func())
+}
+
+// Notifier interface must be implemented by the delivery of the messages to the users
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, email, token string, expiresAt time.Time) (err error)
 }
 
 func New(
@@ -68,49 +260,96 @@ func New(
 	userSaver UserSaver,
 	userProvider UserProvider,
 	refreshTokenProvider RefreshTokenProvider,
+	securityEventSaver SecurityEventSaver,
+	appProvider AppProvider,
+	roleProvider RoleProvider,
+	authCodeProvider AuthorizationCodeProvider,
+	deviceCodeProvider DeviceCodeProvider,
+	grantProvider GrantProvider,
+	resetTokenProvider PasswordResetTokenProvider,
+	keyProvider KeyProvider,
+	denylist Denylist,
+	notifier Notifier,
 	accessTokenTTL time.Duration,
-	accessTokenSecret []byte,
+	accessTokenIssuer string,
+	accessTokenAudience []string,
+	accessTokenLeeway time.Duration,
 	refreshTokenTTL time.Duration,
 	refreshTokenLength uint,
+	refreshTokenReuseGrace time.Duration,
+	authorizationCodeTTL time.Duration,
+	deviceCodeTTL time.Duration,
+	deviceCodeInterval time.Duration,
+	deviceAttemptsPerIP uint,
+	passwordResetTTL time.Duration,
+	openID bool,
 ) *Auth {
 	return &Auth{
-		usrSaver:             userSaver,
-		usrProvider:          userProvider,
-		refreshTokenProvider: refreshTokenProvider,
-		snowflake:            snowflake,
-		log:                  log,
-		accessTokenTTL:       accessTokenTTL,
-		accessTokenSecret:    accessTokenSecret,
-		refreshTokenTTL:      refreshTokenTTL,
-		refreshTokenLength:   refreshTokenLength,
+		usrSaver:               userSaver,
+		usrProvider:            userProvider,
+		refreshTokenProvider:   refreshTokenProvider,
+		securityEventSaver:     securityEventSaver,
+		appProvider:            appProvider,
+		roleProvider:           roleProvider,
+		authCodeProvider:       authCodeProvider,
+		deviceCodeProvider:     deviceCodeProvider,
+		grantProvider:          grantProvider,
+		resetTokenProvider:     resetTokenProvider,
+		keyProvider:            keyProvider,
+		denylist:               denylist,
+		notifier:               notifier,
+		snowflake:              snowflake,
+		log:                    log,
+		accessTokenTTL:         accessTokenTTL,
+		accessTokenIssuer:      accessTokenIssuer,
+		accessTokenAudience:    accessTokenAudience,
+		accessTokenLeeway:      accessTokenLeeway,
+		refreshTokenTTL:        refreshTokenTTL,
+		refreshTokenLength:     refreshTokenLength,
+		refreshTokenReuseGrace: refreshTokenReuseGrace,
+		authorizationCodeTTL:   authorizationCodeTTL,
+		deviceCodeTTL:          deviceCodeTTL,
+		deviceCodeInterval:     deviceCodeInterval,
+		deviceAttemptsByIP:     newAttemptLimiter(int(deviceAttemptsPerIP), deviceCodeTTL),
+		deviceAttemptsByCode:   newAttemptLimiter(userCodeFailures, deviceCodeTTL),
+		passwordResetTTL:       passwordResetTTL,
+		openID:                 openID,
 	}
 }
 
 // Login checks if user with given credentials exists in the system and returns access token.
+// If the app is given, the tokens are issued for it, 0 means no app.
+// Only the requested "resource:action" scopes the roles of the user grant are put in the access token.
 //
 // If user exists, but password is incorrect, returns error.
-// If user doesn't exist, returns error.
+// If user or app doesn't exist, returns error.
 func (a *Auth) Login(ctx context.Context,
 	creds models.Credentials,
+	device models.Device,
+	appId int32,
+	scopes []string,
 ) (accessToken, refreshToken string, err error) {
 	const op = "Auth.Login"
 
 	log := a.log.With(
 		slog.String("op", op),
+		slog.Int("appId", int(appId)),
 	)
 
 	log.Info("attempting to login user")
 
-	// Getting user credentials
-	user, err := a.usrProvider.GetUserCreds(ctx, creds.Email)
+	if err = a.checkApp(ctx, appId, models.GrantPassword); err != nil {
+		log.Error(err.Error())
 
-	// Error handling
+		return "", "", appError(err)
+	}
+
+	userId, err := a.verifyCredentials(ctx, creds)
 	if err != nil {
 		log.Error(err.Error())
 
 		switch {
-		case err == nil: // Do nothing
-		case errors.Is(err, ErrUserNotFound):
+		case errors.Is(err, ErrUserNotFound), errors.Is(err, errWrongPassword):
 			return "", "", auth.ErrWrongCredentials
 		case errors.Is(err, ErrInternal):
 			return "", "", auth.ErrInternal
@@ -119,71 +358,51 @@ func (a *Auth) Login(ctx context.Context,
 		}
 	}
 
-	// Password comparing
-	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(creds.Password)); err != nil {
-		log.Error(err.Error())
-
-		return "", "", auth.ErrWrongCredentials
-	}
-
-	// Creating new JWT token
-	accessToken, err = jwt.NewToken(user.Id, a.accessTokenTTL, a.accessTokenSecret)
+	scopes, err = a.grantedScopes(ctx, userId, scopes)
 	if err != nil {
 		log.Error(err.Error())
 
 		return "", "", auth.ErrInternal
 	}
 
-	log.Debug(
-		"access token was successfully created",
-		slog.String("token", accessToken),
-	)
-
-	// Creating refresh token
-	refreshToken = genRandomString(a.refreshTokenLength)
-
-	log.Debug("refresh token was generated", slog.String("token", refreshToken))
-
-	err = a.refreshTokenProvider.Upsert(ctx, user.Id, refreshToken, time.Now().Add(a.refreshTokenTTL))
-
-	// Error handling
+	accessToken, refreshToken, err = a.startSession(ctx, log, userId, device, appId, scopes)
 	if err != nil {
 		log.Error(err.Error())
 
-		switch {
-		case errors.Is(err, ErrInternal):
-			return "", "", auth.ErrInternal
-		default:
-			return "", "", auth.ErrUnknown
-		}
+		return "", "", auth.ErrInternal
 	}
 
-	log.Debug(
-		"refresh token was successfully upserted",
-		slog.String("token", refreshToken),
-	)
-
 	log.Info("user logged in successfully")
 
 	return accessToken, refreshToken, nil
 }
 
 // RegisterNewUser checks if user with given credentials not exists in the system and returns new user userId.
+// If the app is given, the user registers through it, 0 means no app.
 //
 // If user already exists, returns error.
+// If app doesn't exist, returns error.
 // If data don't pass validation process, returns error.
 func (a *Auth) RegisterNewUser(ctx context.Context,
 	creds models.Credentials,
 	profile models.BriefProfile,
+	appId int32,
 ) (uint64, error) {
 	const op = "Auth.RegisterNewUser"
 
 	log := a.log.With(
 		slog.String("op", op),
+		slog.Int("appId", int(appId)),
 	)
 
 	log.Info("attempting to register user")
 
+	if err := a.checkApp(ctx, appId, ""); err != nil {
+		log.Error(err.Error())
+
+		return 0, appError(err)
+	}
+
 	// Generating password hash
 	passHash, err := bcrypt.GenerateFromPassword([]byte(creds.Password), 5)
 	if err != nil {
@@ -224,33 +443,54 @@ func (a *Auth) RegisterNewUser(ctx context.Context,
 	return userCreds.Id, nil
 }
 
+// RefreshTokens replaces the refresh token of the session with the new one and issues new access token.
+// The refresh token can be refreshed only by the app it was issued for, 0 means no app.
 func (a *Auth) RefreshTokens(
 	ctx context.Context,
 	token string,
+	ip net.IP,
+	appId int32,
 ) (accessToken, refreshToken string, err error) {
 	const op = "Auth.RefreshTokens"
 
-	log := a.log.With(slog.String("op", op))
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(appId)),
+	)
 
-	// Getting user id by refresh token
-	id, err := a.refreshTokenProvider.GetUserId(ctx, token)
+	// Getting the session by refresh token
+	old, err := a.activeRefreshToken(ctx, log, token, ip)
 
 	// Error handling
 	if err != nil {
 		log.With(slog.String("token", token)).Error(err.Error())
 
-		switch {
-		case errors.Is(err, ErrRefreshTokenNotFound):
-			return "", "", auth.ErrTokenNotFound
-		case errors.Is(err, ErrInternal):
-			return "", "", auth.ErrInternal
-		default:
-			return "", "", auth.ErrUnknown
-		}
+		return "", "", refreshTokenError(err)
+	}
+
+	if old.AppId != appId {
+		log.Warn("refresh token was issued for another app", slog.Int("tokenAppId", int(old.AppId)))
+
+		return "", "", auth.ErrAppMismatch
+	}
+
+	// The app could have been deleted or disallowed the grant since the login
+	if err = a.checkApp(ctx, appId, models.GrantRefreshToken); err != nil {
+		log.Error(err.Error())
+
+		return "", "", appError(err)
+	}
+
+	// The user could have lost the permissions of the scopes since the login
+	scopes, err := a.grantedScopes(ctx, old.UserId, old.Scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return "", "", auth.ErrInternal
 	}
 
 	// Creating new JWT token
-	accessToken, err = jwt.NewToken(id, a.accessTokenTTL, a.accessTokenSecret)
+	accessToken, err = a.newAccessToken(old.UserId, old.SessionId, appId, scopes)
 	if err != nil {
 		log.Error(err.Error())
 
@@ -258,22 +498,38 @@ func (a *Auth) RefreshTokens(
 	}
 
 	// Generating new refresh token
-	refreshToken = genRandomString(a.refreshTokenLength)
+	refreshToken, err = genRandomString(a.refreshTokenLength)
+	if err != nil {
+		log.Error(err.Error())
+
+		return "", "", auth.ErrInternal
+	}
+
 	log.Debug("refresh token was generated", slog.String("token", refreshToken))
 
-	// Upserting refresh token
-	err = a.refreshTokenProvider.Upsert(ctx, id, refreshToken, time.Now().Add(a.refreshTokenTTL))
+	now := time.Now()
+
+	// Replacing refresh token, the session and its device stay the same
+	err = a.refreshTokenProvider.RotateRefreshToken(
+		ctx, token, models.RefreshToken{
+			Value:      refreshToken,
+			UserId:     old.UserId,
+			SessionId:  old.SessionId,
+			AppId:      old.AppId,
+			Scopes:     scopes,
+			DeviceName: old.DeviceName,
+			UserAgent:  old.UserAgent,
+			ExpiresIn:  now.Add(a.refreshTokenTTL),
+			CreatedBy:  ip,
+			CreatedAt:  now,
+		},
+	)
 
 	// Error handling
 	if err != nil {
 		log.Error(err.Error())
 
-		switch {
-		case errors.Is(err, ErrInternal):
-			return "", "", auth.ErrInternal
-		default:
-			return "", "", auth.ErrUnknown
-		}
+		return "", "", refreshTokenError(err)
 	}
 
 	log.Debug(
@@ -287,14 +543,419 @@ func (a *Auth) RefreshTokens(
 	return accessToken, refreshToken, nil
 }
 
-func genRandomString(length uint) string {
-	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+// Logout revokes the session of the refresh token, so it can not be refreshed anymore.
+// The revoked tokens are kept in the storage for audit.
+func (a *Auth) Logout(ctx context.Context,
+	token string,
+	ip net.IP,
+) (err error) {
+	const op = "Auth.Logout"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("attempting to logout user")
+
+	t, err := a.activeRefreshToken(ctx, log, token, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		// The session of the revoked token has been logged out already
+		if errors.Is(err, ErrRefreshTokenRevoked) {
+			return auth.ErrTokenNotFound
+		}
+
+		return refreshTokenError(err)
+	}
+
+	err = a.refreshTokenProvider.RevokeSession(ctx, t.UserId, t.SessionId, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return refreshTokenError(err)
+	}
+
+	err = a.revokeAccessTokens(ctx, t.UserId, t.SessionId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	log.Info("user logged out")
+
+	return nil
+}
+
+// LogoutAll revokes all the refresh tokens of the user to whom the given token was issued.
+func (a *Auth) LogoutAll(ctx context.Context,
+	token string,
+	ip net.IP,
+) (err error) {
+	const op = "Auth.LogoutAll"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("attempting to logout user from all sessions")
+
+	t, err := a.activeRefreshToken(ctx, log, token, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return refreshTokenError(err)
+	}
+
+	sessions, err := a.refreshTokenProvider.GetSessions(ctx, t.UserId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return refreshTokenError(err)
+	}
+
+	err = a.refreshTokenProvider.RevokeAll(ctx, t.UserId, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return refreshTokenError(err)
+	}
+
+	for _, session := range sessions {
+		err = a.revokeAccessTokens(ctx, t.UserId, session.Id)
+		if err != nil {
+			log.Error(err.Error())
+
+			return auth.ErrInternal
+		}
+	}
+
+	log.Info("user logged out from all sessions", slog.Uint64("id", t.UserId))
+
+	return nil
+}
+
+// ListSessions returns the active sessions of the user to whom the given token was issued.
+// The session of the token is marked as current.
+func (a *Auth) ListSessions(ctx context.Context,
+	token string,
+	ip net.IP,
+) (sessions []models.Session, err error) {
+	const op = "Auth.ListSessions"
+
+	log := a.log.With(slog.String("op", op))
+
+	t, err := a.activeRefreshToken(ctx, log, token, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return nil, refreshTokenError(err)
+	}
+
+	sessions, err = a.refreshTokenProvider.GetSessions(ctx, t.UserId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return nil, refreshTokenError(err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == t.SessionId
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes the session of the user to whom the given token was issued.
+func (a *Auth) RevokeSession(ctx context.Context,
+	token string,
+	sessionId uint64,
+	ip net.IP,
+) (err error) {
+	const op = "Auth.RevokeSession"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Uint64("sessionId", sessionId),
+	)
+
+	log.Info("attempting to revoke session")
+
+	t, err := a.activeRefreshToken(ctx, log, token, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return refreshTokenError(err)
+	}
+
+	err = a.refreshTokenProvider.RevokeSession(ctx, t.UserId, sessionId, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return refreshTokenError(err)
+	}
+
+	err = a.revokeAccessTokens(ctx, t.UserId, sessionId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	log.Info("session revoked")
+
+	return nil
+}
+
+// verifyCredentials returns the id of the user the credentials belong to.
+func (a *Auth) verifyCredentials(ctx context.Context, creds models.Credentials) (uint64, error) {
+	user, err := a.usrProvider.GetUserCreds(ctx, creds.Email)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(creds.Password)); err != nil {
+		return 0, errWrongPassword
+	}
+
+	return user.Id, nil
+}
+
+// startSession starts new session of the user on the device and issues its access and refresh tokens.
+// The tokens are issued for the app with the scopes granted to it, 0 means no app.
+func (a *Auth) startSession(ctx context.Context,
+	log *slog.Logger,
+	userId uint64,
+	device models.Device,
+	appId int32,
+	scopes []string,
+) (accessToken, refreshToken string, err error) {
+	sessionId := a.snowflake.Generate()
+
+	// Creating new JWT token
+	accessToken, err = a.newAccessToken(userId, sessionId, appId, scopes)
+	if err != nil {
+		return "", "", err
+	}
+
+	log.Debug(
+		"access token was successfully created",
+		slog.String("token", accessToken),
+	)
+
+	// Creating refresh token of the new session
+	refreshToken, err = genRandomString(a.refreshTokenLength)
+	if err != nil {
+		return "", "", err
+	}
+
+	log.Debug("refresh token was generated", slog.String("token", refreshToken))
+
+	now := time.Now()
+
+	err = a.refreshTokenProvider.SaveRefreshToken(
+		ctx, models.RefreshToken{
+			Value:      refreshToken,
+			UserId:     userId,
+			SessionId:  sessionId,
+			AppId:      appId,
+			Scopes:     scopes,
+			DeviceName: device.Name,
+			UserAgent:  device.UserAgent,
+			ExpiresIn:  now.Add(a.refreshTokenTTL),
+			CreatedBy:  device.IP,
+			CreatedAt:  now,
+		},
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	log.Debug(
+		"refresh token was successfully saved",
+		slog.String("token", refreshToken),
+	)
+
+	return accessToken, refreshToken, nil
+}
+
+// newAccessToken creates new JWT token for given user and session signed with the current key of the key ring.
+// The token of the app is intended for the app, other tokens for the configured audience. Both carry the scopes.
+func (a *Auth) newAccessToken(userId, sessionId uint64, appId int32, scopes []string) (string, error) {
+	key, err := a.keyProvider.SigningKey()
+	if err != nil {
+		return "", err
+	}
 
-	b := make([]uint8, length)
+	if appId != 0 {
+		return jwt.NewAppToken(a.accessTokenIssuer, userId, sessionId, appId, scopes, a.accessTokenTTL, key)
+	}
+
+	return jwt.NewToken(a.accessTokenIssuer, a.accessTokenAudience, userId, sessionId, scopes, a.accessTokenTTL, key)
+}
+
+// checkApp checks that the app exists and may obtain the tokens with the grant type, 0 means no app.
+// The empty grant type is not checked.
+func (a *Auth) checkApp(ctx context.Context, appId int32, grantType string) error {
+	if appId == 0 {
+		return nil
+	}
+
+	app, err := a.appProvider.GetApp(ctx, appId)
+	if err != nil {
+		return err
+	}
+
+	if grantType != "" && !app.AllowsGrant(grantType) {
+		return ErrGrantNotAllowed
+	}
+
+	return nil
+}
+
+// parseAccessToken checks the validity of the access token issued by the service.
+// The audience is not checked: the token is presented by its owner or by the resource server it is intended for.
+func (a *Auth) parseAccessToken(token string) (*jwt.DefaultClaims, error) {
+	t, err := jwt.ParseToken(
+		token, a.keyProvider.VerificationKey, jwt.Validation{
+			Issuer: a.accessTokenIssuer,
+			Leeway: a.accessTokenLeeway,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := t.Claims.(*jwt.DefaultClaims)
+	if !ok {
+		return nil, jwt.ErrWrongClaims
+	}
+
+	return claims, nil
+}
+
+// revokeAccessTokens denies the access tokens issued for the session.
+// They were issued no later than now, so they all expire within the access token TTL.
+func (a *Auth) revokeAccessTokens(ctx context.Context, userId, sessionId uint64) error {
+	now := time.Now()
+
+	return a.denylist.Revoke(
+		ctx, models.Revocation{
+			SessionId: sessionId,
+			UserId:    userId,
+			RevokedAt: now,
+			ExpiresAt: now.Add(a.accessTokenTTL),
+		},
+	)
+}
+
+// activeRefreshToken returns the refresh token if it has been neither used nor revoked.
+//
+// The used token is presented again after the rotation. If the same client does it within the grace window,
+// it has most likely sent concurrent refresh requests, so ErrRefreshTokenUsed is returned.
+// Otherwise the token could have been stolen: the whole session is revoked and the security event is saved.
+func (a *Auth) activeRefreshToken(ctx context.Context,
+	log *slog.Logger,
+	token string,
+	ip net.IP,
+) (models.RefreshToken, error) {
+	t, err := a.refreshTokenProvider.GetRefreshToken(ctx, token)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	if t.UsedAt.IsZero() {
+		return t, nil
+	}
+
+	log = log.With(
+		slog.Uint64("userId", t.UserId),
+		slog.Uint64("sessionId", t.SessionId),
+	)
+
+	if time.Since(t.UsedAt) <= a.refreshTokenReuseGrace && t.UsedBy.Equal(ip) {
+		log.Warn("refresh token has been used by a concurrent request")
+
+		return models.RefreshToken{}, ErrRefreshTokenUsed
+	}
+
+	log.Warn(
+		"refresh token reuse detected, revoking the session",
+		slog.String("event", models.SecurityEventRefreshTokenReuse),
+		slog.Any("ip", ip),
+	)
+
+	err = a.refreshTokenProvider.RevokeSession(ctx, t.UserId, t.SessionId, ip)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return models.RefreshToken{}, err
+	}
+
+	err = a.revokeAccessTokens(ctx, t.UserId, t.SessionId)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	err = a.securityEventSaver.SaveSecurityEvent(
+		ctx, models.SecurityEvent{
+			Type:      models.SecurityEventRefreshTokenReuse,
+			UserId:    t.UserId,
+			SessionId: t.SessionId,
+			IP:        ip,
+			CreatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	return models.RefreshToken{}, ErrRefreshTokenRevoked
+}
+
+// refreshTokenError maps the error of the refresh token storage to the error of the transport layer.
+func refreshTokenError(err error) error {
+	switch {
+	case errors.Is(err, ErrRefreshTokenNotFound):
+		return auth.ErrTokenNotFound
+	case errors.Is(err, ErrRefreshTokenRevoked):
+		return auth.ErrTokenRevoked
+	case errors.Is(err, ErrRefreshTokenUsed):
+		return auth.ErrTokenUsed
+	case errors.Is(err, ErrSessionNotFound):
+		return auth.ErrSessionNotFound
+	case errors.Is(err, ErrInternal):
+		return auth.ErrInternal
+	default:
+		return auth.ErrUnknown
+	}
+}
+
+// appError maps the error of the app storage to the error of the transport layer.
+func appError(err error) error {
+	switch {
+	case errors.Is(err, ErrAppNotFound):
+		return auth.ErrAppNotFound
+	case errors.Is(err, ErrGrantNotAllowed):
+		return auth.ErrGrantNotAllowed
+	case errors.Is(err, ErrInternal):
+		return auth.ErrInternal
+	default:
+		return auth.ErrUnknown
+	}
+}
+
+// genRandomString generates the random string of the letters and the digits, e.g. the refresh token.
+// The refresh tokens must not be guessable, so they are generated with crypto/rand.
+func genRandomString(length uint) (string, error) {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	b := make([]byte, length)
+	n := big.NewInt(int64(len(chars)))
 
 	for i := range b {
-		b[i] = chars[rand.Intn(len(chars))]
+		k, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+
+		b[i] = chars[k.Int64()]
 	}
 
-	return string(b)
+	return string(b), nil
 }
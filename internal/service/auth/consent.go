@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/auth"
+	"github.com/puregrade-group/sso/internal/transport/http/oauth"
+)
+
+// Consent completes the authorization that is waiting for the consent of the user and returns the code for the app.
+// If the user approves, the scopes of the code are added to the grant of the user to the app, so they are not asked
+// for again. If the user denies, the code is deleted and oauth.ErrAccessDenied is returned.
+//
+// The code must have been issued by Authorize for the same app and redirect URI.
+func (a *Auth) Consent(ctx context.Context,
+	req models.AuthorizationRequest,
+	code string,
+	approved bool,
+) (string, error) {
+	const op = "Auth.Consent"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(req.AppId)),
+		slog.Bool("approved", approved),
+	)
+
+	if !approved {
+		c, err := a.authCodeProvider.ConsumeAuthorizationCode(ctx, code)
+		if err != nil {
+			log.Error(err.Error())
+
+			return "", consentCodeError(err)
+		}
+
+		if !c.ConsentPending || c.AppId != req.AppId || c.RedirectURI != req.RedirectURI {
+			log.Warn("authorization code does not match the consent request")
+
+			return "", oauth.ErrInvalidConsent
+		}
+
+		log.Info("user denied the authorization", slog.Uint64("userId", c.UserId))
+
+		return "", oauth.ErrAccessDenied
+	}
+
+	c, err := a.authCodeProvider.ConsentAuthorizationCode(ctx, code)
+	if err != nil {
+		log.Error(err.Error())
+
+		return "", consentCodeError(err)
+	}
+
+	log = log.With(slog.Uint64("userId", c.UserId))
+
+	if c.AppId != req.AppId || c.RedirectURI != req.RedirectURI {
+		log.Warn("authorization code does not match the consent request")
+
+		// The code must not be exchanged by the app the user has not consented to
+		if _, err = a.authCodeProvider.ConsumeAuthorizationCode(ctx, code); err != nil {
+			log.Error(err.Error())
+		}
+
+		return "", oauth.ErrInvalidConsent
+	}
+
+	if err = a.grantScopes(ctx, c.UserId, c.AppId, c.Scopes); err != nil {
+		log.Error(err.Error())
+
+		return "", oauth.ErrInternal
+	}
+
+	log.Info("app authorized", slog.Any("scopes", c.Scopes))
+
+	return c.Code, nil
+}
+
+// ListGrants returns the grants of the user to whom the given token was issued.
+func (a *Auth) ListGrants(ctx context.Context,
+	token string,
+	ip net.IP,
+) (grants []models.Grant, err error) {
+	const op = "Auth.ListGrants"
+
+	log := a.log.With(slog.String("op", op))
+
+	t, err := a.activeRefreshToken(ctx, log, token, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return nil, refreshTokenError(err)
+	}
+
+	grants, err = a.grantProvider.GetGrants(ctx, t.UserId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return nil, auth.ErrInternal
+	}
+
+	return grants, nil
+}
+
+// RevokeGrant deletes the grant of the user to whom the given token was issued to the app
+// and revokes the sessions of the user in the app, so the app has to ask for the consent again.
+func (a *Auth) RevokeGrant(ctx context.Context,
+	token string,
+	appId int32,
+	ip net.IP,
+) (err error) {
+	const op = "Auth.RevokeGrant"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(appId)),
+	)
+
+	log.Info("attempting to revoke grant")
+
+	t, err := a.activeRefreshToken(ctx, log, token, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return refreshTokenError(err)
+	}
+
+	err = a.grantProvider.DeleteGrant(ctx, t.UserId, appId)
+	if err != nil {
+		log.Error(err.Error())
+
+		switch {
+		case errors.Is(err, ErrGrantNotFound):
+			return auth.ErrGrantNotFound
+		case errors.Is(err, ErrInternal):
+			return auth.ErrInternal
+		default:
+			return auth.ErrUnknown
+		}
+	}
+
+	sessions, err := a.refreshTokenProvider.GetSessions(ctx, t.UserId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	for _, session := range sessions {
+		if session.AppId != appId {
+			continue
+		}
+
+		// The session could have been revoked since it was listed
+		err = a.refreshTokenProvider.RevokeSession(ctx, t.UserId, session.Id, ip)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			log.Error(err.Error())
+
+			return auth.ErrInternal
+		}
+
+		if err = a.revokeAccessTokens(ctx, t.UserId, session.Id); err != nil {
+			log.Error(err.Error())
+
+			return auth.ErrInternal
+		}
+	}
+
+	log.Info("grant revoked")
+
+	return nil
+}
+
+// consentRequired reports whether the user must consent to the scopes before they are granted to the app.
+// The first-party apps are trusted, the third-party ones are asked for the consent once for every scope.
+func (a *Auth) consentRequired(ctx context.Context, app models.App, userId uint64, scopes []string) (bool, error) {
+	if app.FirstParty {
+		return false, nil
+	}
+
+	grant, err := a.grantProvider.GetGrant(ctx, userId, app.Id)
+	if err != nil {
+		if errors.Is(err, ErrGrantNotFound) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return !hasScopes(grant.Scopes, scopes), nil
+}
+
+// grantScopes adds the scopes to the grant of the user to the app, the grant is created if there is none.
+func (a *Auth) grantScopes(ctx context.Context, userId uint64, appId int32, scopes []string) error {
+	now := time.Now()
+
+	grant, err := a.grantProvider.GetGrant(ctx, userId, appId)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrGrantNotFound):
+		grant = models.Grant{UserId: userId, AppId: appId, CreatedAt: now}
+	default:
+		return err
+	}
+
+	for _, scope := range scopes {
+		if !models.HasScope(grant.Scopes, scope) {
+			grant.Scopes = append(grant.Scopes, scope)
+		}
+	}
+
+	grant.UpdatedAt = now
+
+	return a.grantProvider.SaveGrant(ctx, grant)
+}
+
+// consentCodeError maps the error of the authorization code storage to the error of the consent.
+func consentCodeError(err error) error {
+	switch {
+	case errors.Is(err, ErrAuthorizationCodeNotFound):
+		return oauth.ErrInvalidConsent
+	case errors.Is(err, ErrInternal):
+		return oauth.ErrInternal
+	default:
+		return oauth.ErrUnknown
+	}
+}
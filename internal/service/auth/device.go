@@ -0,0 +1,424 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/http/oauth"
+)
+
+// userCodeAlphabet has no vowels, so the user codes do not spell words,
+// and no characters that look alike (RFC 8628, section 6.1)
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeLen is the number of characters of the user code, it gives about 34 bits of entropy
+const userCodeLen = 8
+
+// userCodeAttempts is the number of times the new user code is generated if it is taken
+const userCodeAttempts = 3
+
+// userCodeFailures is the number of the failed sign-ins on the device page the user code is refused after
+const userCodeFailures = 5
+
+// slowDownStep is the interval the polling is slowed down by every time it is too frequent (RFC 8628, section 3.5)
+const slowDownStep = 5 * time.Second
+
+// AuthorizeDevice issues the device code and the user code for the app (RFC 8628, section 3.2).
+// The user approves the device by the user code, meanwhile the device polls the token endpoint
+// no more often than the interval. Only the supported scopes of the requested ones are granted.
+func (a *Auth) AuthorizeDevice(ctx context.Context,
+	client models.ClientCredentials,
+	scopes []string,
+	device models.Device,
+) (models.DeviceCode, error) {
+	const op = "Auth.AuthorizeDevice"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(client.AppId)),
+	)
+
+	log.Info("attempting to authorize device")
+
+	if _, err := a.authenticateClient(ctx, client, models.GrantDeviceCode); err != nil {
+		log.Error(err.Error())
+
+		return models.DeviceCode{}, err
+	}
+
+	deviceCode, err := genCode()
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.DeviceCode{}, oauth.ErrInternal
+	}
+
+	now := time.Now()
+
+	c := models.DeviceCode{
+		DeviceCode: deviceCode,
+		AppId:      client.AppId,
+		Scopes:     a.supportedScopes(scopes),
+		Interval:   a.deviceCodeInterval,
+		UserAgent:  device.UserAgent,
+		CreatedBy:  device.IP,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(a.deviceCodeTTL),
+	}
+
+	for i := 0; i < userCodeAttempts; i++ {
+		c.UserCode, err = genUserCode()
+		if err != nil {
+			break
+		}
+
+		err = a.deviceCodeProvider.SaveDeviceCode(ctx, c)
+		if !errors.Is(err, ErrUserCodeAlreadyExists) {
+			break
+		}
+	}
+
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.DeviceCode{}, oauth.ErrInternal
+	}
+
+	log.Info("device code issued")
+
+	return c, nil
+}
+
+// ReviewDevice checks the credentials of the user and returns the name of the app and the scopes the device
+// that requested the user code asks for, so the user sees what is approved (RFC 8628, section 5.4).
+// Nothing of the device is shown before the user signs in, and the failed sign-ins are limited
+// by the IP address and by the user code, so the user codes can not be guessed (RFC 8628, section 5.1).
+// The code of the review binds the approval to the user that signed in.
+func (a *Auth) ReviewDevice(ctx context.Context,
+	creds models.Credentials,
+	userCode string,
+	ip net.IP,
+) (models.Authorization, error) {
+	const op = "Auth.ReviewDevice"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("ip", ip.String()),
+	)
+
+	log.Info("attempting to review device")
+
+	now := time.Now()
+	ipKey := ip.String()
+
+	if !a.deviceAttemptsByIP.Allow(ipKey, now) || !a.deviceAttemptsByCode.Allow(userCode, now) {
+		log.Warn("too many failed attempts to review device")
+
+		return models.Authorization{}, oauth.ErrTooManyAttempts
+	}
+
+	fail := func(err error) (models.Authorization, error) {
+		a.deviceAttemptsByIP.Fail(ipKey, now)
+		a.deviceAttemptsByCode.Fail(userCode, now)
+
+		return models.Authorization{}, err
+	}
+
+	userId, err := a.verifyCredentials(ctx, creds)
+	if err != nil {
+		log.Error(err.Error())
+
+		switch {
+		case errors.Is(err, ErrUserNotFound), errors.Is(err, errWrongPassword):
+			return fail(oauth.ErrWrongCredentials)
+		case errors.Is(err, ErrInternal):
+			return models.Authorization{}, oauth.ErrInternal
+		default:
+			return models.Authorization{}, oauth.ErrUnknown
+		}
+	}
+
+	log = log.With(slog.Uint64("userId", userId))
+
+	c, app, err := a.pendingDevice(ctx, log, userCode)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidUserCode) {
+			return fail(err)
+		}
+
+		return models.Authorization{}, err
+	}
+
+	reviewCode, err := genCode()
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Authorization{}, oauth.ErrInternal
+	}
+
+	if err = a.deviceCodeProvider.ReviewDeviceCode(ctx, userCode, userId, reviewCode); err != nil {
+		log.Error(err.Error())
+
+		return models.Authorization{}, userCodeError(err)
+	}
+
+	log.Info("device reviewed")
+
+	return models.Authorization{
+		Code:    reviewCode,
+		AppName: app.Name,
+		Scopes:  c.Scopes,
+	}, nil
+}
+
+// ApproveDevice approves the device that requested the user code on behalf of the user that reviewed it
+// with the code of the review, so the device gets the tokens of the user at the next poll.
+// The user approves the device on the page that shows the app and the scopes, so the approval is the consent
+// and the scopes are added to the grant of the user to the third-party app, as Consent does.
+func (a *Auth) ApproveDevice(ctx context.Context,
+	userCode string,
+	reviewCode string,
+) (err error) {
+	const op = "Auth.ApproveDevice"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("attempting to approve device")
+
+	c, app, err := a.pendingDevice(ctx, log, userCode)
+	if err != nil {
+		return err
+	}
+
+	if c.ReviewedBy == 0 || subtle.ConstantTimeCompare([]byte(c.ReviewCode), []byte(reviewCode)) != 1 {
+		log.Warn("device has not been reviewed with the code")
+
+		return oauth.ErrInvalidUserCode
+	}
+
+	log = log.With(slog.Uint64("userId", c.ReviewedBy))
+
+	scopes, err := a.grantedScopes(ctx, c.ReviewedBy, c.Scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return oauth.ErrInternal
+	}
+
+	consentRequired, err := a.consentRequired(ctx, app, c.ReviewedBy, scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return oauth.ErrInternal
+	}
+
+	err = a.deviceCodeProvider.ApproveDeviceCode(ctx, userCode, reviewCode, time.Now())
+	if err != nil {
+		log.Error(err.Error())
+
+		return userCodeError(err)
+	}
+
+	if consentRequired {
+		if err = a.grantScopes(ctx, c.ReviewedBy, app.Id, scopes); err != nil {
+			log.Error(err.Error())
+
+			return oauth.ErrInternal
+		}
+
+		log.Info("user consented to the scopes", slog.Any("scopes", scopes))
+	}
+
+	log.Info("device approved")
+
+	return nil
+}
+
+// ExchangeDeviceCode exchanges the approved device code for the tokens of the new session (RFC 8628, section 3.4).
+// The device that polls before the user approves it is told to wait, the one that polls too often is slowed down.
+// The code is deleted when it is exchanged or found expired, so it can not be exchanged twice.
+func (a *Auth) ExchangeDeviceCode(ctx context.Context,
+	client models.ClientCredentials,
+	deviceCode string,
+) (models.Tokens, error) {
+	const op = "Auth.ExchangeDeviceCode"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(client.AppId)),
+	)
+
+	app, err := a.authenticateClient(ctx, client, models.GrantDeviceCode)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, err
+	}
+
+	c, err := a.deviceCodeProvider.GetDeviceCode(ctx, deviceCode)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, deviceCodeError(err)
+	}
+
+	if c.AppId != client.AppId {
+		log.Warn("device code was issued for another app", slog.Int("codeAppId", int(c.AppId)))
+
+		return models.Tokens{}, oauth.ErrInvalidGrant
+	}
+
+	now := time.Now()
+
+	if !c.ExpiresAt.After(now) {
+		log.Info("device code has expired")
+
+		err = a.deviceCodeProvider.DeleteDeviceCode(ctx, deviceCode)
+		if err != nil && !errors.Is(err, ErrDeviceCodeNotFound) {
+			log.Error(err.Error())
+
+			return models.Tokens{}, oauth.ErrInternal
+		}
+
+		return models.Tokens{}, oauth.ErrExpiredToken
+	}
+
+	if !c.PolledAt.IsZero() && now.Sub(c.PolledAt) < c.Interval {
+		log.Warn("device polls too often", slog.Duration("interval", c.Interval))
+
+		if err = a.deviceCodeProvider.UpdateDeviceCodePoll(ctx, deviceCode, now, c.Interval+slowDownStep); err != nil {
+			log.Error(err.Error())
+
+			return models.Tokens{}, deviceCodeError(err)
+		}
+
+		return models.Tokens{}, oauth.ErrSlowDown
+	}
+
+	if c.UserId == 0 {
+		if err = a.deviceCodeProvider.UpdateDeviceCodePoll(ctx, deviceCode, now, c.Interval); err != nil {
+			log.Error(err.Error())
+
+			return models.Tokens{}, deviceCodeError(err)
+		}
+
+		return models.Tokens{}, oauth.ErrAuthorizationPending
+	}
+
+	// Only one of the concurrent polls deletes the code and gets the tokens
+	if err = a.deviceCodeProvider.DeleteDeviceCode(ctx, deviceCode); err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, deviceCodeError(err)
+	}
+
+	device := models.Device{
+		UserAgent: c.UserAgent,
+		IP:        c.CreatedBy,
+	}
+
+	// The permissions of the scopes are checked once the user is known
+	scopes, err := a.grantedScopes(ctx, c.UserId, c.Scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	// The grant may have been revoked since the approval
+	consentRequired, err := a.consentRequired(ctx, app, c.UserId, scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	if consentRequired {
+		log.Warn("user has not granted the scopes to the app", slog.Uint64("userId", c.UserId))
+
+		return models.Tokens{}, oauth.ErrAccessDenied
+	}
+
+	tokens, err := a.issueAppTokens(ctx, log, c.UserId, device, c.AppId, scopes, "")
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	log.Info("device code exchanged", slog.Uint64("userId", c.UserId))
+
+	return tokens, nil
+}
+
+// pendingDevice returns the device code of the user code that waits for the approval and its app.
+func (a *Auth) pendingDevice(ctx context.Context,
+	log *slog.Logger,
+	userCode string,
+) (models.DeviceCode, models.App, error) {
+	c, err := a.deviceCodeProvider.GetPendingDeviceCode(ctx, userCode)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.DeviceCode{}, models.App{}, userCodeError(err)
+	}
+
+	app, err := a.appProvider.GetApp(ctx, c.AppId)
+	if err != nil {
+		log.Error(err.Error())
+
+		if errors.Is(err, ErrAppNotFound) {
+			return models.DeviceCode{}, models.App{}, oauth.ErrInvalidUserCode
+		}
+
+		return models.DeviceCode{}, models.App{}, oauth.ErrInternal
+	}
+
+	return c, app, nil
+}
+
+// genUserCode generates the random user code of the characters of the user code alphabet.
+func genUserCode() (string, error) {
+	b := make([]byte, userCodeLen)
+	n := big.NewInt(int64(len(userCodeAlphabet)))
+
+	for i := range b {
+		k, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+
+		b[i] = userCodeAlphabet[k.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// userCodeError maps the error of the device code storage to the error of the device page.
+func userCodeError(err error) error {
+	switch {
+	case errors.Is(err, ErrDeviceCodeNotFound):
+		return oauth.ErrInvalidUserCode
+	case errors.Is(err, ErrInternal):
+		return oauth.ErrInternal
+	default:
+		return oauth.ErrUnknown
+	}
+}
+
+// deviceCodeError maps the error of the device code storage to the error of the oauth transport.
+func deviceCodeError(err error) error {
+	switch {
+	case errors.Is(err, ErrDeviceCodeNotFound):
+		return oauth.ErrInvalidGrant
+	case errors.Is(err, ErrInternal):
+		return oauth.ErrInternal
+	default:
+		return oauth.ErrUnknown
+	}
+}
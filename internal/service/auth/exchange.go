@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/http/oauth"
+	"github.com/puregrade-group/sso/pkg/jwt"
+)
+
+// Permissions the app must be granted in ACS to exchange the tokens of the users
+const (
+	permissionResourceToken = "token"
+	permissionImpersonate   = "impersonate"
+	permissionDelegate      = "delegate"
+
+	// The action of the permission is the audience the app may exchange the tokens for
+	permissionResourceAudience = "audience"
+)
+
+// ExchangeToken exchanges the access token of the user for the one intended for the audience (RFC 8693).
+// Without the actor token the app impersonates the user, with it the subject of the actor token acts
+// on behalf of the user and is put in the "act" claim, so the app must be granted
+// the token:impersonate or the token:delegate permission respectively.
+// Both tokens must be issued to the app: the subject token is the one the user signed in to the app with,
+// the actor token is the client credentials token of the app or the token of another user signed in to it.
+//
+// The new token belongs to the session of the subject token, so it is revoked with the session,
+// and it outlives neither of the exchanged tokens. Its scopes may only be narrowed.
+// Every exchange is saved as the security event for audit.
+func (a *Auth) ExchangeToken(ctx context.Context,
+	client models.ClientCredentials,
+	req models.TokenExchange,
+	ip net.IP,
+) (models.Tokens, error) {
+	const op = "Auth.ExchangeToken"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(client.AppId)),
+	)
+
+	log.Info("attempting to exchange token")
+
+	if client.Secret == "" {
+		log.Warn("public app can not exchange the tokens")
+
+		return models.Tokens{}, oauth.ErrInvalidClient
+	}
+
+	app, err := a.authenticateClient(ctx, client, models.GrantTokenExchange)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, err
+	}
+
+	eventType, action := models.SecurityEventTokenImpersonation, permissionImpersonate
+	if req.ActorToken != "" {
+		eventType, action = models.SecurityEventTokenDelegation, permissionDelegate
+	}
+
+	if !app.HasPermission(permissionResourceToken, action) {
+		log.Warn("app is not allowed to exchange the token", slog.String("action", action))
+
+		return models.Tokens{}, oauth.ErrExchangeNotAllowed
+	}
+
+	subject, err := a.activeAccessToken(ctx, req.SubjectToken)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	if subject == nil || subject.IsClient() {
+		log.Warn("subject token is not the active token of the user")
+
+		return models.Tokens{}, oauth.ErrInvalidSubjectToken
+	}
+
+	if subject.AppId != client.AppId {
+		log.Warn("subject token is issued to another app", slog.Int("subjectAppId", int(subject.AppId)))
+
+		return models.Tokens{}, oauth.ErrInvalidSubjectToken
+	}
+
+	log = log.With(slog.Uint64("userId", subject.UID))
+
+	// The prior actors of the subject token stay in the chain
+	actor := subject.Act
+	expiresAt := subject.ExpiresAt.Time
+
+	if req.ActorToken != "" {
+		var actorClaims *jwt.DefaultClaims
+
+		actorClaims, err = a.activeAccessToken(ctx, req.ActorToken)
+		if err != nil {
+			log.Error(err.Error())
+
+			return models.Tokens{}, oauth.ErrInternal
+		}
+
+		if actorClaims == nil {
+			log.Warn("actor token is not active")
+
+			return models.Tokens{}, oauth.ErrInvalidSubjectToken
+		}
+
+		if actorClaims.AppId != client.AppId {
+			log.Warn("actor token is issued to another app", slog.Int("actorAppId", int(actorClaims.AppId)))
+
+			return models.Tokens{}, oauth.ErrInvalidSubjectToken
+		}
+
+		actor = &jwt.Actor{Subject: actorClaims.SubjectId(), Act: subject.Act}
+
+		if actorClaims.ExpiresAt.Before(expiresAt) {
+			expiresAt = actorClaims.ExpiresAt.Time
+		}
+	}
+
+	if err = a.checkAudience(ctx, app, req.Audience); err != nil {
+		log.Warn("audience is not valid", slog.Any("audience", req.Audience), slog.String("error", err.Error()))
+
+		return models.Tokens{}, err
+	}
+
+	scopes := strings.Fields(subject.Scope)
+	if len(req.Scopes) != 0 {
+		if !hasScopes(scopes, req.Scopes) {
+			log.Warn("requested scopes exceed the subject token", slog.Any("scopes", req.Scopes))
+
+			return models.Tokens{}, oauth.ErrInvalidScope
+		}
+
+		scopes = narrowScopes(scopes, req.Scopes)
+	}
+
+	duration := time.Until(expiresAt)
+	if duration > a.accessTokenTTL {
+		duration = a.accessTokenTTL
+	}
+
+	key, err := a.keyProvider.SigningKey()
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	accessToken, err := jwt.NewExchangedToken(
+		a.accessTokenIssuer,
+		req.Audience,
+		subject.UID,
+		subject.SessionId,
+		client.AppId,
+		scopes,
+		actor,
+		duration,
+		key,
+	)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	event := models.SecurityEvent{
+		Type:      eventType,
+		UserId:    subject.UID,
+		SessionId: subject.SessionId,
+		AppId:     client.AppId,
+		IP:        ip,
+		CreatedAt: time.Now(),
+	}
+	if req.ActorToken != "" {
+		event.Actor = actor.Subject
+	}
+
+	// The token is not issued if the exchange can not be audited
+	if err = a.securityEventSaver.SaveSecurityEvent(ctx, event); err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	log.Warn("token exchanged", slog.String("event", eventType), slog.String("actor", event.Actor))
+
+	return models.Tokens{
+		AccessToken: accessToken,
+		Scopes:      scopes,
+		ExpiresIn:   duration,
+	}, nil
+}
+
+// checkAudience checks that the audience is not empty and the app is granted the audience:<value> permission
+// for every its value, so the admins list the audiences every app may exchange the tokens for.
+// The value is either the audience of the service or the client_id of the app, which must still be registered,
+// e.g. of the internal service the token is narrowed to.
+func (a *Auth) checkAudience(ctx context.Context, app models.App, audience []string) error {
+	if len(audience) == 0 {
+		return oauth.ErrInvalidTarget
+	}
+
+	for _, aud := range audience {
+		if !app.HasPermission(permissionResourceAudience, aud) {
+			return oauth.ErrInvalidTarget
+		}
+
+		if a.isServiceAudience(aud) {
+			continue
+		}
+
+		appId, err := strconv.ParseInt(aud, 10, 32)
+		if err != nil || appId <= 0 {
+			return oauth.ErrInvalidTarget
+		}
+
+		if _, err = a.appProvider.GetApp(ctx, int32(appId)); err != nil {
+			if errors.Is(err, ErrAppNotFound) {
+				return oauth.ErrInvalidTarget
+			}
+
+			return oauth.ErrInternal
+		}
+	}
+
+	return nil
+}
+
+// isServiceAudience reports whether the audience is one of the audiences of the tokens issued without an app.
+func (a *Auth) isServiceAudience(audience string) bool {
+	for _, aud := range a.accessTokenAudience {
+		if aud == audience {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasScopes reports whether the granted scopes include every requested one.
+func hasScopes(granted, requested []string) bool {
+	for _, scope := range requested {
+		if !models.HasScope(granted, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// narrowScopes returns the granted scopes that are requested, without duplicates.
+func narrowScopes(granted, requested []string) []string {
+	var res []string
+
+	for _, scope := range granted {
+		if models.HasScope(requested, scope) {
+			res = append(res, scope)
+		}
+	}
+
+	return res
+}
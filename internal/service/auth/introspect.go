@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/auth"
+	"github.com/puregrade-group/sso/internal/transport/http/introspect"
+	"github.com/puregrade-group/sso/pkg/jwt"
+)
+
+// Introspect returns the state of the access or refresh token (RFC 7662) to the resource server
+// whose access token is granted the token:introspect permission.
+// The token is active if it is valid, unexpired, not revoked and its user still exists.
+// The hint only sets which token type is checked first.
+func (a *Auth) Introspect(ctx context.Context,
+	requesterToken string,
+	token string,
+	tokenTypeHint string,
+) (models.Introspection, error) {
+	const op = "Auth.Introspect"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("hint", tokenTypeHint),
+	)
+
+	err := a.authorizeRequester(ctx, log,
+		models.Requester{Token: requesterToken}, permissionResourceToken, permissionIntrospect,
+	)
+	if err != nil {
+		return models.Introspection{}, err
+	}
+
+	return a.introspect(ctx, log, token, tokenTypeHint)
+}
+
+// IntrospectToken is Introspect of the introspection endpoint, the resource server authenticates
+// with the credentials of its app (RFC 7662, section 2.1) or with the access token.
+func (a *Auth) IntrospectToken(ctx context.Context,
+	requester models.Requester,
+	token string,
+	tokenTypeHint string,
+) (models.Introspection, error) {
+	const op = "Auth.IntrospectToken"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("hint", tokenTypeHint),
+	)
+
+	err := a.authorizeRequester(ctx, log, requester, permissionResourceToken, permissionIntrospect)
+	switch {
+	case err == nil: // Do nothing
+	case errors.Is(err, auth.ErrRequesterNotValid) && requester.Token != "":
+		return models.Introspection{}, introspect.ErrInvalidToken
+	case errors.Is(err, auth.ErrRequesterNotValid):
+		return models.Introspection{}, introspect.ErrInvalidClient
+	case errors.Is(err, auth.ErrPermissionDenied):
+		return models.Introspection{}, introspect.ErrNotEnoughPermissions
+	default:
+		return models.Introspection{}, introspect.ErrInternal
+	}
+
+	info, err := a.introspect(ctx, log, token, tokenTypeHint)
+	if err != nil {
+		return models.Introspection{}, introspect.ErrInternal
+	}
+
+	return info, nil
+}
+
+// introspect looks the token up, the hint only sets which token type is checked first.
+func (a *Auth) introspect(ctx context.Context,
+	log *slog.Logger,
+	token string,
+	tokenTypeHint string,
+) (models.Introspection, error) {
+	introspectors := []func(context.Context, string) (models.Introspection, error){
+		a.introspectAccessToken,
+		a.introspectRefreshToken,
+	}
+	if tokenTypeHint == models.TokenTypeRefresh {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+
+	for _, lookup := range introspectors {
+		info, err := lookup(ctx, token)
+		if err != nil {
+			log.Error(err.Error())
+
+			return models.Introspection{}, auth.ErrInternal
+		}
+
+		if info.Active {
+			log.Debug("token is active", slog.String("type", info.TokenType))
+
+			return info, nil
+		}
+	}
+
+	return models.Introspection{}, nil
+}
+
+// introspectAccessToken checks the signature and the registered claims of the access token
+// and that neither the token nor its session has been revoked.
+// The token of the user is active while the user exists, the token of the app itself while the app does.
+// The audience is returned, so the resource server checks it.
+func (a *Auth) introspectAccessToken(ctx context.Context, token string) (models.Introspection, error) {
+	claims, err := a.activeAccessToken(ctx, token)
+	if err != nil || claims == nil {
+		return models.Introspection{}, err
+	}
+
+	info := models.Introspection{
+		Active:    true,
+		TokenType: models.TokenTypeAccess,
+		TokenId:   claims.ID,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		UserId:    claims.UID,
+		AppId:     claims.AppId,
+		SessionId: claims.SessionId,
+		Scopes:    strings.Fields(claims.Scope),
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if claims.IssuedAt != nil {
+		info.IssuedAt = claims.IssuedAt.Time
+	}
+
+	return info, nil
+}
+
+// activeAccessToken returns the claims of the access token if it is valid, unexpired and not revoked
+// and its user or app still exists, otherwise nil.
+func (a *Auth) activeAccessToken(ctx context.Context, token string) (*jwt.DefaultClaims, error) {
+	claims, err := a.parseAccessToken(token)
+	if err != nil {
+		return nil, nil
+	}
+
+	if a.denylist.IsRevoked(claims.ID, claims.SessionId) {
+		return nil, nil
+	}
+
+	exists, err := a.subjectExists(ctx, claims)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// subjectExists reports whether the user or the app the access token was issued to still exists.
+func (a *Auth) subjectExists(ctx context.Context, claims *jwt.DefaultClaims) (bool, error) {
+	if !claims.IsClient() {
+		return a.usrProvider.UserExists(ctx, claims.UID)
+	}
+
+	_, err := a.appProvider.GetApp(ctx, claims.AppId)
+	if errors.Is(err, ErrAppNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// introspectRefreshToken looks the refresh token up in the storage.
+// Used tokens are inactive, but their reuse is not reported: it is not the client that presents them.
+func (a *Auth) introspectRefreshToken(ctx context.Context, token string) (models.Introspection, error) {
+	t, err := a.refreshTokenProvider.GetRefreshToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) || errors.Is(err, ErrRefreshTokenRevoked) {
+			return models.Introspection{}, nil
+		}
+
+		return models.Introspection{}, err
+	}
+
+	if !t.UsedAt.IsZero() {
+		return models.Introspection{}, nil
+	}
+
+	return models.Introspection{
+		Active:    true,
+		TokenType: models.TokenTypeRefresh,
+		UserId:    t.UserId,
+		AppId:     t.AppId,
+		SessionId: t.SessionId,
+		Scopes:    t.Scopes,
+		IssuedAt:  t.CreatedAt,
+		ExpiresAt: t.ExpiresIn,
+	}, nil
+}
@@ -0,0 +1,451 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/auth"
+	"github.com/puregrade-group/sso/internal/transport/http/oauth"
+	"github.com/puregrade-group/sso/pkg/jwt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// codeLen is the number of random bytes of the authorization and the device codes
+const codeLen = 32
+
+// CheckAuthorizationRequest checks that the app is registered with the redirect URI
+// and may use the authorization code grant.
+//
+// The user agent must not be redirected to the URI that is not registered,
+// so the errors of the client and of the redirect URI are told from the other ones.
+func (a *Auth) CheckAuthorizationRequest(ctx context.Context,
+	req models.AuthorizationRequest,
+) (err error) {
+	const op = "Auth.CheckAuthorizationRequest"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(req.AppId)),
+	)
+
+	_, err = a.authorizationApp(ctx, log, req)
+
+	return err
+}
+
+// Authorize checks the credentials of the user and issues the authorization code for the app.
+// The code expires after the authorization code TTL and is bound to the app, the redirect URI,
+// the PKCE challenge and the nonce of the request. Only the supported scopes of the requested ones are granted,
+// the "resource:action" ones if the roles of the user grant the permissions.
+//
+// The code for the third-party app is pending until the user consents to the scopes with Consent,
+// unless the user has granted all of them to the app already.
+func (a *Auth) Authorize(ctx context.Context,
+	creds models.Credentials,
+	device models.Device,
+	req models.AuthorizationRequest,
+) (models.Authorization, error) {
+	const op = "Auth.Authorize"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(req.AppId)),
+	)
+
+	log.Info("attempting to authorize app")
+
+	app, err := a.authorizationApp(ctx, log, req)
+	if err != nil {
+		return models.Authorization{}, err
+	}
+
+	userId, err := a.verifyCredentials(ctx, creds)
+	if err != nil {
+		log.Error(err.Error())
+
+		switch {
+		case errors.Is(err, ErrUserNotFound), errors.Is(err, errWrongPassword):
+			return models.Authorization{}, oauth.ErrWrongCredentials
+		case errors.Is(err, ErrInternal):
+			return models.Authorization{}, oauth.ErrInternal
+		default:
+			return models.Authorization{}, oauth.ErrUnknown
+		}
+	}
+
+	scopes, err := a.grantedScopes(ctx, userId, req.Scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Authorization{}, oauth.ErrInternal
+	}
+
+	consentRequired, err := a.consentRequired(ctx, app, userId, scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Authorization{}, oauth.ErrInternal
+	}
+
+	code, err := genCode()
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Authorization{}, oauth.ErrInternal
+	}
+
+	now := time.Now()
+
+	err = a.authCodeProvider.SaveAuthorizationCode(
+		ctx, models.AuthorizationCode{
+			Code:           code,
+			AppId:          req.AppId,
+			UserId:         userId,
+			RedirectURI:    req.RedirectURI,
+			CodeChallenge:  req.CodeChallenge,
+			Scopes:         scopes,
+			Nonce:          req.Nonce,
+			ConsentPending: consentRequired,
+			UserAgent:      device.UserAgent,
+			CreatedBy:      device.IP,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(a.authorizationCodeTTL),
+		},
+	)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Authorization{}, oauth.ErrInternal
+	}
+
+	if consentRequired {
+		log.Info("app is waiting for the consent of the user", slog.Uint64("userId", userId))
+	} else {
+		log.Info("app authorized", slog.Uint64("userId", userId))
+	}
+
+	return models.Authorization{
+		Code:            code,
+		ConsentRequired: consentRequired,
+		AppName:         app.Name,
+		Scopes:          scopes,
+	}, nil
+}
+
+// ExchangeAuthorizationCode exchanges the authorization code for the tokens of the new session,
+// the same ones Login issues for the app. The ID token is issued too if the openid scope is granted.
+//
+// The code is consumed before it is checked, so it can not be exchanged twice even by the failed attempt.
+// The redirect URI must be the one of the authorization request and the verifier must match its PKCE challenge.
+func (a *Auth) ExchangeAuthorizationCode(ctx context.Context,
+	client models.ClientCredentials,
+	code string,
+	redirectURI string,
+	codeVerifier string,
+) (models.Tokens, error) {
+	const op = "Auth.ExchangeAuthorizationCode"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(client.AppId)),
+	)
+
+	if _, err := a.authenticateClient(ctx, client, models.GrantAuthorizationCode); err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, err
+	}
+
+	c, err := a.authCodeProvider.ConsumeAuthorizationCode(ctx, code)
+	if err != nil {
+		log.Error(err.Error())
+
+		switch {
+		case errors.Is(err, ErrAuthorizationCodeNotFound):
+			return models.Tokens{}, oauth.ErrInvalidGrant
+		case errors.Is(err, ErrInternal):
+			return models.Tokens{}, oauth.ErrInternal
+		default:
+			return models.Tokens{}, oauth.ErrUnknown
+		}
+	}
+
+	switch {
+	case c.AppId != client.AppId:
+		log.Warn("authorization code was issued for another app", slog.Int("codeAppId", int(c.AppId)))
+
+		return models.Tokens{}, oauth.ErrInvalidGrant
+	case c.ConsentPending:
+		log.Warn("user has not consented to the scopes yet")
+
+		return models.Tokens{}, oauth.ErrInvalidGrant
+	case c.RedirectURI != redirectURI:
+		log.Warn("redirect uri does not match the authorization request")
+
+		return models.Tokens{}, oauth.ErrInvalidGrant
+	case !verifyCodeChallenge(c.CodeChallenge, codeVerifier):
+		log.Warn("code verifier does not match the code challenge")
+
+		return models.Tokens{}, oauth.ErrInvalidGrant
+	}
+
+	device := models.Device{
+		UserAgent: c.UserAgent,
+		IP:        c.CreatedBy,
+	}
+
+	tokens, err := a.issueAppTokens(ctx, log, c.UserId, device, c.AppId, c.Scopes, c.Nonce)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	log.Info("authorization code exchanged", slog.Uint64("userId", c.UserId))
+
+	return tokens, nil
+}
+
+// RefreshAppTokens refreshes the tokens of the app authenticated at the token endpoint.
+// The rotation and the reuse detection are the same as the ones of RefreshTokens.
+func (a *Auth) RefreshAppTokens(ctx context.Context,
+	client models.ClientCredentials,
+	token string,
+	ip net.IP,
+) (models.Tokens, error) {
+	const op = "Auth.RefreshAppTokens"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(client.AppId)),
+	)
+
+	if _, err := a.authenticateClient(ctx, client, models.GrantRefreshToken); err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, err
+	}
+
+	accessToken, refreshToken, err := a.RefreshTokens(ctx, token, ip, client.AppId)
+	switch err {
+	case nil: // Do nothing
+	case auth.ErrTokenNotFound, auth.ErrTokenRevoked, auth.ErrTokenUsed, auth.ErrSessionNotFound, auth.ErrAppMismatch:
+		return models.Tokens{}, oauth.ErrInvalidGrant
+	case auth.ErrAppNotFound:
+		return models.Tokens{}, oauth.ErrInvalidClient
+	case auth.ErrGrantNotAllowed:
+		return models.Tokens{}, oauth.ErrUnauthorizedClient
+	case auth.ErrInternal:
+		return models.Tokens{}, oauth.ErrInternal
+	default:
+		return models.Tokens{}, oauth.ErrUnknown
+	}
+
+	return models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    a.accessTokenTTL,
+	}, nil
+}
+
+// IssueClientToken issues the access token whose subject is the app itself (RFC 6749, section 4.4).
+// Only the confidential apps may use the client credentials grant, so the secret is required.
+// No refresh token is issued: the app obtains the new access token the same way.
+func (a *Auth) IssueClientToken(ctx context.Context,
+	client models.ClientCredentials,
+) (models.Tokens, error) {
+	const op = "Auth.IssueClientToken"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("appId", int(client.AppId)),
+	)
+
+	if client.Secret == "" {
+		log.Warn("public app can not use the client credentials grant")
+
+		return models.Tokens{}, oauth.ErrInvalidClient
+	}
+
+	if _, err := a.authenticateClient(ctx, client, models.GrantClientCredentials); err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, err
+	}
+
+	key, err := a.keyProvider.SigningKey()
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	accessToken, err := jwt.NewClientToken(a.accessTokenIssuer, client.AppId, a.accessTokenTTL, key)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	log.Info("client token issued")
+
+	return models.Tokens{
+		AccessToken: accessToken,
+		ExpiresIn:   a.accessTokenTTL,
+	}, nil
+}
+
+// issueAppTokens starts the session of the user on the device and issues the tokens for the app,
+// the ID token is issued too if the openid scope is granted.
+// The user could have been deleted since the grant, then the grant is not valid.
+func (a *Auth) issueAppTokens(ctx context.Context,
+	log *slog.Logger,
+	userId uint64,
+	device models.Device,
+	appId int32,
+	scopes []string,
+	nonce string,
+) (models.Tokens, error) {
+	exists, err := a.usrProvider.UserExists(ctx, userId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	if !exists {
+		log.Warn("user of the grant does not exist", slog.Uint64("userId", userId))
+
+		return models.Tokens{}, oauth.ErrInvalidGrant
+	}
+
+	accessToken, refreshToken, err := a.startSession(ctx, log, userId, device, appId, scopes)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.Tokens{}, oauth.ErrInternal
+	}
+
+	tokens := models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scopes:       scopes,
+		ExpiresIn:    a.accessTokenTTL,
+	}
+
+	if models.HasScope(scopes, models.ScopeOpenID) {
+		tokens.IDToken, err = a.newIDToken(ctx, userId, appId, scopes, nonce)
+		if err != nil {
+			log.Error(err.Error())
+
+			return models.Tokens{}, oauth.ErrInternal
+		}
+	}
+
+	return tokens, nil
+}
+
+// authenticateClient checks that the app exists and may use the grant type and returns the app.
+// The app which has the secret must present it, only the app without one may present none.
+func (a *Auth) authenticateClient(ctx context.Context,
+	client models.ClientCredentials,
+	grantType string,
+) (models.App, error) {
+	app, err := a.appProvider.GetApp(ctx, client.AppId)
+	if err != nil {
+		return models.App{}, oauthAppError(err)
+	}
+
+	switch {
+	case app.SecretHash == nil && client.Secret != "":
+		return models.App{}, oauth.ErrInvalidClient
+	case app.SecretHash != nil && client.Secret == "":
+		return models.App{}, oauth.ErrInvalidClient
+	case app.SecretHash != nil:
+		if err = bcrypt.CompareHashAndPassword(app.SecretHash, []byte(client.Secret)); err != nil {
+			return models.App{}, oauth.ErrInvalidClient
+		}
+	}
+
+	if !app.AllowsGrant(grantType) {
+		return models.App{}, oauth.ErrUnauthorizedClient
+	}
+
+	return app, nil
+}
+
+// authorizationApp returns the app of the authorization request, the app must be registered with the redirect URI
+// and may use the authorization code grant.
+func (a *Auth) authorizationApp(ctx context.Context,
+	log *slog.Logger,
+	req models.AuthorizationRequest,
+) (models.App, error) {
+	app, err := a.appProvider.GetApp(ctx, req.AppId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return models.App{}, oauthAppError(err)
+	}
+
+	if !hasRedirectURI(app, req.RedirectURI) {
+		log.Warn("redirect uri is not registered", slog.String("redirectUri", req.RedirectURI))
+
+		return models.App{}, oauth.ErrInvalidRedirectURI
+	}
+
+	if !app.AllowsGrant(models.GrantAuthorizationCode) {
+		log.Warn("app is not allowed to use the authorization code grant")
+
+		return models.App{}, oauth.ErrUnauthorizedClient
+	}
+
+	return app, nil
+}
+
+// hasRedirectURI reports whether the redirect URI is registered for the app.
+// The URIs are compared as strings (RFC 6749, section 3.1.2.3).
+func hasRedirectURI(app models.App, redirectURI string) bool {
+	for _, uri := range app.RedirectURIs {
+		if uri == redirectURI {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifyCodeChallenge checks the code verifier against the S256 code challenge (RFC 7636, section 4.6).
+func verifyCodeChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// genCode generates the random code safe to be put in the URI.
+func genCode() (string, error) {
+	b := make([]byte, codeLen)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// oauthAppError maps the error of the app storage to the error of the oauth transport.
+func oauthAppError(err error) error {
+	switch {
+	case errors.Is(err, ErrAppNotFound):
+		return oauth.ErrInvalidClient
+	case errors.Is(err, ErrInternal):
+		return oauth.ErrInternal
+	default:
+		return oauth.ErrUnknown
+	}
+}
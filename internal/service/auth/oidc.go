@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/http/oidc"
+	"github.com/puregrade-group/sso/pkg/jwt"
+)
+
+// errSymmetricIDToken is returned if the ID token would be signed with the shared secret
+var errSymmetricIDToken = errors.New("ID token can not be signed with the symmetric key")
+
+// UserInfo returns the claims about the user to whom the access token was issued (OpenID Connect Core 1.0, section 5.3).
+// The token must be granted the openid scope, the claims are limited to the other scopes granted to it.
+func (a *Auth) UserInfo(ctx context.Context,
+	accessToken string,
+) (models.UserInfo, error) {
+	const op = "Auth.UserInfo"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.parseAccessToken(accessToken)
+	if err != nil {
+		log.Warn("access token is not valid", slog.String("error", err.Error()))
+
+		return models.UserInfo{}, oidc.ErrInvalidToken
+	}
+
+	if a.denylist.IsRevoked(claims.ID, claims.SessionId) {
+		log.Warn("access token is revoked", slog.String("jti", claims.ID))
+
+		return models.UserInfo{}, oidc.ErrInvalidToken
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	if !models.HasScope(scopes, models.ScopeOpenID) {
+		return models.UserInfo{}, oidc.ErrInsufficientScope
+	}
+
+	info, err := a.usrProvider.GetUserInfo(ctx, claims.UID)
+	if err != nil {
+		log.Error(err.Error())
+
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			return models.UserInfo{}, oidc.ErrInvalidToken
+		case errors.Is(err, ErrInternal):
+			return models.UserInfo{}, oidc.ErrInternal
+		default:
+			return models.UserInfo{}, oidc.ErrUnknown
+		}
+	}
+
+	return scopedUserInfo(info, scopes), nil
+}
+
+// newIDToken creates the ID token of the user for the app signed with the current key of the key ring.
+// It lives as long as the access token issued with it. The ID token is never signed with the shared secret,
+// since every app verifying it would be able to forge the ones for the other apps.
+func (a *Auth) newIDToken(ctx context.Context,
+	userId uint64,
+	appId int32,
+	scopes []string,
+	nonce string,
+) (string, error) {
+	info, err := a.usrProvider.GetUserInfo(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	info = scopedUserInfo(info, scopes)
+
+	claims := jwt.IDClaims{
+		Nonce:      nonce,
+		Email:      info.Email,
+		GivenName:  info.FirstName,
+		FamilyName: info.LastName,
+	}
+	if !info.DateOfBirth.IsZero() {
+		claims.Birthdate = info.DateOfBirth.Format(models.BirthdateLayout)
+	}
+
+	key, err := a.keyProvider.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	if key.Symmetric() {
+		return "", errSymmetricIDToken
+	}
+
+	return jwt.NewIDToken(a.accessTokenIssuer, userId, appId, claims, a.accessTokenTTL, key)
+}
+
+// scopedUserInfo leaves only the claims the scopes grant: email for the email scope,
+// the brief profile for the profile one. The user id is always left.
+func scopedUserInfo(info models.UserInfo, scopes []string) models.UserInfo {
+	res := models.UserInfo{UserId: info.UserId}
+
+	if models.HasScope(scopes, models.ScopeEmail) {
+		res.Email = info.Email
+	}
+
+	if models.HasScope(scopes, models.ScopeProfile) {
+		res.BriefProfile = info.BriefProfile
+	}
+
+	return res
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RequestPasswordReset sends the password reset token to the email if the user with it exists.
+// Only the hash of the token is stored, the token expires after the password reset TTL
+// and replaces the one requested before.
+//
+// The result is the same whether the email is registered or not: the token is saved and sent after the response,
+// so the response does not take longer for the registered email, and the failures are logged, but not returned.
+func (a *Auth) RequestPasswordReset(ctx context.Context,
+	email string,
+	ip net.IP,
+) (err error) {
+	const op = "Auth.RequestPasswordReset"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("attempting to request password reset")
+
+	user, err := a.usrProvider.GetUserCreds(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			log.Info("password reset is requested for unknown email")
+
+			return nil
+		}
+
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	log = log.With(slog.Uint64("userId", user.Id))
+
+	a.resets.Add(1)
+
+	go func() {
+		defer a.resets.Done()
+
+		// The request may be done before the token is sent
+		a.sendPasswordReset(context.WithoutCancel(ctx), log, user, ip)
+	}()
+
+	return nil
+}
+
+// WaitPasswordResets waits until the requested password resets are saved and sent,
+// so they are not lost when the service stops.
+func (a *Auth) WaitPasswordResets() {
+	a.resets.Wait()
+}
+
+// sendPasswordReset saves the hash of the new reset token of the user and sends the token to the email.
+func (a *Auth) sendPasswordReset(ctx context.Context,
+	log *slog.Logger,
+	user models.UserCredentials,
+	ip net.IP,
+) {
+	token, err := genCode()
+	if err != nil {
+		log.Error(err.Error())
+
+		return
+	}
+
+	now := time.Now()
+
+	err = a.resetTokenProvider.SavePasswordResetToken(
+		ctx, models.PasswordResetToken{
+			TokenHash: hashResetToken(token),
+			UserId:    user.Id,
+			CreatedBy: ip,
+			CreatedAt: now,
+			ExpiresAt: now.Add(a.passwordResetTTL),
+		},
+	)
+	if err != nil {
+		log.Error(err.Error())
+
+		return
+	}
+
+	err = a.notifier.SendPasswordReset(ctx, user.Email, token, now.Add(a.passwordResetTTL))
+	if err != nil {
+		log.Error(err.Error())
+
+		return
+	}
+
+	log.Info("password reset token sent")
+}
+
+// ResetPassword sets the new password of the user the reset token was sent to.
+// The token is used only once. All the sessions of the user are revoked,
+// since the password could have been reset because the account was compromised.
+func (a *Auth) ResetPassword(ctx context.Context,
+	token string,
+	password string,
+	ip net.IP,
+) (err error) {
+	const op = "Auth.ResetPassword"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("attempting to reset password")
+
+	t, err := a.resetTokenProvider.ConsumePasswordResetToken(ctx, hashResetToken(token))
+	if err != nil {
+		log.Error(err.Error())
+
+		switch {
+		case errors.Is(err, ErrResetTokenNotFound):
+			return auth.ErrInvalidResetToken
+		case errors.Is(err, ErrInternal):
+			return auth.ErrInternal
+		default:
+			return auth.ErrUnknown
+		}
+	}
+
+	log = log.With(slog.Uint64("userId", t.UserId))
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), 5)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	err = a.usrSaver.UpdatePassword(ctx, t.UserId, passHash)
+	if err != nil {
+		log.Error(err.Error())
+
+		switch {
+		// The user has been deleted since the token was sent
+		case errors.Is(err, ErrUserNotFound):
+			return auth.ErrInvalidResetToken
+		case errors.Is(err, ErrInternal):
+			return auth.ErrInternal
+		default:
+			return auth.ErrUnknown
+		}
+	}
+
+	sessions, err := a.refreshTokenProvider.GetSessions(ctx, t.UserId)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	err = a.refreshTokenProvider.RevokeAll(ctx, t.UserId, ip)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	for _, session := range sessions {
+		if err = a.revokeAccessTokens(ctx, t.UserId, session.Id); err != nil {
+			log.Error(err.Error())
+
+			return auth.ErrInternal
+		}
+	}
+
+	err = a.securityEventSaver.SaveSecurityEvent(
+		ctx, models.SecurityEvent{
+			Type:      models.SecurityEventPasswordReset,
+			UserId:    t.UserId,
+			IP:        ip,
+			CreatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	log.Warn("password reset, all the sessions are revoked", slog.String("event", models.SecurityEventPasswordReset))
+
+	return nil
+}
+
+// hashResetToken returns the hex encoded SHA-256 hash of the password reset token, the tokens are stored hashed.
+// The token is random enough, so the hash does not need to be slow.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
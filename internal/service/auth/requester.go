@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Permissions the resource servers must be granted in ACS to read the state of the tokens
+const (
+	permissionIntrospect = "introspect"
+
+	permissionResourceRevocation = "revocation"
+	permissionWatch              = "watch"
+)
+
+// authorizeRequester checks that the resource server is granted the permission.
+// The app presents its credentials or its access token, the user presents the access token
+// and is granted the permission by the roles. The permissions are read on every request,
+// so the revoked ones take effect at once.
+func (a *Auth) authorizeRequester(ctx context.Context,
+	log *slog.Logger,
+	requester models.Requester,
+	resource, action string,
+) error {
+	app, userId, err := a.authenticateRequester(ctx, log, requester)
+	if err != nil {
+		return err
+	}
+
+	allowed := app.HasPermission(resource, action)
+
+	if userId != 0 {
+		roles, err := a.roleProvider.GetUserRoles(ctx, userId)
+		if err != nil {
+			log.Error(err.Error())
+
+			return auth.ErrInternal
+		}
+
+		allowed = hasPermissionScope(roles, resource+":"+action)
+	}
+
+	if !allowed {
+		log.Warn(
+			"requester is not granted the permission",
+			slog.String("permission", resource+":"+action),
+		)
+
+		return auth.ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// authenticateRequester returns the app of the requester or the user of its access token.
+func (a *Auth) authenticateRequester(ctx context.Context,
+	log *slog.Logger,
+	requester models.Requester,
+) (app models.App, userId uint64, err error) {
+	appId := requester.Client.AppId
+
+	if requester.Token != "" {
+		claims, err := a.activeAccessToken(ctx, requester.Token)
+		if err != nil {
+			log.Error(err.Error())
+
+			return models.App{}, 0, auth.ErrInternal
+		}
+
+		if claims == nil {
+			log.Warn("requester token is not active")
+
+			return models.App{}, 0, auth.ErrRequesterNotValid
+		}
+
+		if !claims.IsClient() {
+			return models.App{}, claims.UID, nil
+		}
+
+		appId = claims.AppId
+	}
+
+	app, err = a.appProvider.GetApp(ctx, appId)
+	if err != nil {
+		if errors.Is(err, ErrAppNotFound) {
+			log.Warn("app of the requester does not exist", slog.Int("appId", int(appId)))
+
+			return models.App{}, 0, auth.ErrRequesterNotValid
+		}
+
+		log.Error(err.Error())
+
+		return models.App{}, 0, auth.ErrInternal
+	}
+
+	if requester.Token == "" {
+		if app.SecretHash == nil || requester.Client.Secret == "" ||
+			bcrypt.CompareHashAndPassword(app.SecretHash, []byte(requester.Client.Secret)) != nil {
+			log.Warn("requester app failed to authenticate", slog.Int("appId", int(appId)))
+
+			return models.App{}, 0, auth.ErrRequesterNotValid
+		}
+	}
+
+	return app, 0, nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/auth"
+)
+
+// RevokeAccessToken denies the access token until it expires.
+// Like in RFC 7009, the invalid or expired token is not reported: there is nothing to revoke.
+func (a *Auth) RevokeAccessToken(ctx context.Context,
+	token string,
+) (err error) {
+	const op = "Auth.RevokeAccessToken"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("attempting to revoke access token")
+
+	claims, err := a.parseAccessToken(token)
+	if err != nil {
+		log.Info("token is not valid", slog.String("error", err.Error()))
+
+		return nil
+	}
+
+	if claims.ID == "" {
+		log.Info("token has no id")
+
+		return nil
+	}
+
+	err = a.denylist.Revoke(
+		ctx, models.Revocation{
+			TokenId:   claims.ID,
+			SessionId: claims.SessionId,
+			UserId:    claims.UID,
+			RevokedAt: time.Now(),
+			ExpiresAt: claims.ExpiresAt.Time,
+		},
+	)
+	if err != nil {
+		log.Error(err.Error())
+
+		return auth.ErrInternal
+	}
+
+	log.Info("access token revoked", slog.String("jti", claims.ID))
+
+	return nil
+}
+
+// WatchRevocations sends the active revocations and then the new ones as they come
+// until the context is done or send fails.
+// The subscriber that falls behind is dropped, it must watch again.
+// The requester must be granted the revocation:watch permission.
+func (a *Auth) WatchRevocations(ctx context.Context,
+	requesterToken string,
+	send func(revocation models.Revocation) error,
+) (err error) {
+	const op = "Auth.WatchRevocations"
+
+	log := a.log.With(slog.String("op", op))
+
+	err = a.authorizeRequester(ctx, log,
+		models.Requester{Token: requesterToken}, permissionResourceRevocation, permissionWatch,
+	)
+	if err != nil {
+		return err
+	}
+
+	active, revocations, unsubscribe := a.denylist.Subscribe()
+	defer unsubscribe()
+
+	log.Debug("subscriber is watching revocations", slog.Int("active", len(active)))
+
+	for _, r := range active {
+		if err = send(r); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case r, ok := <-revocations:
+			if !ok {
+				log.Warn("subscriber is too slow")
+
+				return auth.ErrSubscriberTooSlow
+			}
+
+			if err = send(r); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// grantedScopes returns the supported ones of the scopes the user may be granted without duplicates:
+// the scopes of OpenID Connect and the "resource:action" permissions the roles of the user grant.
+// The permissions are read on every call, so the ones the user has lost are not granted again.
+func (a *Auth) grantedScopes(ctx context.Context, userId uint64, scopes []string) ([]string, error) {
+	scopes = a.supportedScopes(scopes)
+
+	hasPermissionScopes := false
+	for _, scope := range scopes {
+		if models.IsPermissionScope(scope) {
+			hasPermissionScopes = true
+
+			break
+		}
+	}
+
+	if !hasPermissionScopes {
+		return scopes, nil
+	}
+
+	roles, err := a.roleProvider.GetUserRoles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+
+	for _, scope := range scopes {
+		if !models.IsPermissionScope(scope) || hasPermissionScope(roles, scope) {
+			res = append(res, scope)
+		}
+	}
+
+	return res, nil
+}
+
+// supportedScopes returns the supported ones of the scopes without duplicates: the scopes of OpenID Connect
+// and the scopes of the "resource:action" form, the user must have the permissions to be granted the latter.
+// The openid scope is not supported if the ID tokens can not be signed with the asymmetric keys.
+func (a *Auth) supportedScopes(scopes []string) []string {
+	var res []string
+
+	for _, scope := range models.SupportedScopes {
+		if scope == models.ScopeOpenID && !a.openID {
+			continue
+		}
+
+		if models.HasScope(scopes, scope) {
+			res = append(res, scope)
+		}
+	}
+
+	for _, scope := range scopes {
+		if models.IsPermissionScope(scope) && !models.HasScope(res, scope) {
+			res = append(res, scope)
+		}
+	}
+
+	return res
+}
+
+// hasPermissionScope reports whether any of the roles has the permission of the scope.
+func hasPermissionScope(roles []models.Role, scope string) bool {
+	for _, role := range roles {
+		for _, p := range role.Permissions {
+			if p.Scope() == scope {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,413 @@
+package keyring
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/pkg/jwt"
+)
+
+const keyIdLen = 8
+
+// KeyRing keeps the keys the access tokens are signed with.
+//
+// The newest activated key signs the tokens, older keys only verify them until they expire.
+// The rotated key is published in JWKS before it starts signing, so the resource servers
+// that cache the document know it in advance, and the retired keys stay published
+// until the last token signed with them expires.
+type KeyRing struct {
+	log *slog.Logger
+	// Database layer interfaces
+	keySaver    SigningKeySaver
+	keyProvider SigningKeyProvider
+	// Service configs
+	algorithm       string
+	masterKey       []byte
+	accessTokenTTL  time.Duration
+	rotationPeriod  time.Duration
+	promotionDelay  time.Duration
+	refreshInterval time.Duration
+
+	mu   sync.RWMutex
+	keys []ringKey // sorted by activation time
+}
+
+type ringKey struct {
+	meta models.SigningKey
+	key  *jwt.Key
+}
+
+var (
+	ErrSigningKeyExists  = errors.New("signing key already exists")
+	ErrKeyRotated        = errors.New("signing key has been rotated by another instance")
+	ErrNoSigningKey      = errors.New("there is no activated signing key")
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrMasterKeyRequired = errors.New("signing key is encrypted, but the master key is not configured")
+	ErrInternal          = errors.New("internal error")
+)
+
+// SigningKeySaver interface must be implemented by the repository layer
+type SigningKeySaver interface {
+	SaveSigningKey(ctx context.Context,
+		key models.SigningKey,
+	) (err error)
+	// RotateSigningKey saves the new key and sets the expiration time
+	// of all the keys which have not been retired yet.
+	// Unless rotatedBefore is zero, ErrKeyRotated is returned and nothing is saved
+	// if any key activates after it, so the concurrent rotations of the instances save one key.
+	RotateSigningKey(ctx context.Context,
+		key models.SigningKey,
+		retiredExpiresAt time.Time,
+		rotatedBefore time.Time,
+	) (err error)
+}
+
+// SigningKeyProvider interface must be implemented by the repository layer
+type SigningKeyProvider interface {
+	// GetSigningKeys returns the unexpired keys.
+	GetSigningKeys(ctx context.Context) (keys []models.SigningKey, err error)
+}
+
+func New(
+	log *slog.Logger,
+	keySaver SigningKeySaver,
+	keyProvider SigningKeyProvider,
+	algorithm string,
+	masterKey []byte,
+	accessTokenTTL time.Duration,
+	rotationPeriod time.Duration,
+	promotionDelay time.Duration,
+	refreshInterval time.Duration,
+) *KeyRing {
+	return &KeyRing{
+		log:             log,
+		keySaver:        keySaver,
+		keyProvider:     keyProvider,
+		algorithm:       algorithm,
+		masterKey:       masterKey,
+		accessTokenTTL:  accessTokenTTL,
+		rotationPeriod:  rotationPeriod,
+		promotionDelay:  promotionDelay,
+		refreshInterval: refreshInterval,
+	}
+}
+
+// Bootstrap loads the keys from the storage.
+// If there are none, the initial key is saved and becomes the current one.
+func (r *KeyRing) Bootstrap(ctx context.Context, initial func() (*jwt.Key, error)) error {
+	const op = "KeyRing.Bootstrap"
+
+	log := r.log.With(slog.String("op", op))
+
+	if err := r.Load(ctx); err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	empty := len(r.keys) == 0
+	r.mu.RUnlock()
+
+	if !empty {
+		return nil
+	}
+
+	key, err := initial()
+	if err != nil {
+		log.Error(err.Error())
+
+		return err
+	}
+
+	meta, err := r.signingKey(key, time.Now())
+	if err != nil {
+		log.Error(err.Error())
+
+		return err
+	}
+
+	// Other instance could have saved the first key concurrently, then its key is used
+	err = r.keySaver.SaveSigningKey(ctx, meta)
+	if err != nil && !errors.Is(err, ErrSigningKeyExists) {
+		log.Error(err.Error())
+
+		return err
+	}
+
+	log.Info("initial signing key is saved", slog.String("kid", key.Id))
+
+	return r.Load(ctx)
+}
+
+// Load reloads the keys from the storage, so the keys rotated by other instances are picked up.
+func (r *KeyRing) Load(ctx context.Context) error {
+	const op = "KeyRing.Load"
+
+	log := r.log.With(slog.String("op", op))
+
+	stored, err := r.keyProvider.GetSigningKeys(ctx)
+	if err != nil {
+		log.Error(err.Error())
+
+		return err
+	}
+
+	// Keys are immutable, so the already parsed ones are reused
+	r.mu.RLock()
+	parsed := make(map[string]*jwt.Key, len(r.keys))
+	for _, k := range r.keys {
+		parsed[k.meta.Id] = k.key
+	}
+	r.mu.RUnlock()
+
+	keys := make([]ringKey, 0, len(stored))
+
+	for _, meta := range stored {
+		key, ok := parsed[meta.Id]
+		if !ok {
+			key, err = r.parse(meta)
+			if err != nil {
+				log.Error(err.Error(), slog.String("kid", meta.Id))
+
+				return err
+			}
+		}
+
+		keys = append(keys, ringKey{meta: meta, key: key})
+	}
+
+	sort.Slice(
+		keys, func(i, j int) bool {
+			return keys[i].meta.ActivatesAt.Before(keys[j].meta.ActivatesAt)
+		},
+	)
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Rotate generates the new key of the configured algorithm.
+// It starts signing after the promotion delay, the current key is retired then
+// and keeps verifying the tokens for the access token TTL.
+func (r *KeyRing) Rotate(ctx context.Context) (kid string, err error) {
+	return r.rotate(ctx, time.Time{})
+}
+
+// rotate rotates the key unless the newest key activates after rotatedBefore, zero time rotates it anyway.
+func (r *KeyRing) rotate(ctx context.Context, rotatedBefore time.Time) (kid string, err error) {
+	const op = "KeyRing.Rotate"
+
+	log := r.log.With(slog.String("op", op))
+
+	log.Info("attempting to rotate signing key")
+
+	key, err := jwt.GenerateKey(newKeyId(), r.algorithm)
+	if err != nil {
+		log.Error(err.Error())
+
+		return "", ErrInternal
+	}
+
+	activatesAt := time.Now().Add(r.promotionDelay)
+
+	meta, err := r.signingKey(key, activatesAt)
+	if err != nil {
+		log.Error(err.Error())
+
+		return "", err
+	}
+
+	err = r.keySaver.RotateSigningKey(ctx, meta, activatesAt.Add(r.accessTokenTTL), rotatedBefore)
+	if err != nil {
+		if errors.Is(err, ErrKeyRotated) {
+			log.Info("signing key has been rotated by another instance")
+
+			return "", r.Load(ctx)
+		}
+
+		log.Error(err.Error())
+
+		return "", err
+	}
+
+	log.Info(
+		"signing key is rotated",
+		slog.String("kid", key.Id),
+		slog.Time("activatesAt", activatesAt),
+	)
+
+	return key.Id, r.Load(ctx)
+}
+
+// Run reloads the keys every refresh interval and rotates them when the rotation period passes.
+// The instances rotate the keys on their own timers, but only the first of them saves the new key.
+// It returns when the context is done.
+func (r *KeyRing) Run(ctx context.Context) {
+	const op = "KeyRing.Run"
+
+	log := r.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.Load(ctx); err != nil {
+			continue
+		}
+
+		if !r.rotationDue() {
+			continue
+		}
+
+		if _, err := r.rotate(ctx, time.Now().Add(-r.rotationPeriod)); err != nil {
+			log.Error("scheduled rotation failed")
+		}
+	}
+}
+
+// SigningKey returns the key the new tokens must be signed with.
+func (r *KeyRing) SigningKey() (*jwt.Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		k := r.keys[i]
+		if !k.meta.ActivatesAt.After(now) && !expired(k.meta, now) {
+			return k.key, nil
+		}
+	}
+
+	return nil, ErrNoSigningKey
+}
+
+// VerificationKey returns the unexpired key with the given id.
+// Keys waiting for the activation are returned too, since other instances could have promoted them earlier.
+func (r *KeyRing) VerificationKey(_ context.Context, kid string) (*jwt.Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+
+	for _, k := range r.keys {
+		if k.meta.Id == kid && !expired(k.meta, now) {
+			return k.key, nil
+		}
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// JWKS returns the public keys of all unexpired keys.
+func (r *KeyRing) JWKS() jwt.JWKS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+
+	keys := make([]*jwt.Key, 0, len(r.keys))
+	for _, k := range r.keys {
+		if !expired(k.meta, now) {
+			keys = append(keys, k.key)
+		}
+	}
+
+	return jwt.NewJWKS(keys...)
+}
+
+// rotationDue reports whether the newest key, current or pending, is older than the rotation period.
+func (r *KeyRing) rotationDue() bool {
+	if r.rotationPeriod == 0 {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.keys) == 0 {
+		return false
+	}
+
+	newest := r.keys[len(r.keys)-1].meta
+
+	return !newest.ActivatesAt.Add(r.rotationPeriod).After(time.Now())
+}
+
+// signingKey prepares the key to be saved, its private part is sealed if the master key is configured.
+func (r *KeyRing) signingKey(key *jwt.Key, activatesAt time.Time) (models.SigningKey, error) {
+	private, err := key.MarshalPrivate()
+	if err != nil {
+		return models.SigningKey{}, ErrInternal
+	}
+
+	meta := models.SigningKey{
+		Id:          key.Id,
+		Algorithm:   key.Method.Alg(),
+		PrivateKey:  private,
+		CreatedAt:   time.Now(),
+		ActivatesAt: activatesAt,
+	}
+
+	if r.masterKey != nil {
+		meta.PrivateKey, err = seal(r.masterKey, private, key.Id)
+		if err != nil {
+			return models.SigningKey{}, ErrInternal
+		}
+
+		meta.Encrypted = true
+	}
+
+	return meta, nil
+}
+
+// parse restores the stored key.
+func (r *KeyRing) parse(meta models.SigningKey) (*jwt.Key, error) {
+	private := meta.PrivateKey
+
+	if meta.Encrypted {
+		if r.masterKey == nil {
+			return nil, ErrMasterKeyRequired
+		}
+
+		var err error
+
+		private, err = open(r.masterKey, private, meta.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := jwt.NewKey(meta.Id, meta.Algorithm, private)
+	if err != nil {
+		return nil, ErrInternal
+	}
+
+	return key, nil
+}
+
+func expired(meta models.SigningKey, now time.Time) bool {
+	return !meta.ExpiresAt.IsZero() && !meta.ExpiresAt.After(now)
+}
+
+func newKeyId() string {
+	b := make([]byte, keyIdLen)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
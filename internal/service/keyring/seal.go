@@ -0,0 +1,64 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// MasterKeyLen is the length of the AES-256 key the private keys are sealed with at rest.
+const MasterKeyLen = 32
+
+var ErrWrongMasterKey = errors.New("signing key can not be opened with the master key")
+
+// seal encrypts the private key with AES-GCM. The kid is authenticated too,
+// so the sealed key can not be moved to the row of another key.
+// The result is the random nonce followed by the ciphertext.
+func seal(masterKey, private []byte, kid string) ([]byte, error) {
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, private, []byte(kid)), nil
+}
+
+// open decrypts the private key sealed by seal.
+func open(masterKey, sealed []byte, kid string) ([]byte, error) {
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrWrongMasterKey
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	private, err := gcm.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return nil, ErrWrongMasterKey
+	}
+
+	return private, nil
+}
+
+func newGCM(masterKey []byte) (cipher.AEAD, error) {
+	if len(masterKey) != MasterKeyLen {
+		return nil, ErrWrongMasterKey
+	}
+
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -2,39 +2,64 @@ package profile
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
-	"github.com/google/uuid"
 	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/grpc/acs"
+	"github.com/puregrade-group/sso/internal/transport/grpc/profile"
+	"github.com/puregrade-group/sso/pkg/snowflake"
 )
 
 type Profile struct {
+	snowflake       *snowflake.Snowflake
 	log             *slog.Logger
 	profileProvider Provider
+	authorizer      Authorizer
 }
 
+// permissionResource is the resource of the ACS permissions to act on the profiles
+const permissionResource = "profile"
+
+var (
+	ErrProfileNotFound      = errors.New("profile not found")
+	ErrProfileAlreadyExists = errors.New("profile is already exists")
+	ErrInternal             = errors.New("internal error")
+)
+
 type Provider interface {
 	SaveProfile(ctx context.Context, profile models.Profile) error
-	GetProfile(ctx context.Context, profileId [16]byte) (models.Profile, error)
-	DeleteProfile(ctx context.Context, profileId [16]byte) error
+	UpdateProfile(ctx context.Context, profileId uint64, update models.ProfileUpdate) error
+	GetProfile(ctx context.Context, profileId uint64) (models.Profile, error)
+	DeleteProfile(ctx context.Context, profileId uint64) error
+}
+
+// Authorizer interface must be implemented by ACS
+type Authorizer interface {
+	Authorize(ctx context.Context, requesterToken string, resource, action string) error
 }
 
 func New(
 	log *slog.Logger,
+	snowflake *snowflake.Snowflake,
 	profileProvider Provider,
+	authorizer Authorizer,
 ) *Profile {
 	return &Profile{
+		snowflake:       snowflake,
 		log:             log,
 		profileProvider: profileProvider,
+		authorizer:      authorizer,
 	}
 }
 
 func (p *Profile) Create(ctx context.Context,
+	requesterToken string,
 	username string,
 	avatarHash string,
 	idp string,
 	accountId string,
-) (profileId [16]byte, err error) {
+) (profileId uint64, err error) {
 	const op = "profile.Create"
 
 	log := p.log.With(
@@ -42,7 +67,12 @@ func (p *Profile) Create(ctx context.Context,
 		slog.String("idp", idp),
 	)
 
-	profileId = uuid.New()
+	if err = p.authorize(ctx, log, requesterToken, "create"); err != nil {
+		return 0, err
+	}
+
+	// Profiles share the id space with users, so the same generator is used
+	profileId = p.snowflake.Generate()
 
 	err = p.profileProvider.SaveProfile(
 		ctx, models.Profile{
@@ -60,31 +90,57 @@ func (p *Profile) Create(ctx context.Context,
 				Value: slog.StringValue(err.Error()),
 			},
 		)
-		return [16]byte{}, err
+		return 0, storageError(err)
 	}
 
-	return profileId, err
+	return profileId, nil
 }
 
-func (p *Profile) Get(ctx context.Context,
-	profileId [16]byte,
-) (profile models.Profile, err error) {
-	const op = "profile.Get"
+// Update changes the non-nil fields of the update and leaves the others as they are.
+func (p *Profile) Update(ctx context.Context,
+	requesterToken string,
+	profileId uint64,
+	update models.ProfileUpdate,
+) (err error) {
+	const op = "profile.Update"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Uint64("profileId", profileId),
+	)
 
-	log := p.log.With(slog.String("op", op))
+	if err = p.authorize(ctx, log, requesterToken, "update"); err != nil {
+		return err
+	}
 
-	id, err := uuid.FromBytes(profileId[:])
+	err = p.profileProvider.UpdateProfile(ctx, profileId, update)
 	if err != nil {
 		log.Error(
-			"wrong uuid", slog.Attr{
+			"internal error", slog.Attr{
 				Key:   "error",
 				Value: slog.StringValue(err.Error()),
 			},
 		)
-		return models.Profile{}, err
+		return storageError(err)
 	}
 
-	log = p.log.With(slog.String("uuid", id.String()))
+	return nil
+}
+
+func (p *Profile) Get(ctx context.Context,
+	requesterToken string,
+	profileId uint64,
+) (profile models.Profile, err error) {
+	const op = "profile.Get"
+
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Uint64("profileId", profileId),
+	)
+
+	if err = p.authorize(ctx, log, requesterToken, "read"); err != nil {
+		return models.Profile{}, err
+	}
 
 	profile, err = p.profileProvider.GetProfile(ctx, profileId)
 	if err != nil {
@@ -94,42 +150,76 @@ func (p *Profile) Get(ctx context.Context,
 				Value: slog.StringValue(err.Error()),
 			},
 		)
-		return models.Profile{}, err
+		return models.Profile{}, storageError(err)
 	}
 
-	return profile, err
+	return profile, nil
 }
 
 func (p *Profile) Delete(ctx context.Context,
-	profileId [16]byte,
+	requesterToken string,
+	profileId uint64,
 ) (err error) {
 	const op = "profile.Delete"
 
-	log := p.log.With(slog.String("op", op))
+	log := p.log.With(
+		slog.String("op", op),
+		slog.Uint64("profileId", profileId),
+	)
+
+	if err = p.authorize(ctx, log, requesterToken, "delete"); err != nil {
+		return err
+	}
 
-	id, err := uuid.FromBytes(profileId[:])
+	err = p.profileProvider.DeleteProfile(ctx, profileId)
 	if err != nil {
 		log.Error(
-			"wrong uuid", slog.Attr{
+			"internal error", slog.Attr{
 				Key:   "error",
 				Value: slog.StringValue(err.Error()),
 			},
 		)
-		return err
+		return storageError(err)
 	}
 
-	log = p.log.With(slog.String("uuid", id.String()))
+	return nil
+}
 
-	err = p.profileProvider.DeleteProfile(ctx, profileId)
-	if err != nil {
+// authorize checks in ACS that the requester is granted the action on the profiles.
+func (p *Profile) authorize(ctx context.Context,
+	log *slog.Logger,
+	requesterToken string,
+	action string,
+) error {
+	err := p.authorizer.Authorize(ctx, requesterToken, permissionResource, action)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, acs.ErrTokenNotValid):
+		return profile.ErrTokenNotValid
+	case errors.Is(err, acs.ErrNotEnoughPermissions):
+		return profile.ErrNotEnoughPermissions
+	default:
 		log.Error(
 			"internal error", slog.Attr{
 				Key:   "error",
 				Value: slog.StringValue(err.Error()),
 			},
 		)
-		return err
+		return profile.ErrInternal
 	}
+}
 
-	return nil
+// storageError maps the error of the storage to the error of the transport layer.
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, ErrProfileNotFound):
+		return profile.ErrProfileNotFound
+	case errors.Is(err, ErrProfileAlreadyExists):
+		return profile.ErrProfileAlreadyExists
+	case errors.Is(err, ErrInternal):
+		return profile.ErrInternal
+	default:
+		return profile.ErrUnknown
+	}
 }
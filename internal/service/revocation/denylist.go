@@ -0,0 +1,248 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// subscriberBuffer is the number of revocations the subscriber may fall behind by.
+const subscriberBuffer = 64
+
+// Denylist keeps the revoked access tokens until they expire.
+//
+// The access tokens are self-contained, so they are checked against the revocations cached in memory.
+// Each revocation is cached for the remaining lifetime of the tokens it denies.
+// The revocations saved by other instances are loaded every refresh interval.
+type Denylist struct {
+	log *slog.Logger
+	// Database layer interfaces
+	revocationSaver    RevocationSaver
+	revocationProvider RevocationProvider
+	// Service configs
+	refreshInterval time.Duration
+
+	mu          sync.RWMutex
+	tokens      map[string]models.Revocation // by jti
+	sessions    map[uint64]models.Revocation // by session id
+	subscribers map[chan models.Revocation]struct{}
+}
+
+var ErrInternal = errors.New("internal error")
+
+// RevocationSaver interface must be implemented by the repository layer
+type RevocationSaver interface {
+	SaveRevocation(ctx context.Context,
+		revocation models.Revocation,
+	) (err error)
+	// DeleteExpiredRevocations deletes the revocations of the already expired tokens.
+	DeleteExpiredRevocations(ctx context.Context) (err error)
+}
+
+// RevocationProvider interface must be implemented by the repository layer
+type RevocationProvider interface {
+	// GetRevocations returns the unexpired revocations.
+	GetRevocations(ctx context.Context) (revocations []models.Revocation, err error)
+}
+
+func New(
+	log *slog.Logger,
+	revocationSaver RevocationSaver,
+	revocationProvider RevocationProvider,
+	refreshInterval time.Duration,
+) *Denylist {
+	return &Denylist{
+		log:                log,
+		revocationSaver:    revocationSaver,
+		revocationProvider: revocationProvider,
+		refreshInterval:    refreshInterval,
+		tokens:             make(map[string]models.Revocation),
+		sessions:           make(map[uint64]models.Revocation),
+		subscribers:        make(map[chan models.Revocation]struct{}),
+	}
+}
+
+// Revoke saves the revocation, it takes effect on this instance at once
+// and on the others after their next refresh.
+func (d *Denylist) Revoke(ctx context.Context, revocation models.Revocation) error {
+	const op = "Denylist.Revoke"
+
+	log := d.log.With(
+		slog.String("op", op),
+		slog.String("jti", revocation.TokenId),
+		slog.Uint64("sessionId", revocation.SessionId),
+	)
+
+	err := d.revocationSaver.SaveRevocation(ctx, revocation)
+	if err != nil {
+		log.Error(err.Error())
+
+		return err
+	}
+
+	d.mu.Lock()
+	d.add(revocation)
+	d.mu.Unlock()
+
+	log.Info("access tokens are revoked")
+
+	return nil
+}
+
+// Load caches the revocations from the storage, so the ones saved by other instances are picked up.
+// The expired revocations are evicted from the cache.
+func (d *Denylist) Load(ctx context.Context) error {
+	const op = "Denylist.Load"
+
+	log := d.log.With(slog.String("op", op))
+
+	revocations, err := d.revocationProvider.GetRevocations(ctx)
+	if err != nil {
+		log.Error(err.Error())
+
+		return err
+	}
+
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, r := range revocations {
+		d.add(r)
+	}
+
+	for jti, r := range d.tokens {
+		if !r.ExpiresAt.After(now) {
+			delete(d.tokens, jti)
+		}
+	}
+
+	for sessionId, r := range d.sessions {
+		if !r.ExpiresAt.After(now) {
+			delete(d.sessions, sessionId)
+		}
+	}
+
+	return nil
+}
+
+// Run reloads the revocations every refresh interval and deletes the expired ones from the storage.
+// It returns when the context is done.
+func (d *Denylist) Run(ctx context.Context) {
+	const op = "Denylist.Run"
+
+	log := d.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(d.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := d.Load(ctx); err != nil {
+			continue
+		}
+
+		if err := d.revocationSaver.DeleteExpiredRevocations(ctx); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
+// IsRevoked reports whether the token with the given id or the session it was issued for is revoked.
+func (d *Denylist) IsRevoked(tokenId string, sessionId uint64) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	now := time.Now()
+
+	if r, ok := d.tokens[tokenId]; ok && tokenId != "" && r.ExpiresAt.After(now) {
+		return true
+	}
+
+	if r, ok := d.sessions[sessionId]; ok && sessionId != 0 && r.ExpiresAt.After(now) {
+		return true
+	}
+
+	return false
+}
+
+// Subscribe returns the active revocations and the channel the new ones are sent to.
+// The channel is closed if the subscriber falls behind, so it must subscribe again.
+// Unsubscribe must be called once the revocations are not needed anymore.
+func (d *Denylist) Subscribe() (active []models.Revocation, revocations <-chan models.Revocation, unsubscribe func()) {
+	ch := make(chan models.Revocation, subscriberBuffer)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+
+	active = make([]models.Revocation, 0, len(d.tokens)+len(d.sessions))
+	for _, r := range d.tokens {
+		if r.ExpiresAt.After(now) {
+			active = append(active, r)
+		}
+	}
+	for _, r := range d.sessions {
+		if r.ExpiresAt.After(now) {
+			active = append(active, r)
+		}
+	}
+
+	d.subscribers[ch] = struct{}{}
+
+	return active, ch, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.unsubscribe(ch)
+	}
+}
+
+// add caches the revocation and sends it to the subscribers unless it is cached already.
+// It must be called with the lock held.
+func (d *Denylist) add(r models.Revocation) {
+	if r.TokenId != "" {
+		if cached, ok := d.tokens[r.TokenId]; ok && !cached.ExpiresAt.Before(r.ExpiresAt) {
+			return
+		}
+
+		d.tokens[r.TokenId] = r
+	} else {
+		if cached, ok := d.sessions[r.SessionId]; ok && !cached.ExpiresAt.Before(r.ExpiresAt) {
+			return
+		}
+
+		d.sessions[r.SessionId] = r
+	}
+
+	for ch := range d.subscribers {
+		select {
+		case ch <- r:
+		default:
+			d.log.Warn("revocations subscriber is too slow, dropping it")
+
+			d.unsubscribe(ch)
+		}
+	}
+}
+
+// unsubscribe closes the channel of the subscriber. It must be called with the lock held.
+func (d *Denylist) unsubscribe(ch chan models.Revocation) {
+	if _, ok := d.subscribers[ch]; !ok {
+		return
+	}
+
+	delete(d.subscribers, ch)
+	close(ch)
+}
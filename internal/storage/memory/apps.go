@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/acs"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// errAppNotFound is reported to both Auth and ACS, since both of them look the apps up.
+var errAppNotFound = fmt.Errorf("%w (%w)", acs.ErrAppNotFound, auth.ErrAppNotFound)
+
+// SaveApp saves new app with its permissions and returns the app id.
+func (s *Storage) SaveApp(_ context.Context,
+	app models.App,
+) (appId int32, err error) {
+	const op = "storage.memory.SaveApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.appNameTaken(app.Name, 0) {
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrAppAlreadyExists)
+	}
+
+	perms, err := s.appPermissionIds(app.Permissions)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.lastAppId++
+	app.Id = s.lastAppId
+	app.Secret = ""
+	app.Permissions = nil
+	app.CreatedAt = time.Now()
+	s.apps[app.Id] = app
+	s.appPermissions[app.Id] = perms
+
+	return app.Id, nil
+}
+
+// UpdateApp replaces the name, the redirect URIs, the grant types and the permissions of the app.
+func (s *Storage) UpdateApp(_ context.Context,
+	app models.App,
+) (err error) {
+	const op = "storage.memory.UpdateApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.apps[app.Id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrAppNotFound)
+	}
+
+	if s.appNameTaken(app.Name, app.Id) {
+		return fmt.Errorf("%s: %w", op, acs.ErrAppAlreadyExists)
+	}
+
+	perms, err := s.appPermissionIds(app.Permissions)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	old.Name = app.Name
+	old.FirstParty = app.FirstParty
+	old.RedirectURIs = app.RedirectURIs
+	old.GrantTypes = app.GrantTypes
+	s.apps[app.Id] = old
+	s.appPermissions[app.Id] = perms
+
+	return nil
+}
+
+// UpdateAppSecret replaces the secret hash of the app.
+func (s *Storage) UpdateAppSecret(_ context.Context,
+	appId int32,
+	secretHash []byte,
+) (err error) {
+	const op = "storage.memory.UpdateAppSecret"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.apps[appId]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrAppNotFound)
+	}
+
+	app.SecretHash = secretHash
+	s.apps[appId] = app
+
+	return nil
+}
+
+// GetApp returns the app with the given id.
+func (s *Storage) GetApp(_ context.Context, appId int32) (models.App, error) {
+	const op = "storage.memory.GetApp"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.apps[appId]; !ok {
+		return models.App{}, fmt.Errorf("%s: %w", op, errAppNotFound)
+	}
+
+	return s.app(appId), nil
+}
+
+// GetApps returns all the apps ordered by id.
+func (s *Storage) GetApps(_ context.Context) (apps []models.App, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	apps = make([]models.App, 0, len(s.apps))
+
+	for appId := range s.apps {
+		apps = append(apps, s.app(appId))
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Id < apps[j].Id
+	})
+
+	return apps, nil
+}
+
+// DeleteApp deletes the app with its redirect URIs, grant types, permissions and the grants of the users.
+func (s *Storage) DeleteApp(_ context.Context,
+	appId int32,
+) (err error) {
+	const op = "storage.memory.DeleteApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[appId]; !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrAppNotFound)
+	}
+
+	delete(s.apps, appId)
+	delete(s.appPermissions, appId)
+
+	for _, grants := range s.grants {
+		delete(grants, appId)
+	}
+
+	return nil
+}
+
+// app returns the app with its permissions. It must be called with the lock held.
+func (s *Storage) app(appId int32) models.App {
+	app := s.apps[appId]
+
+	for permId := range s.appPermissions[appId] {
+		app.Permissions = append(app.Permissions, s.permissions[permId])
+	}
+
+	sort.Slice(app.Permissions, func(i, j int) bool {
+		return app.Permissions[i].Id < app.Permissions[j].Id
+	})
+
+	return app
+}
+
+// appNameTaken reports whether any app but the given one has the name. It must be called with the lock held.
+func (s *Storage) appNameTaken(name string, appId int32) bool {
+	for id, app := range s.apps {
+		if id != appId && app.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appPermissionIds returns the set of the permission ids, all of them must exist.
+// It must be called with the lock held.
+func (s *Storage) appPermissionIds(permissions []models.Permission) (map[int32]struct{}, error) {
+	perms := make(map[int32]struct{}, len(permissions))
+
+	for _, p := range permissions {
+		if _, ok := s.permissions[p.Id]; !ok {
+			return nil, acs.ErrPermissionNotFound
+		}
+
+		perms[p.Id] = struct{}{}
+	}
+
+	return perms, nil
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveAuthorizationCode saves the authorization code and deletes the expired ones.
+func (s *Storage) SaveAuthorizationCode(_ context.Context,
+	code models.AuthorizationCode,
+) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for value, c := range s.authorizationCodes {
+		if !c.ExpiresAt.After(now) {
+			delete(s.authorizationCodes, value)
+		}
+	}
+
+	s.authorizationCodes[code.Code] = code
+
+	return nil
+}
+
+// ConsumeAuthorizationCode deletes the unexpired authorization code and returns it,
+// so the code can be exchanged only once.
+func (s *Storage) ConsumeAuthorizationCode(_ context.Context,
+	code string,
+) (models.AuthorizationCode, error) {
+	const op = "storage.memory.ConsumeAuthorizationCode"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.authorizationCodes[code]
+	if !ok {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrAuthorizationCodeNotFound)
+	}
+
+	delete(s.authorizationCodes, code)
+
+	if !c.ExpiresAt.After(time.Now()) {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrAuthorizationCodeNotFound)
+	}
+
+	return c, nil
+}
+
+// ConsentAuthorizationCode marks the unexpired pending authorization code as consented to and returns it,
+// so it can be exchanged for the tokens.
+func (s *Storage) ConsentAuthorizationCode(_ context.Context,
+	code string,
+) (models.AuthorizationCode, error) {
+	const op = "storage.memory.ConsentAuthorizationCode"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.authorizationCodes[code]
+	if !ok || !c.ConsentPending || !c.ExpiresAt.After(time.Now()) {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrAuthorizationCodeNotFound)
+	}
+
+	c.ConsentPending = false
+	s.authorizationCodes[code] = c
+
+	return c, nil
+}
@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveDeviceCode saves the device code and deletes the expired ones, so their user codes can be issued again.
+func (s *Storage) SaveDeviceCode(_ context.Context,
+	code models.DeviceCode,
+) (err error) {
+	const op = "storage.memory.SaveDeviceCode"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for value, c := range s.deviceCodes {
+		if !c.ExpiresAt.After(now) {
+			delete(s.deviceCodes, value)
+		}
+	}
+
+	for value, c := range s.deviceCodes {
+		if value == code.DeviceCode || c.UserCode == code.UserCode {
+			return fmt.Errorf("%s: %w", op, auth.ErrUserCodeAlreadyExists)
+		}
+	}
+
+	s.deviceCodes[code.DeviceCode] = code
+
+	return nil
+}
+
+// GetDeviceCode returns the device code, the expired one is returned too, so its expiration can be reported.
+func (s *Storage) GetDeviceCode(_ context.Context,
+	deviceCode string,
+) (models.DeviceCode, error) {
+	const op = "storage.memory.GetDeviceCode"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.deviceCodes[deviceCode]
+	if !ok {
+		return models.DeviceCode{}, fmt.Errorf("%s: %w", op, auth.ErrDeviceCodeNotFound)
+	}
+
+	return c, nil
+}
+
+// GetPendingDeviceCode returns the unexpired code of the user code that has not been approved yet.
+func (s *Storage) GetPendingDeviceCode(_ context.Context,
+	userCode string,
+) (models.DeviceCode, error) {
+	const op = "storage.memory.GetPendingDeviceCode"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	for _, c := range s.deviceCodes {
+		if c.UserCode == userCode && c.UserId == 0 && c.ExpiresAt.After(now) {
+			return c, nil
+		}
+	}
+
+	return models.DeviceCode{}, fmt.Errorf("%s: %w", op, auth.ErrDeviceCodeNotFound)
+}
+
+// ReviewDeviceCode binds the user that signed in to review the unexpired code that has not been approved yet
+// and the code of the review, the one of the previous review is replaced.
+func (s *Storage) ReviewDeviceCode(_ context.Context,
+	userCode string,
+	userId uint64,
+	reviewCode string,
+) (err error) {
+	const op = "storage.memory.ReviewDeviceCode"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for value, c := range s.deviceCodes {
+		if c.UserCode == userCode && c.UserId == 0 && c.ExpiresAt.After(now) {
+			c.ReviewedBy = userId
+			c.ReviewCode = reviewCode
+			s.deviceCodes[value] = c
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w", op, auth.ErrDeviceCodeNotFound)
+}
+
+// ApproveDeviceCode binds the user that reviewed the unexpired code that has not been approved yet to it,
+// the code reviewed again since is not approved.
+func (s *Storage) ApproveDeviceCode(_ context.Context,
+	userCode string,
+	reviewCode string,
+	approvedAt time.Time,
+) (err error) {
+	const op = "storage.memory.ApproveDeviceCode"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for value, c := range s.deviceCodes {
+		if c.UserCode == userCode && c.ReviewCode != "" && c.ReviewCode == reviewCode &&
+			c.UserId == 0 && c.ExpiresAt.After(approvedAt) {
+			c.UserId = c.ReviewedBy
+			c.ApprovedAt = approvedAt
+			s.deviceCodes[value] = c
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w", op, auth.ErrDeviceCodeNotFound)
+}
+
+// UpdateDeviceCodePoll saves the time the device polled at and the interval it must wait before the next poll.
+func (s *Storage) UpdateDeviceCodePoll(_ context.Context,
+	deviceCode string,
+	polledAt time.Time,
+	interval time.Duration,
+) (err error) {
+	const op = "storage.memory.UpdateDeviceCodePoll"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.deviceCodes[deviceCode]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrDeviceCodeNotFound)
+	}
+
+	c.PolledAt = polledAt
+	c.Interval = interval
+	s.deviceCodes[deviceCode] = c
+
+	return nil
+}
+
+// DeleteDeviceCode deletes the device code, so it can be exchanged only once.
+func (s *Storage) DeleteDeviceCode(_ context.Context,
+	deviceCode string,
+) (err error) {
+	const op = "storage.memory.DeleteDeviceCode"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deviceCodes[deviceCode]; !ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrDeviceCodeNotFound)
+	}
+
+	delete(s.deviceCodes, deviceCode)
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveGrant saves the grant of the user to the app or replaces its scopes, the time it was created at is kept.
+func (s *Storage) SaveGrant(_ context.Context,
+	grant models.Grant,
+) (err error) {
+	const op = "storage.memory.SaveGrant"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[grant.AppId]; !ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrAppNotFound)
+	}
+
+	if s.grants[grant.UserId] == nil {
+		s.grants[grant.UserId] = make(map[int32]models.Grant)
+	}
+
+	if old, ok := s.grants[grant.UserId][grant.AppId]; ok {
+		grant.CreatedAt = old.CreatedAt
+	}
+
+	grant.AppName = ""
+	s.grants[grant.UserId][grant.AppId] = grant
+
+	return nil
+}
+
+// GetGrant returns the grant of the user to the app.
+func (s *Storage) GetGrant(_ context.Context,
+	userId uint64,
+	appId int32,
+) (models.Grant, error) {
+	const op = "storage.memory.GetGrant"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.grants[userId][appId]
+	if !ok {
+		return models.Grant{}, fmt.Errorf("%s: %w", op, auth.ErrGrantNotFound)
+	}
+
+	g.AppName = s.apps[appId].Name
+
+	return g, nil
+}
+
+// GetGrants returns the grants of the user, the last updated first.
+func (s *Storage) GetGrants(_ context.Context,
+	userId uint64,
+) (grants []models.Grant, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	grants = make([]models.Grant, 0, len(s.grants[userId]))
+
+	for appId, g := range s.grants[userId] {
+		g.AppName = s.apps[appId].Name
+		grants = append(grants, g)
+	}
+
+	sort.Slice(
+		grants, func(i, j int) bool {
+			if !grants[i].UpdatedAt.Equal(grants[j].UpdatedAt) {
+				return grants[i].UpdatedAt.After(grants[j].UpdatedAt)
+			}
+
+			return grants[i].AppId < grants[j].AppId
+		},
+	)
+
+	return grants, nil
+}
+
+// DeleteGrant deletes the grant of the user to the app.
+func (s *Storage) DeleteGrant(_ context.Context,
+	userId uint64,
+	appId int32,
+) (err error) {
+	const op = "storage.memory.DeleteGrant"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.grants[userId][appId]; !ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrGrantNotFound)
+	}
+
+	delete(s.grants[userId], appId)
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// Storage keeps all the data in the process memory.
+// It is intended for local development and hermetic tests:
+// everything is lost when the process stops.
+type Storage struct {
+	mu sync.RWMutex
+
+	creds          map[string]models.UserCredentials // by email
+	users          map[uint64]models.Profile
+	refreshTokens  map[string]models.RefreshToken       // by value
+	resetTokens    map[string]models.PasswordResetToken // by token hash
+	securityEvents []models.SecurityEvent
+	signingKeys    map[string]models.SigningKey // by kid
+	revocations    []models.Revocation
+
+	authorizationCodes map[string]models.AuthorizationCode // by code
+	deviceCodes        map[string]models.DeviceCode        // by device code
+
+	permissions     map[int32]models.Permission
+	lastPermId      int32
+	roles           map[int32]models.Role
+	lastRoleId      int32
+	rolePermissions map[int32]map[int32]struct{}
+	userRoles       map[uint64]map[int32]struct{}
+	apps            map[int32]models.App
+	lastAppId       int32
+	appPermissions  map[int32]map[int32]struct{}
+	grants          map[uint64]map[int32]models.Grant // by user id and app id
+
+	profiles map[uint64]models.Profile
+}
+
+// basePermissions are the permissions that the migrations of the SQL storages create.
+var basePermissions = []models.Permission{
+	{Resource: "permission", Action: "create", Description: "Permission to create new permissions"},
+	{Resource: "permission", Action: "delete", Description: "Permission to delete permissions"},
+	{Resource: "permission", Action: "grant", Description: "Permission to grant permissions to role"},
+	{Resource: "permission", Action: "revoke", Description: "Permission to revoke permissions from role"},
+	{Resource: "role", Action: "create", Description: "Permission to create new roles"},
+	{Resource: "role", Action: "delete", Description: "Permission to delete roles"},
+	{Resource: "role", Action: "grant", Description: "Permission to grant roles to user"},
+	{Resource: "role", Action: "revoke", Description: "Permission to revoke roles from user"},
+	{Resource: "app", Action: "create", Description: "Permission to register new apps"},
+	{Resource: "app", Action: "read", Description: "Permission to read apps"},
+	{Resource: "app", Action: "update", Description: "Permission to update apps and reset their secrets"},
+	{Resource: "app", Action: "delete", Description: "Permission to delete apps"},
+	{
+		Resource:    "token",
+		Action:      "impersonate",
+		Description: "Permission to exchange the token of the user for the one to act as the user",
+	},
+	{
+		Resource:    "token",
+		Action:      "delegate",
+		Description: "Permission to exchange the token of the user for the one to act on behalf of the user",
+	},
+	{
+		Resource:    "token",
+		Action:      "introspect",
+		Description: "Permission to introspect the tokens of the users and the apps",
+	},
+	{
+		Resource:    "revocation",
+		Action:      "watch",
+		Description: "Permission to watch the revocations of the access tokens",
+	},
+	{Resource: "profile", Action: "create", Description: "Permission to create new profiles"},
+	{Resource: "profile", Action: "read", Description: "Permission to read profiles"},
+	{Resource: "profile", Action: "update", Description: "Permission to update profiles"},
+	{Resource: "profile", Action: "delete", Description: "Permission to delete profiles"},
+}
+
+// adminRole is the role that the migrations of the SQL storages create, it has every base permission.
+var adminRole = models.Role{Name: models.RoleAdmin, Description: "Administrators of the service"}
+
+// New creates new instance of the in-memory storage
+// that contains nothing but the base permissions and the admin role.
+func New() *Storage {
+	s := &Storage{
+		creds:              make(map[string]models.UserCredentials),
+		users:              make(map[uint64]models.Profile),
+		refreshTokens:      make(map[string]models.RefreshToken),
+		resetTokens:        make(map[string]models.PasswordResetToken),
+		authorizationCodes: make(map[string]models.AuthorizationCode),
+		deviceCodes:        make(map[string]models.DeviceCode),
+		signingKeys:        make(map[string]models.SigningKey),
+		permissions:        make(map[int32]models.Permission),
+		roles:              make(map[int32]models.Role),
+		rolePermissions:    make(map[int32]map[int32]struct{}),
+		userRoles:          make(map[uint64]map[int32]struct{}),
+		apps:               make(map[int32]models.App),
+		appPermissions:     make(map[int32]map[int32]struct{}),
+		grants:             make(map[uint64]map[int32]models.Grant),
+		profiles:           make(map[uint64]models.Profile),
+	}
+
+	s.lastRoleId++
+	admin := adminRole
+	admin.Id = s.lastRoleId
+	s.roles[admin.Id] = admin
+	s.rolePermissions[admin.Id] = make(map[int32]struct{}, len(basePermissions))
+
+	for _, p := range basePermissions {
+		s.lastPermId++
+		p.Id = s.lastPermId
+		s.permissions[p.Id] = p
+		s.rolePermissions[admin.Id][p.Id] = struct{}{}
+	}
+
+	return s
+}
+
+// Stop does nothing and exists to match other storages.
+func (s *Storage) Stop() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SavePasswordResetToken saves the token and deletes the other tokens of the user and the expired ones,
+// so only the last requested token of the user can be used.
+func (s *Storage) SavePasswordResetToken(_ context.Context,
+	token models.PasswordResetToken,
+) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for hash, t := range s.resetTokens {
+		if t.UserId == token.UserId || !t.ExpiresAt.After(now) {
+			delete(s.resetTokens, hash)
+		}
+	}
+
+	s.resetTokens[token.TokenHash] = token
+
+	return nil
+}
+
+// ConsumePasswordResetToken deletes the unexpired token with the given hash and returns it,
+// so the token can be used only once.
+func (s *Storage) ConsumePasswordResetToken(_ context.Context,
+	tokenHash string,
+) (models.PasswordResetToken, error) {
+	const op = "storage.memory.ConsumePasswordResetToken"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.resetTokens[tokenHash]
+	if !ok {
+		return models.PasswordResetToken{}, fmt.Errorf("%s: %w", op, auth.ErrResetTokenNotFound)
+	}
+
+	delete(s.resetTokens, tokenHash)
+
+	if !t.ExpiresAt.After(time.Now()) {
+		return models.PasswordResetToken{}, fmt.Errorf("%s: %w", op, auth.ErrResetTokenNotFound)
+	}
+
+	return t, nil
+}
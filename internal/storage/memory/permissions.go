@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/acs"
+)
+
+// SavePermission saves new permission and returns its id.
+func (s *Storage) SavePermission(_ context.Context,
+	permission models.Permission,
+) (permissionId int32, err error) {
+	const op = "storage.memory.SavePermission"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.findPermission(permission.Resource, permission.Action); ok {
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrPermissionAlreadyExists)
+	}
+
+	s.lastPermId++
+	permission.Id = s.lastPermId
+	s.permissions[permission.Id] = permission
+
+	return permission.Id, nil
+}
+
+// SaveRolePermission links the permission to the role.
+func (s *Storage) SaveRolePermission(_ context.Context,
+	roleId int32,
+	permissionId int32,
+) (err error) {
+	const op = "storage.memory.SaveRolePermission"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[roleId]; !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrRoleNotFound)
+	}
+
+	if _, ok := s.permissions[permissionId]; !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+	}
+
+	s.rolePermissions[roleId][permissionId] = struct{}{}
+
+	return nil
+}
+
+// CheckUserPermission checks if any of the user roles has the permission.
+func (s *Storage) CheckUserPermission(_ context.Context,
+	userId uint64,
+	resource, action string,
+) (hasPermission bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.findPermission(resource, action)
+	if !ok {
+		return false, nil
+	}
+
+	for roleId := range s.userRoles[userId] {
+		if _, ok := s.rolePermissions[roleId][p.Id]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetPermissionByName returns the permission with the given resource and action.
+func (s *Storage) GetPermissionByName(_ context.Context,
+	resource,
+	action string,
+) (permission models.Permission, err error) {
+	const op = "storage.memory.GetPermissionByName"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.findPermission(resource, action)
+	if !ok {
+		return models.Permission{}, fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+	}
+
+	return p, nil
+}
+
+// DeletePermissionById deletes the permission and unlinks it from all roles and apps.
+func (s *Storage) DeletePermissionById(_ context.Context,
+	permissionId int32,
+) (err error) {
+	const op = "storage.memory.DeletePermissionById"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.permissions[permissionId]; !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+	}
+
+	s.deletePermission(permissionId)
+
+	return nil
+}
+
+// DeletePermissionByName deletes the permission and unlinks it from all roles and apps.
+func (s *Storage) DeletePermissionByName(_ context.Context,
+	resource,
+	action string,
+) (err error) {
+	const op = "storage.memory.DeletePermissionByName"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.findPermission(resource, action)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+	}
+
+	s.deletePermission(p.Id)
+
+	return nil
+}
+
+// DeleteRolePermission unlinks the permission from the role.
+func (s *Storage) DeleteRolePermission(_ context.Context,
+	roleId int32,
+	permissionId int32,
+) (err error) {
+	const op = "storage.memory.DeleteRolePermission"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rolePermissions[roleId][permissionId]; !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+	}
+
+	delete(s.rolePermissions[roleId], permissionId)
+
+	return nil
+}
+
+func (s *Storage) findPermission(resource, action string) (models.Permission, bool) {
+	for _, p := range s.permissions {
+		if p.Resource == resource && p.Action == action {
+			return p, true
+		}
+	}
+
+	return models.Permission{}, false
+}
+
+func (s *Storage) deletePermission(permissionId int32) {
+	delete(s.permissions, permissionId)
+
+	for _, perms := range s.rolePermissions {
+		delete(perms, permissionId)
+	}
+
+	for _, perms := range s.appPermissions {
+		delete(perms, permissionId)
+	}
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/profile"
+)
+
+// SaveProfile saves new profile.
+func (s *Storage) SaveProfile(_ context.Context, p models.Profile) error {
+	const op = "storage.memory.SaveProfile"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.profiles[p.Id]; ok {
+		return fmt.Errorf("%s: %w", op, profile.ErrProfileAlreadyExists)
+	}
+
+	s.profiles[p.Id] = p
+
+	return nil
+}
+
+// UpdateProfile updates the non-nil fields of the profile.
+func (s *Storage) UpdateProfile(_ context.Context, profileId uint64, update models.ProfileUpdate) error {
+	const op = "storage.memory.UpdateProfile"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.profiles[profileId]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, profile.ErrProfileNotFound)
+	}
+
+	if update.Username != nil {
+		p.Username = *update.Username
+	}
+
+	if update.AvatarHash != nil {
+		p.AvatarHash = *update.AvatarHash
+	}
+
+	s.profiles[profileId] = p
+
+	return nil
+}
+
+// GetProfile returns the profile with the given id.
+func (s *Storage) GetProfile(_ context.Context, profileId uint64) (models.Profile, error) {
+	const op = "storage.memory.GetProfile"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.profiles[profileId]
+	if !ok {
+		return models.Profile{}, fmt.Errorf("%s: %w", op, profile.ErrProfileNotFound)
+	}
+
+	return p, nil
+}
+
+// DeleteProfile deletes the profile with the given id.
+func (s *Storage) DeleteProfile(_ context.Context, profileId uint64) error {
+	const op = "storage.memory.DeleteProfile"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.profiles[profileId]; !ok {
+		return fmt.Errorf("%s: %w", op, profile.ErrProfileNotFound)
+	}
+
+	delete(s.profiles, profileId)
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveRefreshToken saves the refresh token of the new session.
+func (s *Storage) SaveRefreshToken(_ context.Context,
+	token models.RefreshToken,
+) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshTokens[token.Value] = token
+
+	return nil
+}
+
+// GetRefreshToken returns the unexpired and unrevoked refresh token.
+// Used tokens are returned too, so the reuse of them can be detected.
+func (s *Storage) GetRefreshToken(_ context.Context,
+	token string,
+) (models.RefreshToken, error) {
+	const op = "storage.memory.GetRefreshToken"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.refreshTokens[token]
+	if !ok || !t.ExpiresIn.After(time.Now()) {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, auth.ErrRefreshTokenNotFound)
+	}
+
+	if !t.RevokedAt.IsZero() {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, auth.ErrRefreshTokenRevoked)
+	}
+
+	return t, nil
+}
+
+// RotateRefreshToken marks the old refresh token as used and saves the new one of the same session.
+// If the old token has been used or revoked already, ErrRefreshTokenUsed is returned.
+func (s *Storage) RotateRefreshToken(_ context.Context,
+	oldToken string,
+	newToken models.RefreshToken,
+) (err error) {
+	const op = "storage.memory.RotateRefreshToken"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.refreshTokens[oldToken]
+	if !ok || !t.UsedAt.IsZero() || !t.RevokedAt.IsZero() {
+		return fmt.Errorf("%s: %w", op, auth.ErrRefreshTokenUsed)
+	}
+
+	t.UsedAt = newToken.CreatedAt
+	t.UsedBy = newToken.CreatedBy
+	s.refreshTokens[oldToken] = t
+
+	s.refreshTokens[newToken.Value] = newToken
+
+	return nil
+}
+
+// RevokeAll marks all the unrevoked refresh tokens of the user as revoked.
+func (s *Storage) RevokeAll(_ context.Context,
+	userId uint64,
+	revokedBy net.IP,
+) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for value, t := range s.refreshTokens {
+		if t.UserId == userId {
+			s.revoke(value, revokedBy)
+		}
+	}
+
+	return nil
+}
+
+// GetSessions returns the active sessions of the user, the last refreshed first.
+func (s *Storage) GetSessions(_ context.Context,
+	userId uint64,
+) (sessions []models.Session, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	sessions = make([]models.Session, 0)
+
+	for _, t := range s.refreshTokens {
+		if t.UserId != userId || !t.UsedAt.IsZero() || !t.RevokedAt.IsZero() || !t.ExpiresIn.After(now) {
+			continue
+		}
+
+		sessions = append(
+			sessions, models.Session{
+				Id:          t.SessionId,
+				AppId:       t.AppId,
+				DeviceName:  t.DeviceName,
+				UserAgent:   t.UserAgent,
+				IP:          t.CreatedBy,
+				RefreshedAt: t.CreatedAt,
+				ExpiresIn:   t.ExpiresIn,
+			},
+		)
+	}
+
+	sort.Slice(
+		sessions, func(i, j int) bool {
+			return sessions[i].RefreshedAt.After(sessions[j].RefreshedAt)
+		},
+	)
+
+	return sessions, nil
+}
+
+// RevokeSession marks the unrevoked refresh tokens of the user session as revoked.
+func (s *Storage) RevokeSession(_ context.Context,
+	userId uint64,
+	sessionId uint64,
+	revokedBy net.IP,
+) (err error) {
+	const op = "storage.memory.RevokeSession"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := false
+
+	for value, t := range s.refreshTokens {
+		if t.UserId == userId && t.SessionId == sessionId && s.revoke(value, revokedBy) {
+			revoked = true
+		}
+	}
+
+	if !revoked {
+		return fmt.Errorf("%s: %w", op, auth.ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+// revoke marks the token as revoked and reports whether it was unrevoked.
+// The caller must hold the write lock.
+func (s *Storage) revoke(token string, revokedBy net.IP) bool {
+	t, ok := s.refreshTokens[token]
+	if !ok || !t.RevokedAt.IsZero() {
+		return false
+	}
+
+	t.RevokedAt = time.Now()
+	t.RevokedBy = revokedBy
+	s.refreshTokens[token] = t
+
+	return true
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// SaveRevocation saves the revocation of the access tokens.
+func (s *Storage) SaveRevocation(_ context.Context,
+	revocation models.Revocation,
+) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revocations = append(s.revocations, revocation)
+
+	return nil
+}
+
+// DeleteExpiredRevocations deletes the revocations of the already expired tokens.
+func (s *Storage) DeleteExpiredRevocations(_ context.Context) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	active := s.revocations[:0]
+	for _, r := range s.revocations {
+		if r.ExpiresAt.After(now) {
+			active = append(active, r)
+		}
+	}
+
+	s.revocations = active
+
+	return nil
+}
+
+// GetRevocations returns the unexpired revocations.
+func (s *Storage) GetRevocations(_ context.Context) (revocations []models.Revocation, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	revocations = make([]models.Revocation, 0, len(s.revocations))
+
+	for _, r := range s.revocations {
+		if r.ExpiresAt.After(now) {
+			revocations = append(revocations, r)
+		}
+	}
+
+	return revocations, nil
+}
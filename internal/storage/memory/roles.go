@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/acs"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveRole saves new role with its permissions and returns the role id.
+func (s *Storage) SaveRole(_ context.Context,
+	role models.Role,
+) (roleId int32, err error) {
+	const op = "storage.memory.SaveRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.roles {
+		if r.Name == role.Name {
+			return 0, fmt.Errorf("%s: %w", op, acs.ErrRoleAlreadyExists)
+		}
+	}
+
+	perms := make(map[int32]struct{}, len(role.Permissions))
+	for _, p := range role.Permissions {
+		if _, ok := s.permissions[p.Id]; !ok {
+			return 0, fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+		}
+
+		perms[p.Id] = struct{}{}
+	}
+
+	s.lastRoleId++
+	role.Id = s.lastRoleId
+	role.Permissions = nil
+	s.roles[role.Id] = role
+	s.rolePermissions[role.Id] = perms
+
+	return role.Id, nil
+}
+
+// SaveUserRole assigns the role to the user.
+func (s *Storage) SaveUserRole(_ context.Context,
+	userId uint64,
+	roleId int32,
+) (err error) {
+	const op = "storage.memory.SaveUserRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[roleId]; !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrRoleNotFound)
+	}
+
+	if _, ok := s.userRoles[userId]; !ok {
+		s.userRoles[userId] = make(map[int32]struct{})
+	}
+
+	s.userRoles[userId][roleId] = struct{}{}
+
+	return nil
+}
+
+// GetUserRoles returns all roles of the user with their permissions.
+func (s *Storage) GetUserRoles(_ context.Context,
+	userId uint64,
+) (roles []models.Role, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles = make([]models.Role, 0, len(s.userRoles[userId]))
+
+	for roleId := range s.userRoles[userId] {
+		role := s.roles[roleId]
+
+		for permId := range s.rolePermissions[roleId] {
+			role.Permissions = append(role.Permissions, s.permissions[permId])
+		}
+
+		sort.Slice(role.Permissions, func(i, j int) bool {
+			return role.Permissions[i].Id < role.Permissions[j].Id
+		})
+
+		roles = append(roles, role)
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Id < roles[j].Id
+	})
+
+	return roles, nil
+}
+
+// DeleteRole deletes the role with the given name and returns its id.
+func (s *Storage) DeleteRole(_ context.Context,
+	roleName string,
+) (roleId int32, err error) {
+	const op = "storage.memory.DeleteRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, r := range s.roles {
+		if r.Name != roleName {
+			continue
+		}
+
+		delete(s.roles, id)
+		delete(s.rolePermissions, id)
+
+		for _, userRoles := range s.userRoles {
+			delete(userRoles, id)
+		}
+
+		return id, nil
+	}
+
+	return 0, fmt.Errorf("%s: %w", op, acs.ErrRoleNotFound)
+}
+
+// DeleteUserRole takes the role away from the user.
+func (s *Storage) DeleteUserRole(_ context.Context,
+	userId uint64,
+	roleId int32,
+) (err error) {
+	const op = "storage.memory.DeleteUserRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.userRoles[userId][roleId]; !ok {
+		return fmt.Errorf("%s: %w", op, acs.ErrRoleNotFound)
+	}
+
+	delete(s.userRoles[userId], roleId)
+
+	return nil
+}
+
+// GrantAdminRole assigns the admin role to the user.
+func (s *Storage) GrantAdminRole(_ context.Context,
+	userId uint64,
+) (err error) {
+	const op = "storage.memory.GrantAdminRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, r := range s.roles {
+		if r.Name != models.RoleAdmin {
+			continue
+		}
+
+		if _, ok := s.userRoles[userId]; !ok {
+			s.userRoles[userId] = make(map[int32]struct{})
+		}
+
+		s.userRoles[userId][id] = struct{}{}
+
+		return nil
+	}
+
+	return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+}
@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// SaveSecurityEvent saves the security event for audit.
+func (s *Storage) SaveSecurityEvent(_ context.Context,
+	event models.SecurityEvent,
+) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.securityEvents = append(s.securityEvents, event)
+
+	return nil
+}
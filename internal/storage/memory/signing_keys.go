@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/keyring"
+)
+
+// SaveSigningKey saves the signing key.
+// If the key with the same id exists, ErrSigningKeyExists is returned.
+func (s *Storage) SaveSigningKey(_ context.Context,
+	key models.SigningKey,
+) (err error) {
+	const op = "storage.memory.SaveSigningKey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.signingKeys[key.Id]; ok {
+		return fmt.Errorf("%s: %w", op, keyring.ErrSigningKeyExists)
+	}
+
+	s.signingKeys[key.Id] = key
+
+	return nil
+}
+
+// RotateSigningKey saves the new signing key and sets the expiration time of the keys which have none.
+func (s *Storage) RotateSigningKey(_ context.Context,
+	key models.SigningKey,
+	retiredExpiresAt time.Time,
+	rotatedBefore time.Time,
+) (err error) {
+	const op = "storage.memory.RotateSigningKey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.signingKeys {
+		if !rotatedBefore.IsZero() && k.ActivatesAt.After(rotatedBefore) {
+			return fmt.Errorf("%s: %w", op, keyring.ErrKeyRotated)
+		}
+	}
+
+	if _, ok := s.signingKeys[key.Id]; ok {
+		return fmt.Errorf("%s: %w", op, keyring.ErrSigningKeyExists)
+	}
+
+	for kid, k := range s.signingKeys {
+		if k.ExpiresAt.IsZero() {
+			k.ExpiresAt = retiredExpiresAt
+			s.signingKeys[kid] = k
+		}
+	}
+
+	s.signingKeys[key.Id] = key
+
+	return nil
+}
+
+// GetSigningKeys returns the unexpired signing keys.
+func (s *Storage) GetSigningKeys(_ context.Context) (keys []models.SigningKey, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	keys = make([]models.SigningKey, 0, len(s.signingKeys))
+
+	for _, k := range s.signingKeys {
+		if k.ExpiresAt.IsZero() || k.ExpiresAt.After(now) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(
+		keys, func(i, j int) bool {
+			return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+		},
+	)
+
+	return keys, nil
+}
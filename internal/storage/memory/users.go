@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveUser saves the user profile and credentials.
+func (s *Storage) SaveUser(_ context.Context,
+	creds models.UserCredentials,
+	profile models.Profile,
+) (err error) {
+	const op = "storage.memory.SaveUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.creds[creds.Email]; ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrUserAlreadyExists)
+	}
+
+	if _, ok := s.users[creds.Id]; ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrUserAlreadyExists)
+	}
+
+	s.creds[creds.Email] = creds
+	s.users[creds.Id] = profile
+
+	return nil
+}
+
+// GetUserCreds returns the credentials of the user with the given email.
+func (s *Storage) GetUserCreds(_ context.Context,
+	email string,
+) (models.UserCredentials, error) {
+	const op = "storage.memory.GetUserCreds"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	creds, ok := s.creds[email]
+	if !ok {
+		return models.UserCredentials{}, fmt.Errorf("%s: %w", op, auth.ErrUserNotFound)
+	}
+
+	return creds, nil
+}
+
+// UpdatePassword replaces the password hash of the user with the given id.
+func (s *Storage) UpdatePassword(_ context.Context,
+	userId uint64,
+	passHash []byte,
+) (err error) {
+	const op = "storage.memory.UpdatePassword"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for email, creds := range s.creds {
+		if creds.Id == userId {
+			creds.PasswordHash = passHash
+			s.creds[email] = creds
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w", op, auth.ErrUserNotFound)
+}
+
+// UserExists reports whether the user with the given id exists.
+func (s *Storage) UserExists(_ context.Context,
+	userId uint64,
+) (exists bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists = s.users[userId]
+
+	return exists, nil
+}
+
+// GetUserInfo returns the email and the brief profile of the user with the given id.
+func (s *Storage) GetUserInfo(_ context.Context,
+	userId uint64,
+) (models.UserInfo, error) {
+	const op = "storage.memory.GetUserInfo"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profile, ok := s.users[userId]
+	if !ok {
+		return models.UserInfo{}, fmt.Errorf("%s: %w", op, auth.ErrUserNotFound)
+	}
+
+	info := models.UserInfo{UserId: userId, BriefProfile: profile.BriefProfile}
+
+	// The credentials are kept by email, the users are few enough to look through
+	for _, creds := range s.creds {
+		if creds.Id == userId {
+			info.Email = creds.Email
+
+			break
+		}
+	}
+
+	return info, nil
+}
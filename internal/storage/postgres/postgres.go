@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq" // init postgres driver
+	"github.com/puregrade-group/sso/internal/storage/sqlstore"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// Config contains the connection parameters of the Postgres instance.
+type Config struct {
+	Host     string
+	Port     uint16
+	Database string
+	User     string
+	Password string
+	SSLMode  string
+}
+
+// New creates new instance of the Postgres storage and checks the connection.
+func New(cfg Config) (*sqlstore.Storage, error) {
+	const op = "storage.postgres.New"
+
+	db, err := sqlx.Connect("postgres", cfg.dsn())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sqlstore.New(db, dialect{}), nil
+}
+
+func (c Config) dsn() string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port))),
+		Path:   c.Database,
+	}
+
+	if c.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+	}
+
+	return dsn.String()
+}
+
+// dialect describes the errors and the locks of Postgres.
+type dialect struct{}
+
+func (dialect) IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+func (dialect) IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
+
+// LockTable locks the table in the mode that lets the transactions read it, but not write.
+func (dialect) LockTable(table string) string {
+	return "lock table " + table + " in exclusive mode"
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3" // init sqlite3 driver
+	"github.com/puregrade-group/sso/internal/storage/sqlstore"
+)
+
+// New creates new instance of the SQLite storage.
+// The database file is created if it does not exist.
+// The transactions take the write lock when they begin, so they are serialized.
+func New(storagePath string) (*sqlstore.Storage, error) {
+	const op = "storage.sqlite.New"
+
+	db, err := sqlx.Connect("sqlite3", storagePath+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sqlstore.New(db, dialect{}), nil
+}
+
+// dialect describes the errors and the locks of SQLite.
+type dialect struct{}
+
+func (dialect) IsUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+func (dialect) IsForeignKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
+
+// LockTable returns nothing, the immediate transactions are serialized already.
+func (dialect) LockTable(string) string {
+	return ""
+}
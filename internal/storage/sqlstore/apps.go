@@ -0,0 +1,288 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/acs"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// The apps are looked up by both Auth and ACS, so GetApp reports the errors of both.
+var (
+	errAppNotFound = fmt.Errorf("%w (%w)", acs.ErrAppNotFound, auth.ErrAppNotFound)
+	errAppInternal = fmt.Errorf("%w (%w)", acs.ErrInternal, auth.ErrInternal)
+)
+
+// SaveApp saves new app with its redirect URIs, grant types and permissions and returns the app id.
+func (s *Storage) SaveApp(ctx context.Context,
+	app models.App,
+) (appId int32, err error) {
+	const op = "storage.sql.SaveApp"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.GetContext(
+		ctx,
+		&appId,
+		`insert into apps (name, secret_hash, first_party, created_at) values (?, ?, ?, current_timestamp) returning id`,
+		app.Name,
+		app.SecretHash,
+		app.FirstParty,
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, acs.ErrAppAlreadyExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	app.Id = appId
+
+	if err = s.saveAppDetails(ctx, tx, app); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return appId, nil
+}
+
+// UpdateApp replaces the name, the first-party flag, the redirect URIs, the grant types and the permissions of the app.
+func (s *Storage) UpdateApp(ctx context.Context,
+	app models.App,
+) (err error) {
+	const op = "storage.sql.UpdateApp"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`update apps set name = ?, first_party = ? where id = ?`,
+		app.Name,
+		app.FirstParty,
+		app.Id,
+	)
+	if err != nil && s.dialect.IsUniqueViolation(err) {
+		return fmt.Errorf("%s: %w", op, acs.ErrAppAlreadyExists)
+	}
+
+	if err = wrapExecResult(op, res, err, acs.ErrAppNotFound, acs.ErrInternal); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"app_redirect_uris", "app_grant_types", "app_permissions"} {
+		_, err = tx.ExecContext(ctx, `delete from `+table+` where app_id = ?`, app.Id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, acs.ErrInternal)
+		}
+	}
+
+	if err = s.saveAppDetails(ctx, tx, app); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return nil
+}
+
+// UpdateAppSecret replaces the secret hash of the app.
+func (s *Storage) UpdateAppSecret(ctx context.Context,
+	appId int32,
+	secretHash []byte,
+) (err error) {
+	const op = "storage.sql.UpdateAppSecret"
+
+	res, err := s.db.ExecContext(ctx, `update apps set secret_hash = ? where id = ?`, secretHash, appId)
+
+	return wrapExecResult(op, res, err, acs.ErrAppNotFound, acs.ErrInternal)
+}
+
+// GetApp returns the app with the given id.
+func (s *Storage) GetApp(ctx context.Context, appId int32) (models.App, error) {
+	const op = "storage.sql.GetApp"
+
+	var app models.App
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`select id, name, secret_hash, first_party, created_at from apps where id = ?`,
+		appId,
+	).Scan(&app.Id, &app.Name, &app.SecretHash, &app.FirstParty, &app.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, fmt.Errorf("%s: %w", op, errAppNotFound)
+		}
+
+		return models.App{}, fmt.Errorf("%s: %w", op, errAppInternal)
+	}
+
+	if err = s.getAppDetails(ctx, &app); err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, errAppInternal)
+	}
+
+	return app, nil
+}
+
+// GetApps returns all the apps ordered by id.
+func (s *Storage) GetApps(ctx context.Context) (apps []models.App, err error) {
+	const op = "storage.sql.GetApps"
+
+	rows, err := s.db.QueryContext(ctx, `select id, name, secret_hash, first_party, created_at from apps order by id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+	defer rows.Close()
+
+	apps = make([]models.App, 0)
+
+	for rows.Next() {
+		var app models.App
+
+		if err = rows.Scan(&app.Id, &app.Name, &app.SecretHash, &app.FirstParty, &app.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+		}
+
+		apps = append(apps, app)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	for i := range apps {
+		if err = s.getAppDetails(ctx, &apps[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+		}
+	}
+
+	return apps, nil
+}
+
+// DeleteApp deletes the app with its redirect URIs, grant types and permissions.
+func (s *Storage) DeleteApp(ctx context.Context,
+	appId int32,
+) (err error) {
+	const op = "storage.sql.DeleteApp"
+
+	res, err := s.db.ExecContext(ctx, `delete from apps where id = ?`, appId)
+
+	return wrapExecResult(op, res, err, acs.ErrAppNotFound, acs.ErrInternal)
+}
+
+// saveAppDetails saves the redirect URIs, the grant types and the permissions of the app.
+func (s *Storage) saveAppDetails(ctx context.Context, tx tx, app models.App) error {
+	for _, uri := range app.RedirectURIs {
+		_, err := tx.ExecContext(
+			ctx,
+			`insert into app_redirect_uris (app_id, uri) values (?, ?) on conflict do nothing`,
+			app.Id,
+			uri,
+		)
+		if err != nil {
+			return acs.ErrInternal
+		}
+	}
+
+	for _, grantType := range app.GrantTypes {
+		_, err := tx.ExecContext(
+			ctx,
+			`insert into app_grant_types (app_id, grant_type) values (?, ?) on conflict do nothing`,
+			app.Id,
+			grantType,
+		)
+		if err != nil {
+			return acs.ErrInternal
+		}
+	}
+
+	for _, p := range app.Permissions {
+		_, err := tx.ExecContext(
+			ctx,
+			`insert into app_permissions (app_id, permission_id) values (?, ?) on conflict do nothing`,
+			app.Id,
+			p.Id,
+		)
+		if err != nil {
+			if s.dialect.IsForeignKeyViolation(err) {
+				return acs.ErrPermissionNotFound
+			}
+
+			return acs.ErrInternal
+		}
+	}
+
+	return nil
+}
+
+// getAppDetails fills the redirect URIs, the grant types and the permissions of the app.
+func (s *Storage) getAppDetails(ctx context.Context, app *models.App) error {
+	err := s.db.SelectContext(
+		ctx, &app.RedirectURIs,
+		`select uri from app_redirect_uris where app_id = ? order by uri`,
+		app.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SelectContext(
+		ctx, &app.GrantTypes,
+		`select grant_type from app_grant_types where app_id = ? order by grant_type`,
+		app.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select p.id, p.resource, p.action, p.description
+		from app_permissions ap
+		join permissions p on p.id = ap.permission_id
+		where ap.app_id = ?
+		order by p.id`,
+		app.Id,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Permission
+
+		if err = rows.Scan(&p.Id, &p.Resource, &p.Action, &p.Description); err != nil {
+			return err
+		}
+
+		app.Permissions = append(app.Permissions, p)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,136 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveAuthorizationCode saves the authorization code and deletes the expired ones.
+func (s *Storage) SaveAuthorizationCode(ctx context.Context,
+	code models.AuthorizationCode,
+) (err error) {
+	const op = "storage.sql.SaveAuthorizationCode"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`delete from authorization_codes where expires_at <= ?`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`insert into authorization_codes
+		(code, app_id, user_id, redirect_uri, code_challenge, scope, nonce, consent_pending, user_agent, created_by,
+		created_at, expires_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		code.Code,
+		code.AppId,
+		code.UserId,
+		code.RedirectURI,
+		code.CodeChallenge,
+		strings.Join(code.Scopes, " "),
+		code.Nonce,
+		code.ConsentPending,
+		code.UserAgent,
+		nullIP(code.CreatedBy),
+		code.CreatedAt.UTC(),
+		code.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode deletes the unexpired authorization code and returns it,
+// so the code can be exchanged only once.
+func (s *Storage) ConsumeAuthorizationCode(ctx context.Context,
+	code string,
+) (models.AuthorizationCode, error) {
+	const op = "storage.sql.ConsumeAuthorizationCode"
+
+	var (
+		c         = models.AuthorizationCode{Code: code}
+		scope     string
+		createdBy sql.NullString
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`delete from authorization_codes where code = ?
+		returning app_id, user_id, redirect_uri, code_challenge, scope, nonce, consent_pending, user_agent, created_by,
+			created_at, expires_at`,
+		code,
+	).Scan(
+		&c.AppId, &c.UserId, &c.RedirectURI, &c.CodeChallenge, &scope, &c.Nonce, &c.ConsentPending, &c.UserAgent,
+		&createdBy, &c.CreatedAt, &c.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrAuthorizationCodeNotFound)
+		}
+
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	if !c.ExpiresAt.After(time.Now()) {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrAuthorizationCodeNotFound)
+	}
+
+	c.Scopes = strings.Fields(scope)
+	c.CreatedBy = net.ParseIP(createdBy.String)
+
+	return c, nil
+}
+
+// ConsentAuthorizationCode marks the unexpired pending authorization code as consented to and returns it,
+// so it can be exchanged for the tokens.
+func (s *Storage) ConsentAuthorizationCode(ctx context.Context,
+	code string,
+) (models.AuthorizationCode, error) {
+	const op = "storage.sql.ConsentAuthorizationCode"
+
+	var (
+		c         = models.AuthorizationCode{Code: code}
+		scope     string
+		createdBy sql.NullString
+		now       = time.Now().UTC()
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`update authorization_codes set consent_pending = false
+		where code = ? and consent_pending and expires_at > ?
+		returning app_id, user_id, redirect_uri, code_challenge, scope, nonce, user_agent, created_by,
+			created_at, expires_at`,
+		code,
+		now,
+	).Scan(
+		&c.AppId, &c.UserId, &c.RedirectURI, &c.CodeChallenge, &scope, &c.Nonce, &c.UserAgent,
+		&createdBy, &c.CreatedAt, &c.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrAuthorizationCodeNotFound)
+		}
+
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	c.Scopes = strings.Fields(scope)
+	c.CreatedBy = net.ParseIP(createdBy.String)
+
+	return c, nil
+}
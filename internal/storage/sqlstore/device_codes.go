@@ -0,0 +1,195 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveDeviceCode saves the device code and deletes the expired ones, so their user codes can be issued again.
+func (s *Storage) SaveDeviceCode(ctx context.Context,
+	code models.DeviceCode,
+) (err error) {
+	const op = "storage.sql.SaveDeviceCode"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`delete from device_codes where expires_at <= ?`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`insert into device_codes
+		(device_code, user_code, app_id, scope, poll_interval, user_agent, created_by, created_at, expires_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		code.DeviceCode,
+		code.UserCode,
+		code.AppId,
+		strings.Join(code.Scopes, " "),
+		int64(code.Interval/time.Second),
+		code.UserAgent,
+		nullIP(code.CreatedBy),
+		code.CreatedAt.UTC(),
+		code.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, auth.ErrUserCodeAlreadyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetDeviceCode returns the device code, the expired one is returned too, so its expiration can be reported.
+func (s *Storage) GetDeviceCode(ctx context.Context,
+	deviceCode string,
+) (models.DeviceCode, error) {
+	const op = "storage.sql.GetDeviceCode"
+
+	return s.getDeviceCode(ctx, op, `where device_code = ?`, deviceCode)
+}
+
+// GetPendingDeviceCode returns the unexpired code of the user code that has not been approved yet.
+func (s *Storage) GetPendingDeviceCode(ctx context.Context,
+	userCode string,
+) (models.DeviceCode, error) {
+	const op = "storage.sql.GetPendingDeviceCode"
+
+	return s.getDeviceCode(
+		ctx, op, `where user_code = ? and user_id is null and expires_at > ?`, userCode, time.Now().UTC(),
+	)
+}
+
+// getDeviceCode returns the device code the condition selects.
+func (s *Storage) getDeviceCode(ctx context.Context,
+	op string,
+	where string,
+	args ...any,
+) (models.DeviceCode, error) {
+	var (
+		c                    models.DeviceCode
+		scope                string
+		userId, reviewedBy   sql.NullInt64
+		reviewCode           sql.NullString
+		interval             int64
+		createdBy            sql.NullString
+		polledAt, approvedAt sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`select device_code, user_code, app_id, scope, user_id, reviewed_by, review_code, poll_interval,
+			user_agent, created_by, created_at, expires_at, polled_at, approved_at
+		from device_codes `+where,
+		args...,
+	).Scan(
+		&c.DeviceCode, &c.UserCode, &c.AppId, &scope, &userId, &reviewedBy, &reviewCode, &interval,
+		&c.UserAgent, &createdBy, &c.CreatedAt, &c.ExpiresAt, &polledAt, &approvedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DeviceCode{}, fmt.Errorf("%s: %w", op, auth.ErrDeviceCodeNotFound)
+		}
+
+		return models.DeviceCode{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	c.Scopes = strings.Fields(scope)
+	c.UserId = uint64(userId.Int64)
+	c.ReviewedBy = uint64(reviewedBy.Int64)
+	c.ReviewCode = reviewCode.String
+	c.Interval = time.Duration(interval) * time.Second
+	c.CreatedBy = net.ParseIP(createdBy.String)
+	c.PolledAt = polledAt.Time
+	c.ApprovedAt = approvedAt.Time
+
+	return c, nil
+}
+
+// ReviewDeviceCode binds the user that signed in to review the unexpired code that has not been approved yet
+// and the code of the review, the one of the previous review is replaced.
+func (s *Storage) ReviewDeviceCode(ctx context.Context,
+	userCode string,
+	userId uint64,
+	reviewCode string,
+) (err error) {
+	const op = "storage.sql.ReviewDeviceCode"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`update device_codes set reviewed_by = ?, review_code = ?
+		where user_code = ? and user_id is null and expires_at > ?`,
+		userId,
+		reviewCode,
+		userCode,
+		time.Now().UTC(),
+	)
+
+	return wrapExecResult(op, res, err, auth.ErrDeviceCodeNotFound, auth.ErrInternal)
+}
+
+// ApproveDeviceCode binds the user that reviewed the unexpired code that has not been approved yet to it,
+// the code reviewed again since is not approved.
+func (s *Storage) ApproveDeviceCode(ctx context.Context,
+	userCode string,
+	reviewCode string,
+	approvedAt time.Time,
+) (err error) {
+	const op = "storage.sql.ApproveDeviceCode"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`update device_codes set user_id = reviewed_by, approved_at = ?
+		where user_code = ? and review_code = ? and user_id is null and expires_at > ?`,
+		approvedAt.UTC(),
+		userCode,
+		reviewCode,
+		approvedAt.UTC(),
+	)
+
+	return wrapExecResult(op, res, err, auth.ErrDeviceCodeNotFound, auth.ErrInternal)
+}
+
+// UpdateDeviceCodePoll saves the time the device polled at and the interval it must wait before the next poll.
+func (s *Storage) UpdateDeviceCodePoll(ctx context.Context,
+	deviceCode string,
+	polledAt time.Time,
+	interval time.Duration,
+) (err error) {
+	const op = "storage.sql.UpdateDeviceCodePoll"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`update device_codes set polled_at = ?, poll_interval = ? where device_code = ?`,
+		polledAt.UTC(),
+		int64(interval/time.Second),
+		deviceCode,
+	)
+
+	return wrapExecResult(op, res, err, auth.ErrDeviceCodeNotFound, auth.ErrInternal)
+}
+
+// DeleteDeviceCode deletes the device code, so it can be exchanged only once.
+func (s *Storage) DeleteDeviceCode(ctx context.Context,
+	deviceCode string,
+) (err error) {
+	const op = "storage.sql.DeleteDeviceCode"
+
+	res, err := s.db.ExecContext(ctx, `delete from device_codes where device_code = ?`, deviceCode)
+
+	return wrapExecResult(op, res, err, auth.ErrDeviceCodeNotFound, auth.ErrInternal)
+}
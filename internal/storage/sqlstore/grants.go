@@ -0,0 +1,129 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveGrant saves the grant of the user to the app or replaces its scopes, the time it was created at is kept.
+func (s *Storage) SaveGrant(ctx context.Context,
+	grant models.Grant,
+) (err error) {
+	const op = "storage.sql.SaveGrant"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`insert into grants (user_id, app_id, scope, created_at, updated_at) values (?, ?, ?, ?, ?)
+		on conflict (user_id, app_id) do update set scope = excluded.scope, updated_at = excluded.updated_at`,
+		grant.UserId,
+		grant.AppId,
+		strings.Join(grant.Scopes, " "),
+		grant.CreatedAt.UTC(),
+		grant.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		if s.dialect.IsForeignKeyViolation(err) {
+			return fmt.Errorf("%s: %w", op, auth.ErrAppNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetGrant returns the grant of the user to the app.
+func (s *Storage) GetGrant(ctx context.Context,
+	userId uint64,
+	appId int32,
+) (models.Grant, error) {
+	const op = "storage.sql.GetGrant"
+
+	var (
+		g     = models.Grant{UserId: userId, AppId: appId}
+		scope string
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`select a.name, g.scope, g.created_at, g.updated_at
+		from grants g
+		join apps a on a.id = g.app_id
+		where g.user_id = ? and g.app_id = ?`,
+		userId,
+		appId,
+	).Scan(&g.AppName, &scope, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Grant{}, fmt.Errorf("%s: %w", op, auth.ErrGrantNotFound)
+		}
+
+		return models.Grant{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	g.Scopes = strings.Fields(scope)
+
+	return g, nil
+}
+
+// GetGrants returns the grants of the user, the last updated first.
+func (s *Storage) GetGrants(ctx context.Context,
+	userId uint64,
+) (grants []models.Grant, err error) {
+	const op = "storage.sql.GetGrants"
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select g.app_id, a.name, g.scope, g.created_at, g.updated_at
+		from grants g
+		join apps a on a.id = g.app_id
+		where g.user_id = ?
+		order by g.updated_at desc, g.app_id`,
+		userId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+	defer rows.Close()
+
+	grants = make([]models.Grant, 0)
+
+	for rows.Next() {
+		var (
+			g     = models.Grant{UserId: userId}
+			scope string
+		)
+
+		if err = rows.Scan(&g.AppId, &g.AppName, &scope, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+		}
+
+		g.Scopes = strings.Fields(scope)
+
+		grants = append(grants, g)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return grants, nil
+}
+
+// DeleteGrant deletes the grant of the user to the app.
+func (s *Storage) DeleteGrant(ctx context.Context,
+	userId uint64,
+	appId int32,
+) (err error) {
+	const op = "storage.sql.DeleteGrant"
+
+	res, err := s.db.ExecContext(ctx, `delete from grants where user_id = ? and app_id = ?`, userId, appId)
+
+	return wrapExecResult(op, res, err, auth.ErrGrantNotFound, auth.ErrInternal)
+}
@@ -0,0 +1,96 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SavePasswordResetToken saves the token and deletes the other tokens of the user and the expired ones,
+// so only the last requested token of the user can be used.
+func (s *Storage) SavePasswordResetToken(ctx context.Context,
+	token models.PasswordResetToken,
+) (err error) {
+	const op = "storage.sql.SavePasswordResetToken"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`delete from password_reset_tokens where user_id = ? or expires_at <= ?`,
+		token.UserId,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`insert into password_reset_tokens (token_hash, user_id, created_by, created_at, expires_at)
+		values (?, ?, ?, ?, ?)`,
+		token.TokenHash,
+		token.UserId,
+		nullIP(token.CreatedBy),
+		token.CreatedAt.UTC(),
+		token.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// ConsumePasswordResetToken deletes the unexpired token with the given hash and returns it,
+// so the token can be used only once.
+func (s *Storage) ConsumePasswordResetToken(ctx context.Context,
+	tokenHash string,
+) (models.PasswordResetToken, error) {
+	const op = "storage.sql.ConsumePasswordResetToken"
+
+	var (
+		t         = models.PasswordResetToken{TokenHash: tokenHash}
+		createdBy sql.NullString
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`delete from password_reset_tokens where token_hash = ?
+		returning user_id, created_by, created_at, expires_at`,
+		tokenHash,
+	).Scan(&t.UserId, &createdBy, &t.CreatedAt, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PasswordResetToken{}, fmt.Errorf("%s: %w", op, auth.ErrResetTokenNotFound)
+		}
+
+		return models.PasswordResetToken{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	if !t.ExpiresAt.After(time.Now()) {
+		return models.PasswordResetToken{}, fmt.Errorf("%s: %w", op, auth.ErrResetTokenNotFound)
+	}
+
+	t.CreatedBy = net.ParseIP(createdBy.String)
+
+	return t, nil
+}
@@ -0,0 +1,171 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/acs"
+)
+
+// SavePermission saves new permission and returns its id.
+func (s *Storage) SavePermission(ctx context.Context,
+	permission models.Permission,
+) (permissionId int32, err error) {
+	const op = "storage.sql.SavePermission"
+
+	err = s.db.GetContext(
+		ctx,
+		&permissionId,
+		`insert into permissions (resource, action, description) values (?, ?, ?) returning id`,
+		permission.Resource,
+		permission.Action,
+		permission.Description,
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, acs.ErrPermissionAlreadyExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return permissionId, nil
+}
+
+// SaveRolePermission links the permission to the role.
+func (s *Storage) SaveRolePermission(ctx context.Context,
+	roleId int32,
+	permissionId int32,
+) (err error) {
+	const op = "storage.sql.SaveRolePermission"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`insert into role_permissions (role_id, permission_id) values (?, ?) on conflict do nothing`,
+		roleId,
+		permissionId,
+	)
+	if err != nil {
+		if s.dialect.IsForeignKeyViolation(err) {
+			return fmt.Errorf("%s: %w", op, s.roleOrPermissionNotFound(ctx, roleId))
+		}
+
+		return fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return nil
+}
+
+// CheckUserPermission checks if any of the user roles has the permission.
+func (s *Storage) CheckUserPermission(ctx context.Context,
+	userId uint64,
+	resource, action string,
+) (hasPermission bool, err error) {
+	const op = "storage.sql.CheckUserPermission"
+
+	err = s.db.GetContext(
+		ctx,
+		&hasPermission,
+		`select exists (
+			select 1 from user_roles ur
+			join role_permissions rp on rp.role_id = ur.role_id
+			join permissions p on p.id = rp.permission_id
+			where ur.user_id = ? and p.resource = ? and p.action = ?
+		)`,
+		userId,
+		resource,
+		action,
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return hasPermission, nil
+}
+
+// GetPermissionByName returns the permission with the given resource and action.
+func (s *Storage) GetPermissionByName(ctx context.Context,
+	resource,
+	action string,
+) (permission models.Permission, err error) {
+	const op = "storage.sql.GetPermissionByName"
+
+	err = s.db.QueryRowContext(
+		ctx,
+		`select id, resource, action, description from permissions where resource = ? and action = ?`,
+		resource,
+		action,
+	).Scan(&permission.Id, &permission.Resource, &permission.Action, &permission.Description)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Permission{}, fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+		}
+
+		return models.Permission{}, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return permission, nil
+}
+
+// DeletePermissionById deletes the permission and unlinks it from all roles.
+func (s *Storage) DeletePermissionById(ctx context.Context,
+	permissionId int32,
+) (err error) {
+	const op = "storage.sql.DeletePermissionById"
+
+	res, err := s.db.ExecContext(ctx, `delete from permissions where id = ?`, permissionId)
+
+	return wrapExecResult(op, res, err, acs.ErrPermissionNotFound, acs.ErrInternal)
+}
+
+// DeletePermissionByName deletes the permission and unlinks it from all roles.
+func (s *Storage) DeletePermissionByName(ctx context.Context,
+	resource,
+	action string,
+) (err error) {
+	const op = "storage.sql.DeletePermissionByName"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`delete from permissions where resource = ? and action = ?`,
+		resource,
+		action,
+	)
+
+	return wrapExecResult(op, res, err, acs.ErrPermissionNotFound, acs.ErrInternal)
+}
+
+// DeleteRolePermission unlinks the permission from the role.
+func (s *Storage) DeleteRolePermission(ctx context.Context,
+	roleId int32,
+	permissionId int32,
+) (err error) {
+	const op = "storage.sql.DeleteRolePermission"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`delete from role_permissions where role_id = ? and permission_id = ?`,
+		roleId,
+		permissionId,
+	)
+
+	return wrapExecResult(op, res, err, acs.ErrPermissionNotFound, acs.ErrInternal)
+}
+
+// roleOrPermissionNotFound finds out which side of the role-permission link is missing.
+func (s *Storage) roleOrPermissionNotFound(ctx context.Context, roleId int32) error {
+	var exists bool
+
+	err := s.db.GetContext(ctx, &exists, `select exists (select 1 from roles where id = ?)`, roleId)
+	switch {
+	case err != nil:
+		return acs.ErrInternal
+	case !exists:
+		return acs.ErrRoleNotFound
+	default:
+		return acs.ErrPermissionNotFound
+	}
+}
@@ -0,0 +1,82 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/profile"
+)
+
+// SaveProfile saves new profile.
+func (s *Storage) SaveProfile(ctx context.Context, p models.Profile) error {
+	const op = "storage.sql.SaveProfile"
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`insert into idp_profiles (id, username, avatar_hash, idp, account_id) values (?, ?, ?, ?, ?)`,
+		p.Id,
+		p.Username,
+		p.AvatarHash,
+		p.Idp,
+		p.AccountId,
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, profile.ErrProfileAlreadyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, profile.ErrInternal)
+	}
+
+	return nil
+}
+
+// UpdateProfile updates the non-nil fields of the profile.
+func (s *Storage) UpdateProfile(ctx context.Context, profileId uint64, update models.ProfileUpdate) error {
+	const op = "storage.sql.UpdateProfile"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`update idp_profiles set username = coalesce(?, username), avatar_hash = coalesce(?, avatar_hash)
+		where id = ?`,
+		update.Username,
+		update.AvatarHash,
+		profileId,
+	)
+
+	return wrapExecResult(op, res, err, profile.ErrProfileNotFound, profile.ErrInternal)
+}
+
+// GetProfile returns the profile with the given id.
+func (s *Storage) GetProfile(ctx context.Context, profileId uint64) (models.Profile, error) {
+	const op = "storage.sql.GetProfile"
+
+	p := models.Profile{Id: profileId}
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`select username, avatar_hash, idp, account_id from idp_profiles where id = ?`,
+		profileId,
+	).Scan(&p.Username, &p.AvatarHash, &p.Idp, &p.AccountId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Profile{}, fmt.Errorf("%s: %w", op, profile.ErrProfileNotFound)
+		}
+
+		return models.Profile{}, fmt.Errorf("%s: %w", op, profile.ErrInternal)
+	}
+
+	return p, nil
+}
+
+// DeleteProfile deletes the profile with the given id.
+func (s *Storage) DeleteProfile(ctx context.Context, profileId uint64) error {
+	const op = "storage.sql.DeleteProfile"
+
+	res, err := s.db.ExecContext(ctx, `delete from idp_profiles where id = ?`, profileId)
+
+	return wrapExecResult(op, res, err, profile.ErrProfileNotFound, profile.ErrInternal)
+}
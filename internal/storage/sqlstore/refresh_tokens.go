@@ -0,0 +1,243 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveRefreshToken saves the refresh token of the new session.
+func (s *Storage) SaveRefreshToken(ctx context.Context,
+	token models.RefreshToken,
+) (err error) {
+	const op = "storage.sql.SaveRefreshToken"
+
+	err = insertRefreshToken(ctx, s.db, token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetRefreshToken returns the unexpired and unrevoked refresh token.
+// Used tokens are returned too, so the reuse of them can be detected.
+func (s *Storage) GetRefreshToken(ctx context.Context,
+	token string,
+) (models.RefreshToken, error) {
+	const op = "storage.sql.GetRefreshToken"
+
+	var (
+		t                            = models.RefreshToken{Value: token}
+		appId                        sql.NullInt32
+		scope                        string
+		createdBy, usedBy            sql.NullString
+		createdAt, usedAt, revokedAt sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`select session_id, user_id, app_id, scope, device_name, user_agent, created_by, created_at, expires_in,
+			used_by, used_at, revoked_at
+		from refresh_tokens where value = ? and expires_in > ?`,
+		token,
+		time.Now().UTC(),
+	).Scan(
+		&t.SessionId, &t.UserId, &appId, &scope, &t.DeviceName, &t.UserAgent,
+		&createdBy, &createdAt, &t.ExpiresIn,
+		&usedBy, &usedAt, &revokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, fmt.Errorf("%s: %w", op, auth.ErrRefreshTokenNotFound)
+		}
+
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	if revokedAt.Valid {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, auth.ErrRefreshTokenRevoked)
+	}
+
+	t.AppId = appId.Int32
+	t.Scopes = strings.Fields(scope)
+	t.CreatedBy = net.ParseIP(createdBy.String)
+	t.CreatedAt = createdAt.Time
+	t.UsedBy = net.ParseIP(usedBy.String)
+	t.UsedAt = usedAt.Time
+
+	return t, nil
+}
+
+// RotateRefreshToken marks the old refresh token as used and saves the new one of the same session.
+// If the old token has been used or revoked already, ErrRefreshTokenUsed is returned.
+func (s *Storage) RotateRefreshToken(ctx context.Context,
+	oldToken string,
+	newToken models.RefreshToken,
+) (err error) {
+	const op = "storage.sql.RotateRefreshToken"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`update refresh_tokens set used_at = ?, used_by = ?
+		where value = ? and used_at is null and revoked_at is null`,
+		newToken.CreatedAt.UTC(),
+		nullIP(newToken.CreatedBy),
+		oldToken,
+	)
+	if err = wrapExecResult(op, res, err, auth.ErrRefreshTokenUsed, auth.ErrInternal); err != nil {
+		return err
+	}
+
+	err = insertRefreshToken(ctx, tx, newToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// RevokeAll marks all the unrevoked refresh tokens of the user as revoked.
+func (s *Storage) RevokeAll(ctx context.Context,
+	userId uint64,
+	revokedBy net.IP,
+) (err error) {
+	const op = "storage.sql.RevokeAll"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`update refresh_tokens set revoked_at = ?, revoked_by = ? where user_id = ? and revoked_at is null`,
+		time.Now().UTC(),
+		nullIP(revokedBy),
+		userId,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetSessions returns the active sessions of the user, the last refreshed first.
+func (s *Storage) GetSessions(ctx context.Context,
+	userId uint64,
+) (sessions []models.Session, err error) {
+	const op = "storage.sql.GetSessions"
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select session_id, app_id, device_name, user_agent, created_by, created_at, expires_in
+		from refresh_tokens
+		where user_id = ? and used_at is null and revoked_at is null and expires_in > ?
+		order by created_at desc`,
+		userId,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+	defer rows.Close()
+
+	sessions = make([]models.Session, 0)
+
+	for rows.Next() {
+		var (
+			session   models.Session
+			appId     sql.NullInt32
+			createdBy sql.NullString
+			createdAt sql.NullTime
+		)
+
+		err = rows.Scan(
+			&session.Id, &appId, &session.DeviceName, &session.UserAgent,
+			&createdBy, &createdAt, &session.ExpiresIn,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+		}
+
+		session.AppId = appId.Int32
+		session.IP = net.ParseIP(createdBy.String)
+		session.RefreshedAt = createdAt.Time
+
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession marks the unrevoked refresh tokens of the user session as revoked.
+func (s *Storage) RevokeSession(ctx context.Context,
+	userId uint64,
+	sessionId uint64,
+	revokedBy net.IP,
+) (err error) {
+	const op = "storage.sql.RevokeSession"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`update refresh_tokens set revoked_at = ?, revoked_by = ?
+		where user_id = ? and session_id = ? and revoked_at is null`,
+		time.Now().UTC(),
+		nullIP(revokedBy),
+		userId,
+		sessionId,
+	)
+
+	return wrapExecResult(op, res, err, auth.ErrSessionNotFound, auth.ErrInternal)
+}
+
+func insertRefreshToken(ctx context.Context, db sqlx.ExecerContext, t models.RefreshToken) error {
+	_, err := db.ExecContext(
+		ctx,
+		`insert into refresh_tokens
+		(value, session_id, user_id, app_id, scope, device_name, user_agent, created_by, created_at, expires_in)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Value,
+		t.SessionId,
+		t.UserId,
+		sql.NullInt32{Int32: t.AppId, Valid: t.AppId != 0},
+		strings.Join(t.Scopes, " "),
+		t.DeviceName,
+		t.UserAgent,
+		nullIP(t.CreatedBy),
+		t.CreatedAt.UTC(),
+		t.ExpiresIn.UTC(),
+	)
+
+	return err
+}
+
+func nullIP(ip net.IP) sql.NullString {
+	if ip == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: ip.String(), Valid: true}
+}
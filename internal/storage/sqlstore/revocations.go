@@ -0,0 +1,92 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/revocation"
+)
+
+// SaveRevocation saves the revocation of the access tokens.
+func (s *Storage) SaveRevocation(ctx context.Context,
+	r models.Revocation,
+) (err error) {
+	const op = "storage.sql.SaveRevocation"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`insert into access_token_revocations (jti, session_id, user_id, revoked_at, expires_at)
+		values (?, ?, ?, ?, ?)`,
+		sql.NullString{String: r.TokenId, Valid: r.TokenId != ""},
+		r.SessionId,
+		r.UserId,
+		r.RevokedAt.UTC(),
+		r.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, revocation.ErrInternal)
+	}
+
+	return nil
+}
+
+// DeleteExpiredRevocations deletes the revocations of the already expired tokens.
+func (s *Storage) DeleteExpiredRevocations(ctx context.Context) (err error) {
+	const op = "storage.sql.DeleteExpiredRevocations"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`delete from access_token_revocations where expires_at <= ?`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, revocation.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetRevocations returns the unexpired revocations.
+func (s *Storage) GetRevocations(ctx context.Context) (revocations []models.Revocation, err error) {
+	const op = "storage.sql.GetRevocations"
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select jti, session_id, user_id, revoked_at, expires_at
+		from access_token_revocations
+		where expires_at > ?
+		order by id`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, revocation.ErrInternal)
+	}
+	defer rows.Close()
+
+	revocations = make([]models.Revocation, 0)
+
+	for rows.Next() {
+		var (
+			r   models.Revocation
+			jti sql.NullString
+		)
+
+		err = rows.Scan(&jti, &r.SessionId, &r.UserId, &r.RevokedAt, &r.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, revocation.ErrInternal)
+		}
+
+		r.TokenId = jti.String
+
+		revocations = append(revocations, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, revocation.ErrInternal)
+	}
+
+	return revocations, nil
+}
@@ -0,0 +1,211 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/acs"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveRole saves new role with its permissions and returns the role id.
+func (s *Storage) SaveRole(ctx context.Context,
+	role models.Role,
+) (roleId int32, err error) {
+	const op = "storage.sql.SaveRole"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.GetContext(
+		ctx,
+		&roleId,
+		`insert into roles (name, description) values (?, ?) returning id`,
+		role.Name,
+		role.Description,
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, acs.ErrRoleAlreadyExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	for _, p := range role.Permissions {
+		_, err = tx.ExecContext(
+			ctx,
+			`insert into role_permissions (role_id, permission_id) values (?, ?) on conflict do nothing`,
+			roleId,
+			p.Id,
+		)
+		if err != nil {
+			if s.dialect.IsForeignKeyViolation(err) {
+				return 0, fmt.Errorf("%s: %w", op, acs.ErrPermissionNotFound)
+			}
+
+			return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return roleId, nil
+}
+
+// SaveUserRole assigns the role to the user.
+func (s *Storage) SaveUserRole(ctx context.Context,
+	userId uint64,
+	roleId int32,
+) (err error) {
+	const op = "storage.sql.SaveUserRole"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`insert into user_roles (user_id, role_id) values (?, ?) on conflict do nothing`,
+		userId,
+		roleId,
+	)
+	if err != nil {
+		if s.dialect.IsForeignKeyViolation(err) {
+			return fmt.Errorf("%s: %w", op, acs.ErrRoleNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetUserRoles returns all roles of the user with their permissions.
+func (s *Storage) GetUserRoles(ctx context.Context,
+	userId uint64,
+) (roles []models.Role, err error) {
+	const op = "storage.sql.GetUserRoles"
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select r.id, r.name, r.description, p.id, p.resource, p.action, p.description
+		from user_roles ur
+		join roles r on r.id = ur.role_id
+		left join role_permissions rp on rp.role_id = r.id
+		left join permissions p on p.id = rp.permission_id
+		where ur.user_id = ?
+		order by r.id, p.id`,
+		userId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+	defer rows.Close()
+
+	roles = make([]models.Role, 0)
+
+	for rows.Next() {
+		var (
+			role                        models.Role
+			permId                      sql.NullInt32
+			resource, action, permDescr sql.NullString
+		)
+
+		err = rows.Scan(&role.Id, &role.Name, &role.Description, &permId, &resource, &action, &permDescr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Id != role.Id {
+			roles = append(roles, role)
+		}
+
+		if permId.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(
+				last.Permissions, models.Permission{
+					Id:          permId.Int32,
+					Resource:    resource.String,
+					Action:      action.String,
+					Description: permDescr.String,
+				},
+			)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return roles, nil
+}
+
+// DeleteRole deletes the role with the given name and returns its id.
+func (s *Storage) DeleteRole(ctx context.Context,
+	roleName string,
+) (roleId int32, err error) {
+	const op = "storage.sql.DeleteRole"
+
+	err = s.db.GetContext(ctx, &roleId, `delete from roles where name = ? returning id`, roleName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, acs.ErrRoleNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, acs.ErrInternal)
+	}
+
+	return roleId, nil
+}
+
+// DeleteUserRole takes the role away from the user.
+func (s *Storage) DeleteUserRole(ctx context.Context,
+	userId uint64,
+	roleId int32,
+) (err error) {
+	const op = "storage.sql.DeleteUserRole"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`delete from user_roles where user_id = ? and role_id = ?`,
+		userId,
+		roleId,
+	)
+
+	return wrapExecResult(op, res, err, acs.ErrRoleNotFound, acs.ErrInternal)
+}
+
+// GrantAdminRole assigns the admin role to the user.
+func (s *Storage) GrantAdminRole(ctx context.Context,
+	userId uint64,
+) (err error) {
+	const op = "storage.sql.GrantAdminRole"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`insert into user_roles (user_id, role_id)
+		select ?, id from roles where name = ?
+		on conflict do nothing`,
+		userId,
+		models.RoleAdmin,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	// The admin role is created by the migrations, it may be missing only if they are not applied
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
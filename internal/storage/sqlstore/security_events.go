@@ -0,0 +1,34 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveSecurityEvent saves the security event for audit.
+func (s *Storage) SaveSecurityEvent(ctx context.Context,
+	event models.SecurityEvent,
+) (err error) {
+	const op = "storage.sql.SaveSecurityEvent"
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`insert into security_events (type, user_id, session_id, app_id, actor, ip, created_at)
+		values (?, ?, ?, ?, ?, ?, ?)`,
+		event.Type,
+		event.UserId,
+		event.SessionId,
+		event.AppId,
+		nullString(event.Actor),
+		nullIP(event.IP),
+		event.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/keyring"
+)
+
+// SaveSigningKey saves the signing key.
+// If the key with the same id exists, ErrSigningKeyExists is returned.
+func (s *Storage) SaveSigningKey(ctx context.Context,
+	key models.SigningKey,
+) (err error) {
+	const op = "storage.sql.SaveSigningKey"
+
+	err = insertSigningKey(ctx, s.db, key)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, keyring.ErrSigningKeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+	}
+
+	return nil
+}
+
+// RotateSigningKey saves the new signing key and sets the expiration time of the keys which have none.
+// Unless rotatedBefore is zero, the key is not saved if any key activates after it.
+// The table is locked, so the concurrent rotations see the key saved by the first of them.
+func (s *Storage) RotateSigningKey(ctx context.Context,
+	key models.SigningKey,
+	retiredExpiresAt time.Time,
+	rotatedBefore time.Time,
+) (err error) {
+	const op = "storage.sql.RotateSigningKey"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if lock := s.dialect.LockTable("signing_keys"); lock != "" {
+		if _, err = tx.ExecContext(ctx, lock); err != nil {
+			return fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+		}
+	}
+
+	if !rotatedBefore.IsZero() {
+		var rotated bool
+
+		err = tx.GetContext(
+			ctx,
+			&rotated,
+			`select exists(select 1 from signing_keys where activates_at > ?)`,
+			rotatedBefore.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+		}
+
+		if rotated {
+			return fmt.Errorf("%s: %w", op, keyring.ErrKeyRotated)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`update signing_keys set expires_at = ? where expires_at is null`,
+		retiredExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+	}
+
+	err = insertSigningKey(ctx, tx, key)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, keyring.ErrSigningKeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetSigningKeys returns the unexpired signing keys.
+func (s *Storage) GetSigningKeys(ctx context.Context) (keys []models.SigningKey, err error) {
+	const op = "storage.sql.GetSigningKeys"
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select kid, algorithm, private_key, encrypted, created_at, activates_at, expires_at
+		from signing_keys
+		where expires_at is null or expires_at > ?
+		order by activates_at`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+	}
+	defer rows.Close()
+
+	keys = make([]models.SigningKey, 0)
+
+	for rows.Next() {
+		var (
+			key       models.SigningKey
+			expiresAt sql.NullTime
+		)
+
+		err = rows.Scan(
+			&key.Id, &key.Algorithm, &key.PrivateKey, &key.Encrypted,
+			&key.CreatedAt, &key.ActivatesAt, &expiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+		}
+
+		key.ExpiresAt = expiresAt.Time
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, keyring.ErrInternal)
+	}
+
+	return keys, nil
+}
+
+func insertSigningKey(ctx context.Context, db sqlx.ExecerContext, key models.SigningKey) error {
+	_, err := db.ExecContext(
+		ctx,
+		`insert into signing_keys (kid, algorithm, private_key, encrypted, created_at, activates_at)
+		values (?, ?, ?, ?, ?, ?)`,
+		key.Id,
+		key.Algorithm,
+		key.PrivateKey,
+		key.Encrypted,
+		key.CreatedAt.UTC(),
+		key.ActivatesAt.UTC(),
+	)
+
+	return err
+}
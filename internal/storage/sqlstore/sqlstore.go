@@ -0,0 +1,104 @@
+// Package sqlstore implements the repositories of the SQL storage drivers.
+// The queries are written once with the "?" placeholders, which are rebound to the ones of the driver,
+// and the drivers differ only in the connection, the migrations and the errors they report.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect describes what differs between the databases besides the placeholders.
+type Dialect interface {
+	// IsUniqueViolation reports whether the statement has violated the unique or the primary key constraint.
+	IsUniqueViolation(err error) bool
+	// IsForeignKeyViolation reports whether the statement has violated the foreign key constraint.
+	IsForeignKeyViolation(err error) bool
+	// LockTable returns the statement that keeps other transactions from writing to the table
+	// until the transaction ends, or nothing if the database serializes the transactions anyway.
+	LockTable(table string) string
+}
+
+type Storage struct {
+	db      conn
+	dialect Dialect
+}
+
+// New creates new instance of the storage on top of the connected database.
+func New(db *sqlx.DB, dialect Dialect) *Storage {
+	return &Storage{db: conn{DB: db}, dialect: dialect}
+}
+
+// Stop closes the database.
+func (s *Storage) Stop() error {
+	return s.db.Close()
+}
+
+// conn rebinds the placeholders of the queries to the ones of the driver, e.g. "$1" of Postgres.
+type conn struct {
+	*sqlx.DB
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.DB.ExecContext(ctx, c.Rebind(query), args...)
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.DB.QueryContext(ctx, c.Rebind(query), args...)
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.DB.QueryRowContext(ctx, c.Rebind(query), args...)
+}
+
+func (c conn) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return c.DB.GetContext(ctx, dest, c.Rebind(query), args...)
+}
+
+func (c conn) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return c.DB.SelectContext(ctx, dest, c.Rebind(query), args...)
+}
+
+func (c conn) BeginTxx(ctx context.Context, opts *sql.TxOptions) (tx, error) {
+	t, err := c.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return tx{}, err
+	}
+
+	return tx{Tx: t}, nil
+}
+
+// tx rebinds the placeholders of the queries of the transaction like conn does.
+type tx struct {
+	*sqlx.Tx
+}
+
+func (t tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.Rebind(query), args...)
+}
+
+func (t tx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return t.Tx.GetContext(ctx, dest, t.Rebind(query), args...)
+}
+
+// wrapExecResult maps the result of the update or delete statement to the storage errors:
+// notFound if no rows were affected and internal if the statement failed.
+func wrapExecResult(op string, res sql.Result, err, notFound, internal error) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, internal)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, internal)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, notFound)
+	}
+
+	return nil
+}
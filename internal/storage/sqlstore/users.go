@@ -0,0 +1,174 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/service/auth"
+)
+
+// SaveUser saves the user profile and credentials in one transaction.
+func (s *Storage) SaveUser(ctx context.Context,
+	creds models.UserCredentials,
+	profile models.Profile,
+) (err error) {
+	const op = "storage.sql.SaveUser"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	now := time.Now().UTC()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`insert into profiles (id, first_name, last_name, date_of_birth, created_at, updated_at)
+		values (?, ?, ?, ?, ?, ?)`,
+		creds.Id,
+		profile.FirstName,
+		nullString(profile.LastName),
+		nullTime(profile.DateOfBirth),
+		now,
+		now,
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, auth.ErrUserAlreadyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`insert into credentials (id, email, pass_hash) values (?, ?, ?)`,
+		creds.Id,
+		creds.Email,
+		string(creds.PasswordHash),
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, auth.ErrUserAlreadyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return nil
+}
+
+// GetUserCreds returns the credentials of the user with the given email.
+func (s *Storage) GetUserCreds(ctx context.Context,
+	email string,
+) (models.UserCredentials, error) {
+	const op = "storage.sql.GetUserCreds"
+
+	var creds models.UserCredentials
+
+	err := s.db.GetContext(
+		ctx,
+		&creds,
+		`select id, email, pass_hash from credentials where email = ?`,
+		email,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserCredentials{}, fmt.Errorf("%s: %w", op, auth.ErrUserNotFound)
+		}
+
+		return models.UserCredentials{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return creds, nil
+}
+
+// UpdatePassword replaces the password hash of the user with the given id.
+func (s *Storage) UpdatePassword(ctx context.Context,
+	userId uint64,
+	passHash []byte,
+) (err error) {
+	const op = "storage.sql.UpdatePassword"
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`update credentials set pass_hash = ? where id = ?`,
+		string(passHash),
+		userId,
+	)
+
+	return wrapExecResult(op, res, err, auth.ErrUserNotFound, auth.ErrInternal)
+}
+
+// UserExists reports whether the user with the given id exists.
+func (s *Storage) UserExists(ctx context.Context,
+	userId uint64,
+) (exists bool, err error) {
+	const op = "storage.sql.UserExists"
+
+	err = s.db.GetContext(
+		ctx,
+		&exists,
+		`select exists(select 1 from credentials where id = ?)`,
+		userId,
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	return exists, nil
+}
+
+// GetUserInfo returns the email and the brief profile of the user with the given id.
+func (s *Storage) GetUserInfo(ctx context.Context,
+	userId uint64,
+) (models.UserInfo, error) {
+	const op = "storage.sql.GetUserInfo"
+
+	var (
+		info        = models.UserInfo{UserId: userId}
+		lastName    sql.NullString
+		dateOfBirth sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`select c.email, p.first_name, p.last_name, p.date_of_birth
+		from credentials c join profiles p on p.id = c.id
+		where c.id = ?`,
+		userId,
+	).Scan(&info.Email, &info.FirstName, &lastName, &dateOfBirth)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserInfo{}, fmt.Errorf("%s: %w", op, auth.ErrUserNotFound)
+		}
+
+		return models.UserInfo{}, fmt.Errorf("%s: %w", op, auth.ErrInternal)
+	}
+
+	info.LastName = lastName.String
+	info.DateOfBirth = dateOfBirth.Time
+
+	return info, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
@@ -0,0 +1,76 @@
+package acs
+
+import (
+	"errors"
+	"math"
+
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrTokenNotValid           = errors.New("requester token is not valid")
+	ErrNotEnoughPermissions    = errors.New("not enough permissions")
+	ErrPermissionNotFound      = errors.New("permission not found")
+	ErrPermissionAlreadyExists = errors.New("permission already exists")
+	ErrRoleNotFound            = errors.New("role not found")
+	ErrRoleAlreadyExists       = errors.New("role already exists")
+	ErrAppNotFound             = errors.New("app not found")
+	ErrAppAlreadyExists        = errors.New("app already exists")
+	ErrInternal                = errors.New("internal error")
+	ErrUnknown                 = errors.New("unknown error")
+)
+
+// ACS interface must be implemented by the service layer
+type ACS interface {
+	Permissions
+	Roles
+	Apps
+}
+
+func Register(gRPC *grpc.Server, acsService ACS) {
+	acs.RegisterPermissionsServer(gRPC, &permissionsServerApi{acs: acsService})
+	acs.RegisterRolesServer(gRPC, &rolesServerApi{acs: acsService})
+	acs.RegisterAppsServer(gRPC, &appsServerApi{acs: acsService})
+}
+
+// statusError converts the service error to the gRPC status error.
+func statusError(err error) error {
+	switch err {
+	case ErrTokenNotValid:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case ErrNotEnoughPermissions:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case ErrPermissionNotFound, ErrRoleNotFound, ErrAppNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case ErrPermissionAlreadyExists, ErrRoleAlreadyExists, ErrAppAlreadyExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case ErrInternal:
+		return status.Error(codes.Internal, err.Error())
+	default:
+		return status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+}
+
+func validateRequesterToken(token string) error {
+	if token == "" {
+		return status.Error(codes.InvalidArgument, "requester_token is required")
+	}
+
+	return nil
+}
+
+func validateUserId(userId uint64) error {
+	if userId == 0 {
+		return status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	// Snowflake ids always fit into the signed bigint columns of the storages
+	if userId > math.MaxInt64 {
+		return status.Error(codes.InvalidArgument, "user_id is out of range")
+	}
+
+	return nil
+}
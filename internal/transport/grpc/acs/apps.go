@@ -0,0 +1,262 @@
+package acs
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type appsServerApi struct {
+	acs.UnimplementedAppsServer
+	acs ACS
+}
+
+// Apps interface must be implemented by the service layer
+type Apps interface {
+	CreateApp(ctx context.Context,
+		requesterToken string,
+		app models.App,
+	) (appId int32, secret string, err error)
+	GetApp(ctx context.Context,
+		requesterToken string,
+		appId int32,
+	) (app models.App, err error)
+	ListApps(ctx context.Context,
+		requesterToken string,
+	) (apps []models.App, err error)
+	UpdateApp(ctx context.Context,
+		requesterToken string,
+		app models.App,
+	) (err error)
+	DeleteApp(ctx context.Context,
+		requesterToken string,
+		appId int32,
+	) (err error)
+	ResetAppSecret(ctx context.Context,
+		requesterToken string,
+		appId int32,
+	) (secret string, err error)
+}
+
+// grantTypes are the grant types the app may be allowed
+var grantTypes = map[string]struct{}{
+	models.GrantPassword:          {},
+	models.GrantRefreshToken:      {},
+	models.GrantAuthorizationCode: {},
+	models.GrantClientCredentials: {},
+	models.GrantDeviceCode:        {},
+	models.GrantTokenExchange:     {},
+}
+
+func (s *appsServerApi) Create(
+	ctx context.Context,
+	req *acs.CreateAppRequest,
+) (*acs.CreateAppResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateApp(req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	id, secret, err := s.acs.CreateApp(ctx, req.GetRequesterToken(), appFromProto(req.GetApp()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.CreateAppResponse{
+		AppId:  id,
+		Secret: secret,
+	}, nil
+}
+
+func (s *appsServerApi) Get(
+	ctx context.Context,
+	req *acs.GetAppRequest,
+) (*acs.GetAppResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateAppId(req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	app, err := s.acs.GetApp(ctx, req.GetRequesterToken(), req.GetAppId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.GetAppResponse{
+		App: appToProto(app),
+	}, nil
+}
+
+func (s *appsServerApi) List(
+	ctx context.Context,
+	req *acs.ListAppsRequest,
+) (*acs.ListAppsResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	apps, err := s.acs.ListApps(ctx, req.GetRequesterToken())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &acs.ListAppsResponse{
+		Apps: make([]*acs.App, 0, len(apps)),
+	}
+
+	for _, app := range apps {
+		resp.Apps = append(resp.Apps, appToProto(app))
+	}
+
+	return resp, nil
+}
+
+func (s *appsServerApi) Update(
+	ctx context.Context,
+	req *acs.UpdateAppRequest,
+) (*acs.UpdateAppResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateAppId(req.GetApp().GetAppId()); err != nil {
+		return nil, err
+	}
+
+	if err := validateApp(req.GetApp()); err != nil {
+		return nil, err
+	}
+
+	err := s.acs.UpdateApp(ctx, req.GetRequesterToken(), appFromProto(req.GetApp()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.UpdateAppResponse{}, nil
+}
+
+func (s *appsServerApi) Delete(
+	ctx context.Context,
+	req *acs.DeleteAppRequest,
+) (*acs.DeleteAppResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateAppId(req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	err := s.acs.DeleteApp(ctx, req.GetRequesterToken(), req.GetAppId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.DeleteAppResponse{}, nil
+}
+
+func (s *appsServerApi) ResetSecret(
+	ctx context.Context,
+	req *acs.ResetAppSecretRequest,
+) (*acs.ResetAppSecretResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateAppId(req.GetAppId()); err != nil {
+		return nil, err
+	}
+
+	secret, err := s.acs.ResetAppSecret(ctx, req.GetRequesterToken(), req.GetAppId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.ResetAppSecretResponse{
+		Secret: secret,
+	}, nil
+}
+
+func appFromProto(app *acs.App) models.App {
+	res := models.App{
+		Id:           app.GetAppId(),
+		Name:         app.GetName(),
+		FirstParty:   app.GetFirstParty(),
+		RedirectURIs: app.GetRedirectUris(),
+		GrantTypes:   app.GetGrantTypes(),
+	}
+
+	for _, p := range app.GetPermissions() {
+		res.Permissions = append(res.Permissions, models.Permission{Id: p.GetPermissionId()})
+	}
+
+	return res
+}
+
+func appToProto(app models.App) *acs.App {
+	appId := app.Id
+	res := &acs.App{
+		AppId:        &appId,
+		Name:         app.Name,
+		RedirectUris: app.RedirectURIs,
+		GrantTypes:   app.GrantTypes,
+		CreatedAt:    timestamppb.New(app.CreatedAt),
+		FirstParty:   app.FirstParty,
+	}
+
+	for _, p := range app.Permissions {
+		permId := p.Id
+		res.Permissions = append(
+			res.Permissions, &acs.Permission{
+				PermissionId: &permId,
+				Resource:     p.Resource,
+				Action:       p.Action,
+				Description:  p.Description,
+			},
+		)
+	}
+
+	return res
+}
+
+func validateAppId(appId int32) error {
+	if appId <= 0 {
+		return status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	return nil
+}
+
+// validateApp checks the app the way RFC 6749 requires:
+// the redirect URIs must be absolute and must not have the fragment.
+func validateApp(app *acs.App) error {
+	if app.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "app name is required")
+	}
+
+	for _, uri := range app.GetRedirectUris() {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+			return status.Errorf(codes.InvalidArgument, "redirect uri %q must be absolute and without fragment", uri)
+		}
+	}
+
+	for _, grantType := range app.GetGrantTypes() {
+		if _, ok := grantTypes[grantType]; !ok {
+			return status.Errorf(codes.InvalidArgument, "unknown grant type %q", grantType)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package acs
+
+import (
+	"context"
+
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type permissionsServerApi struct {
+	acs.UnimplementedPermissionsServer
+	acs ACS
+}
+
+// Permissions interface must be implemented by the service layer
+type Permissions interface {
+	CreatePermission(ctx context.Context,
+		requesterToken,
+		resource, action,
+		description string,
+	) (id int32, err error)
+	CheckUserPermission(ctx context.Context,
+		requesterToken string,
+		userId uint64,
+		resource, action string,
+	) (ok bool, err error)
+	DeletePermission(ctx context.Context,
+		requesterToken string,
+		permissionId int32,
+	) (err error)
+	AddPermission(ctx context.Context,
+		requesterToken string,
+		roleId int32,
+		permissionId int32,
+	) (err error)
+	RemovePermission(ctx context.Context,
+		requesterToken string,
+		roleId int32,
+		permissionId int32,
+	) (err error)
+}
+
+func (s *permissionsServerApi) Create(
+	ctx context.Context,
+	req *acs.CreatePermissionRequest,
+) (*acs.CreatePermissionResponse, error) {
+	if err := validateCreatePermission(req); err != nil {
+		return nil, err
+	}
+
+	id, err := s.acs.CreatePermission(
+		ctx,
+		req.GetRequesterToken(),
+		req.GetPermission().GetResource(),
+		req.GetPermission().GetAction(),
+		req.GetPermission().GetDescription(),
+	)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.CreatePermissionResponse{
+		PermissionId: id,
+	}, nil
+}
+
+func (s *permissionsServerApi) CheckPermissions(
+	ctx context.Context,
+	req *acs.CheckPermissionsRequest,
+) (*acs.CheckPermissionsResponse, error) {
+	if err := validateCheckPermissions(req); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.acs.CheckUserPermission(
+		ctx,
+		req.GetRequesterToken(),
+		req.GetUserId(),
+		req.GetResource(),
+		req.GetAction(),
+	)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.CheckPermissionsResponse{
+		Ok: ok,
+	}, nil
+}
+
+func (s *permissionsServerApi) Delete(
+	ctx context.Context,
+	req *acs.DeletePermissionRequest,
+) (*acs.DeletePermissionResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	err := s.acs.DeletePermission(ctx, req.GetRequesterToken(), req.GetPermissionId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.DeletePermissionResponse{}, nil
+}
+
+func (s *permissionsServerApi) Add(
+	ctx context.Context,
+	req *acs.AddPermissionRequest,
+) (*acs.AddPermissionResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	err := s.acs.AddPermission(ctx, req.GetRequesterToken(), req.GetRoleId(), req.GetPermissionId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.AddPermissionResponse{}, nil
+}
+
+func (s *permissionsServerApi) Remove(
+	ctx context.Context,
+	req *acs.RemovePermissionRequest,
+) (*acs.RemovePermissionResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	err := s.acs.RemovePermission(ctx, req.GetRequesterToken(), req.GetRoleId(), req.GetPermissionId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.RemovePermissionResponse{}, nil
+}
+
+func validateCreatePermission(req *acs.CreatePermissionRequest) error {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return err
+	}
+
+	if req.GetPermission().GetResource() == "" {
+		return status.Error(codes.InvalidArgument, "resource is required")
+	}
+
+	if req.GetPermission().GetAction() == "" {
+		return status.Error(codes.InvalidArgument, "action is required")
+	}
+
+	return nil
+}
+
+func validateCheckPermissions(req *acs.CheckPermissionsRequest) error {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return err
+	}
+
+	if err := validateUserId(req.GetUserId()); err != nil {
+		return err
+	}
+
+	if req.GetResource() == "" {
+		return status.Error(codes.InvalidArgument, "resource is required")
+	}
+
+	if req.GetAction() == "" {
+		return status.Error(codes.InvalidArgument, "action is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package acs
+
+import (
+	"context"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type rolesServerApi struct {
+	acs.UnimplementedRolesServer
+	acs ACS
+}
+
+// Roles interface must be implemented by the service layer
+type Roles interface {
+	CreateRole(ctx context.Context,
+		requesterToken string,
+		role models.Role,
+	) (roleId int32, err error)
+	GetUserRoles(ctx context.Context,
+		requesterToken string,
+		userId uint64,
+	) (roles []models.Role, err error)
+	DeleteRole(ctx context.Context,
+		requesterToken string,
+		roleName string,
+	) (err error)
+	AddRole(ctx context.Context,
+		requesterToken string,
+		userId uint64,
+		roleId int32,
+	) (err error)
+	RemoveRole(ctx context.Context,
+		requesterToken string,
+		userId uint64,
+		roleId int32,
+	) (err error)
+}
+
+func (s *rolesServerApi) Create(
+	ctx context.Context,
+	req *acs.CreateRoleRequest,
+) (*acs.CreateRoleResponse, error) {
+	if err := validateCreateRole(req); err != nil {
+		return nil, err
+	}
+
+	role := models.Role{
+		Name:        req.GetRole().GetName(),
+		Description: req.GetRole().GetDescription(),
+	}
+
+	for _, p := range req.GetRole().GetPermissions() {
+		role.Permissions = append(role.Permissions, models.Permission{Id: p.GetPermissionId()})
+	}
+
+	id, err := s.acs.CreateRole(ctx, req.GetRequesterToken(), role)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.CreateRoleResponse{
+		RoleId: id,
+	}, nil
+}
+
+func (s *rolesServerApi) GetUserRoles(
+	ctx context.Context,
+	req *acs.GetUserRolesRequest,
+) (*acs.GetUserRolesResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateUserId(req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.acs.GetUserRoles(ctx, req.GetRequesterToken(), req.GetUserId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &acs.GetUserRolesResponse{
+		Roles: make([]*acs.Role, 0, len(roles)),
+	}
+
+	for _, r := range roles {
+		roleId := r.Id
+		role := &acs.Role{
+			RoleId:      &roleId,
+			Name:        r.Name,
+			Description: r.Description,
+		}
+
+		for _, p := range r.Permissions {
+			permId := p.Id
+			role.Permissions = append(
+				role.Permissions, &acs.Permission{
+					PermissionId: &permId,
+					Resource:     p.Resource,
+					Action:       p.Action,
+					Description:  p.Description,
+				},
+			)
+		}
+
+		resp.Roles = append(resp.Roles, role)
+	}
+
+	return resp, nil
+}
+
+func (s *rolesServerApi) Delete(
+	ctx context.Context,
+	req *acs.DeleteRoleRequest,
+) (*acs.DeleteRoleResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if req.GetRoleName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "role_name is required")
+	}
+
+	err := s.acs.DeleteRole(ctx, req.GetRequesterToken(), req.GetRoleName())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.DeleteRoleResponse{}, nil
+}
+
+func (s *rolesServerApi) Add(
+	ctx context.Context,
+	req *acs.AddRoleRequest,
+) (*acs.AddRoleResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateUserId(req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	err := s.acs.AddRole(ctx, req.GetRequesterToken(), req.GetUserId(), req.GetRoleId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.AddRoleResponse{}, nil
+}
+
+func (s *rolesServerApi) Remove(
+	ctx context.Context,
+	req *acs.RemoveRoleRequest,
+) (*acs.RemoveRoleResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateUserId(req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	err := s.acs.RemoveRole(ctx, req.GetRequesterToken(), req.GetUserId(), req.GetRoleId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &acs.RemoveRoleResponse{}, nil
+}
+
+func validateCreateRole(req *acs.CreateRoleRequest) error {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return err
+	}
+
+	if req.GetRole().GetName() == "" {
+		return status.Error(codes.InvalidArgument, "role name is required")
+	}
+
+	return nil
+}
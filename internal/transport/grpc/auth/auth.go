@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"net"
 	"regexp"
 	"time"
 
@@ -10,18 +11,34 @@ import (
 	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
 	passMinLen = 8
 	passMaxlen = 36
+
+	deviceNameMaxLen = 64
 )
 
 var (
 	ErrWrongCredentials  = errors.New("invalid email or password")
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrTokenNotFound     = errors.New("provided refresh token is not exists")
+	ErrTokenRevoked      = errors.New("provided refresh token has been revoked")
+	ErrTokenUsed         = errors.New("provided refresh token has been used by a concurrent request")
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrAppNotFound       = errors.New("unknown app")
+	ErrAppMismatch       = errors.New("provided refresh token was issued for another app")
+	ErrGrantNotAllowed   = errors.New("app is not allowed to use this grant type")
+	ErrGrantNotFound     = errors.New("user has not granted anything to the app")
+	ErrInvalidResetToken = errors.New("password reset token is invalid or has expired")
+	ErrSubscriberTooSlow = errors.New("subscriber does not keep up with the revocations, watch again")
+	ErrRequesterNotValid = errors.New("requester token is not valid")
+	ErrPermissionDenied  = errors.New("requester is not granted the permission")
 	ErrInternal          = errors.New("internal error")
 	ErrUnknown           = errors.New("unknown error")
 )
@@ -35,14 +52,67 @@ type serverApi struct {
 type Auth interface {
 	Login(ctx context.Context,
 		creds models.Credentials,
+		device models.Device,
+		appId int32,
+		scopes []string,
 	) (accessToken, refreshToken string, err error)
 	RegisterNewUser(ctx context.Context,
 		creds models.Credentials,
 		profile models.BriefProfile,
+		appId int32,
 	) (userId uint64, err error)
 	RefreshTokens(ctx context.Context,
 		token string,
+		ip net.IP,
+		appId int32,
 	) (accessToken, refreshToken string, err error)
+	Logout(ctx context.Context,
+		token string,
+		ip net.IP,
+	) (err error)
+	LogoutAll(ctx context.Context,
+		token string,
+		ip net.IP,
+	) (err error)
+	ListSessions(ctx context.Context,
+		token string,
+		ip net.IP,
+	) (sessions []models.Session, err error)
+	RevokeSession(ctx context.Context,
+		token string,
+		sessionId uint64,
+		ip net.IP,
+	) (err error)
+	ListGrants(ctx context.Context,
+		token string,
+		ip net.IP,
+	) (grants []models.Grant, err error)
+	RevokeGrant(ctx context.Context,
+		token string,
+		appId int32,
+		ip net.IP,
+	) (err error)
+	RequestPasswordReset(ctx context.Context,
+		email string,
+		ip net.IP,
+	) (err error)
+	ResetPassword(ctx context.Context,
+		token string,
+		password string,
+		ip net.IP,
+	) (err error)
+	Introspect(ctx context.Context,
+		requesterToken string,
+		token string,
+		tokenTypeHint string,
+	) (info models.Introspection, err error)
+	RevokeAccessToken(ctx context.Context,
+		token string,
+	) (err error)
+	WatchRevocations(ctx context.Context,
+		requesterToken string,
+		send func(revocation models.Revocation) error,
+	) (err error)
 }
 
 func Register(gRPC *grpc.Server, authService Auth) {
@@ -62,11 +132,19 @@ func (s *serverApi) Login(
 		Password: req.GetCreds().GetPassword(),
 	}
 
-	access, refresh, err := s.auth.Login(ctx, creds)
+	device := models.Device{
+		Name:      req.GetDeviceName(),
+		UserAgent: userAgent(ctx),
+		IP:        peerIP(ctx),
+	}
+
+	access, refresh, err := s.auth.Login(ctx, creds, device, req.GetAppId(), req.GetScopes())
 	switch err {
 	case nil: // Do nothing
-	case ErrWrongCredentials:
+	case ErrWrongCredentials, ErrAppNotFound:
 		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case ErrGrantNotAllowed:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
 	case ErrInternal:
 		return nil, status.Error(codes.Internal, err.Error())
 	default:
@@ -98,11 +176,13 @@ func (s *serverApi) Register(
 		DateOfBirth: req.GetProfile().GetDateOfBirth().AsTime(),
 	}
 
-	userId, err := s.auth.RegisterNewUser(ctx, creds, profile)
+	userId, err := s.auth.RegisterNewUser(ctx, creds, profile, req.GetAppId())
 	switch err {
 	case nil: // Do nothing
 	case ErrUserAlreadyExists:
 		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case ErrAppNotFound:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	default:
 		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
 	}
@@ -120,11 +200,21 @@ func (s *serverApi) Refresh(
 		return nil, err
 	}
 
-	access, refresh, err := s.auth.RefreshTokens(ctx, req.GetRefreshToken())
+	access, refresh, err := s.auth.RefreshTokens(ctx, req.GetRefreshToken(), peerIP(ctx), req.GetAppId())
 	switch err {
 	case nil: // Do nothing
 	case ErrTokenNotFound:
 		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrAppNotFound:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case ErrAppMismatch, ErrGrantNotAllowed:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case ErrTokenRevoked, ErrSessionNotFound:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrTokenUsed:
+		return nil, status.Error(codes.Aborted, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
 	default:
 		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
 	}
@@ -135,6 +225,357 @@ func (s *serverApi) Refresh(
 	}, nil
 }
 
+func (s *serverApi) Logout(
+	ctx context.Context,
+	req *auth.LogoutRequest,
+) (*auth.LogoutResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	err := s.auth.Logout(ctx, req.GetRefreshToken(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotFound, ErrSessionNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrTokenUsed:
+		return nil, status.Error(codes.Aborted, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &auth.LogoutResponse{}, nil
+}
+
+func (s *serverApi) LogoutAll(
+	ctx context.Context,
+	req *auth.LogoutAllRequest,
+) (*auth.LogoutAllResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	err := s.auth.LogoutAll(ctx, req.GetRefreshToken(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrTokenRevoked:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrTokenUsed:
+		return nil, status.Error(codes.Aborted, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &auth.LogoutAllResponse{}, nil
+}
+
+func (s *serverApi) ListSessions(
+	ctx context.Context,
+	req *auth.ListSessionsRequest,
+) (*auth.ListSessionsResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	sessions, err := s.auth.ListSessions(ctx, req.GetRefreshToken(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrTokenRevoked:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrTokenUsed:
+		return nil, status.Error(codes.Aborted, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	resp := &auth.ListSessionsResponse{
+		Sessions: make([]*auth.Session, 0, len(sessions)),
+	}
+
+	for _, session := range sessions {
+		var ip string
+		if session.IP != nil {
+			ip = session.IP.String()
+		}
+
+		resp.Sessions = append(
+			resp.Sessions, &auth.Session{
+				SessionId:   session.Id,
+				DeviceName:  session.DeviceName,
+				UserAgent:   session.UserAgent,
+				Ip:          ip,
+				RefreshedAt: timestamppb.New(session.RefreshedAt),
+				ExpiresAt:   timestamppb.New(session.ExpiresIn),
+				Current:     session.Current,
+				AppId:       session.AppId,
+			},
+		)
+	}
+
+	return resp, nil
+}
+
+func (s *serverApi) RevokeSession(
+	ctx context.Context,
+	req *auth.RevokeSessionRequest,
+) (*auth.RevokeSessionResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	if req.GetSessionId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+
+	err := s.auth.RevokeSession(ctx, req.GetRefreshToken(), req.GetSessionId(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotFound, ErrSessionNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrTokenRevoked:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrTokenUsed:
+		return nil, status.Error(codes.Aborted, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &auth.RevokeSessionResponse{}, nil
+}
+
+func (s *serverApi) ListGrants(
+	ctx context.Context,
+	req *auth.ListGrantsRequest,
+) (*auth.ListGrantsResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	grants, err := s.auth.ListGrants(ctx, req.GetRefreshToken(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrTokenRevoked:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrTokenUsed:
+		return nil, status.Error(codes.Aborted, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	resp := &auth.ListGrantsResponse{
+		Grants: make([]*auth.Grant, 0, len(grants)),
+	}
+
+	for _, grant := range grants {
+		resp.Grants = append(
+			resp.Grants, &auth.Grant{
+				AppId:     grant.AppId,
+				AppName:   grant.AppName,
+				Scopes:    grant.Scopes,
+				CreatedAt: timestamppb.New(grant.CreatedAt),
+				UpdatedAt: timestamppb.New(grant.UpdatedAt),
+			},
+		)
+	}
+
+	return resp, nil
+}
+
+func (s *serverApi) RevokeGrant(
+	ctx context.Context,
+	req *auth.RevokeGrantRequest,
+) (*auth.RevokeGrantResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	if req.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	err := s.auth.RevokeGrant(ctx, req.GetRefreshToken(), req.GetAppId(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotFound, ErrGrantNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrTokenRevoked:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrTokenUsed:
+		return nil, status.Error(codes.Aborted, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &auth.RevokeGrantResponse{}, nil
+}
+
+func (s *serverApi) RequestPasswordReset(
+	ctx context.Context,
+	req *auth.RequestPasswordResetRequest,
+) (*auth.RequestPasswordResetResponse, error) {
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	if !isEmail(req.GetEmail()) {
+		return nil, status.Error(codes.InvalidArgument, "email has the wrong structure")
+	}
+
+	err := s.auth.RequestPasswordReset(ctx, req.GetEmail(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &auth.RequestPasswordResetResponse{}, nil
+}
+
+func (s *serverApi) ResetPassword(
+	ctx context.Context,
+	req *auth.ResetPasswordRequest,
+) (*auth.ResetPasswordResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if err := validatePassword(req.GetPassword()); err != nil {
+		return nil, err
+	}
+
+	err := s.auth.ResetPassword(ctx, req.GetToken(), req.GetPassword(), peerIP(ctx))
+	switch err {
+	case nil: // Do nothing
+	case ErrInvalidResetToken:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &auth.ResetPasswordResponse{}, nil
+}
+
+func (s *serverApi) Introspect(
+	ctx context.Context,
+	req *auth.IntrospectRequest,
+) (*auth.IntrospectResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	info, err := s.auth.Introspect(ctx, req.GetRequesterToken(), req.GetToken(), req.GetTokenTypeHint())
+	switch err {
+	case nil: // Do nothing
+	case ErrRequesterNotValid:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrPermissionDenied:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	if !info.Active {
+		return &auth.IntrospectResponse{Active: false}, nil
+	}
+
+	resp := &auth.IntrospectResponse{
+		Active:    true,
+		TokenType: info.TokenType,
+		UserId:    info.UserId,
+		AppId:     info.AppId,
+		Scopes:    info.Scopes,
+		SessionId: info.SessionId,
+		ExpiresAt: timestamppb.New(info.ExpiresAt),
+		TokenId:   info.TokenId,
+		Issuer:    info.Issuer,
+		Audience:  info.Audience,
+	}
+	if !info.IssuedAt.IsZero() {
+		resp.IssuedAt = timestamppb.New(info.IssuedAt)
+	}
+
+	return resp, nil
+}
+
+func (s *serverApi) RevokeAccessToken(
+	ctx context.Context,
+	req *auth.RevokeAccessTokenRequest,
+) (*auth.RevokeAccessTokenResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access token is required")
+	}
+
+	err := s.auth.RevokeAccessToken(ctx, req.GetAccessToken())
+	switch err {
+	case nil: // Do nothing
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &auth.RevokeAccessTokenResponse{}, nil
+}
+
+func (s *serverApi) WatchRevocations(
+	req *auth.WatchRevocationsRequest,
+	stream auth.Auth_WatchRevocationsServer,
+) error {
+	err := s.auth.WatchRevocations(
+		stream.Context(), req.GetRequesterToken(), func(r models.Revocation) error {
+			return stream.Send(
+				&auth.Revocation{
+					TokenId:   r.TokenId,
+					SessionId: r.SessionId,
+					UserId:    r.UserId,
+					RevokedAt: timestamppb.New(r.RevokedAt),
+					ExpiresAt: timestamppb.New(r.ExpiresAt),
+				},
+			)
+		},
+	)
+	switch err {
+	case nil: // Do nothing
+	case ErrRequesterNotValid:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case ErrPermissionDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case ErrInternal:
+		return status.Error(codes.Internal, err.Error())
+	case ErrSubscriberTooSlow:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		// The stream is broken, the client will not get the status anyway
+		return err
+	}
+
+	return nil
+}
+
 func validateLogin(req *auth.LoginRequest) error {
 	if req.GetCreds().GetEmail() == "" {
 		return status.Error(codes.InvalidArgument, "email is required")
@@ -152,6 +593,14 @@ func validateLogin(req *auth.LoginRequest) error {
 		return status.Error(codes.InvalidArgument, "password length is not within the allowed range")
 	}
 
+	if len(req.GetDeviceName()) > deviceNameMaxLen {
+		return status.Error(codes.InvalidArgument, "device_name is too long")
+	}
+
+	if req.GetAppId() < 0 {
+		return status.Error(codes.InvalidArgument, "app_id must not be negative")
+	}
+
 	return nil
 }
 
@@ -164,12 +613,8 @@ func validateRegister(req *auth.RegisterRequest) error {
 		return status.Error(codes.InvalidArgument, "email has the wrong structure")
 	}
 
-	if req.GetCreds().GetPassword() == "" {
-		return status.Error(codes.InvalidArgument, "password is required")
-	}
-
-	if len(req.GetCreds().GetPassword()) < passMinLen || len(req.GetCreds().GetPassword()) > passMaxlen {
-		return status.Error(codes.InvalidArgument, "password length is not within the allowed range")
+	if err := validatePassword(req.GetCreds().GetPassword()); err != nil {
+		return err
 	}
 
 	if req.GetProfile().GetFirstName() == "" {
@@ -184,6 +629,23 @@ func validateRegister(req *auth.RegisterRequest) error {
 		return status.Error(codes.InvalidArgument, "too old")
 	}
 
+	if req.GetAppId() < 0 {
+		return status.Error(codes.InvalidArgument, "app_id must not be negative")
+	}
+
+	return nil
+}
+
+// validatePassword checks the new password of the user, it is chosen on the registration and on the reset.
+func validatePassword(password string) error {
+	if password == "" {
+		return status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	if len(password) < passMinLen || len(password) > passMaxlen {
+		return status.Error(codes.InvalidArgument, "password length is not within the allowed range")
+	}
+
 	return nil
 }
 
@@ -192,9 +654,41 @@ func validateRefresh(req *auth.RefreshRequest) error {
 		return status.Error(codes.InvalidArgument, "refresh token is required")
 	}
 
+	if req.GetAppId() < 0 {
+		return status.Error(codes.InvalidArgument, "app_id must not be negative")
+	}
+
+	return nil
+}
+
+// peerIP returns the IP address of the client or nil if it is unknown.
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+
 	return nil
 }
 
+// userAgent returns the user agent of the client or empty string if it is unknown.
+func userAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if ua := md.Get("user-agent"); len(ua) > 0 {
+		return ua[0]
+	}
+
+	return ""
+}
+
 func isEmail(email string) bool {
 	pattern, err := regexp.Compile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 	if err != nil {
@@ -0,0 +1,292 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/profile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	usernameMinLen = 3
+	usernameMaxLen = 32
+
+	pathUsername   = "username"
+	pathAvatarHash = "avatar_hash"
+)
+
+var (
+	ErrTokenNotValid        = errors.New("requester token is not valid")
+	ErrNotEnoughPermissions = errors.New("not enough permissions")
+	ErrProfileNotFound      = errors.New("profile not found")
+	ErrProfileAlreadyExists = errors.New("profile already exists")
+	ErrInternal             = errors.New("internal error")
+	ErrUnknown              = errors.New("unknown error")
+)
+
+type serverApi struct {
+	profile.UnimplementedProfilesServer
+	profile Profile
+}
+
+// Profile interface must be implemented by the service layer
+type Profile interface {
+	Create(ctx context.Context,
+		requesterToken string,
+		username string,
+		avatarHash string,
+		idp string,
+		accountId string,
+	) (profileId uint64, err error)
+	Update(ctx context.Context,
+		requesterToken string,
+		profileId uint64,
+		update models.ProfileUpdate,
+	) (err error)
+	Get(ctx context.Context,
+		requesterToken string,
+		profileId uint64,
+	) (profile models.Profile, err error)
+	Delete(ctx context.Context,
+		requesterToken string,
+		profileId uint64,
+	) (err error)
+}
+
+func Register(gRPC *grpc.Server, profileService Profile) {
+	profile.RegisterProfilesServer(gRPC, &serverApi{profile: profileService})
+}
+
+func (s *serverApi) Create(
+	ctx context.Context,
+	req *profile.CreateProfileRequest,
+) (*profile.CreateProfileResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateCreate(req); err != nil {
+		return nil, err
+	}
+
+	p := req.GetProfile()
+
+	id, err := s.profile.Create(
+		ctx, req.GetRequesterToken(),
+		p.GetUsername(), p.GetAvatarHash(), p.GetIdentityProvider().String(), p.GetAccountId(),
+	)
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotValid:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrNotEnoughPermissions:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case ErrProfileAlreadyExists:
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &profile.CreateProfileResponse{
+		ProfileId: id,
+	}, nil
+}
+
+func (s *serverApi) Update(
+	ctx context.Context,
+	req *profile.UpdateProfileRequest,
+) (*profile.UpdateProfileResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	update, err := validateUpdate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.profile.Update(ctx, req.GetRequesterToken(), req.GetProfile().GetProfileId(), update)
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotValid:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrNotEnoughPermissions:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case ErrProfileNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &profile.UpdateProfileResponse{}, nil
+}
+
+func (s *serverApi) Get(
+	ctx context.Context,
+	req *profile.GetProfileRequest,
+) (*profile.GetProfileResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateProfileId(req.GetProfileId()); err != nil {
+		return nil, err
+	}
+
+	p, err := s.profile.Get(ctx, req.GetRequesterToken(), req.GetProfileId())
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotValid:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrNotEnoughPermissions:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case ErrProfileNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &profile.GetProfileResponse{
+		Profile: &profile.Profile{
+			ProfileId:        p.Id,
+			Username:         p.Username,
+			AvatarHash:       p.AvatarHash,
+			IdentityProvider: profile.IdP(profile.IdP_value[p.Idp]),
+			AccountId:        p.AccountId,
+		},
+	}, nil
+}
+
+func (s *serverApi) Delete(
+	ctx context.Context,
+	req *profile.DeleteProfileRequest,
+) (*profile.DeleteProfileResponse, error) {
+	if err := validateRequesterToken(req.GetRequesterToken()); err != nil {
+		return nil, err
+	}
+
+	if err := validateProfileId(req.GetProfileId()); err != nil {
+		return nil, err
+	}
+
+	err := s.profile.Delete(ctx, req.GetRequesterToken(), req.GetProfileId())
+	switch err {
+	case nil: // Do nothing
+	case ErrTokenNotValid:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case ErrNotEnoughPermissions:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case ErrProfileNotFound:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case ErrInternal:
+		return nil, status.Error(codes.Internal, err.Error())
+	default:
+		return nil, status.Error(codes.Unknown, ErrUnknown.Error())
+	}
+
+	return &profile.DeleteProfileResponse{}, nil
+}
+
+func validateCreate(req *profile.CreateProfileRequest) error {
+	if err := validateUsername(req.GetProfile().GetUsername()); err != nil {
+		return err
+	}
+
+	if _, ok := profile.IdP_name[int32(req.GetProfile().GetIdentityProvider())]; !ok {
+		return status.Error(codes.InvalidArgument, "identity_provider is unknown")
+	}
+
+	if req.GetProfile().GetIdentityProvider() != profile.IdP_Internal && req.GetProfile().GetAccountId() == "" {
+		return status.Error(codes.InvalidArgument, "account_id is required for external identity providers")
+	}
+
+	return nil
+}
+
+// validateUpdate validates the request and collects the fields to update:
+// the ones listed in the update mask or, if the mask is empty, the non-empty ones.
+func validateUpdate(req *profile.UpdateProfileRequest) (models.ProfileUpdate, error) {
+	var update models.ProfileUpdate
+
+	if err := validateProfileId(req.GetProfile().GetProfileId()); err != nil {
+		return update, err
+	}
+
+	p := req.GetProfile()
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		if p.GetUsername() != "" {
+			paths = append(paths, pathUsername)
+		}
+
+		if p.GetAvatarHash() != "" {
+			paths = append(paths, pathAvatarHash)
+		}
+	}
+
+	for _, path := range paths {
+		switch path {
+		case pathUsername:
+			if err := validateUsername(p.GetUsername()); err != nil {
+				return update, err
+			}
+
+			update.Username = &p.Username
+		case pathAvatarHash:
+			update.AvatarHash = &p.AvatarHash
+		default:
+			return update, status.Errorf(codes.InvalidArgument, "field %q can not be updated", path)
+		}
+	}
+
+	if update.Username == nil && update.AvatarHash == nil {
+		return update, status.Error(codes.InvalidArgument, "nothing to update")
+	}
+
+	return update, nil
+}
+
+func validateRequesterToken(token string) error {
+	if token == "" {
+		return status.Error(codes.InvalidArgument, "requester_token is required")
+	}
+
+	return nil
+}
+
+func validateProfileId(profileId uint64) error {
+	if profileId == 0 {
+		return status.Error(codes.InvalidArgument, "profile_id is required")
+	}
+
+	// Snowflake ids always fit into the signed bigint columns of the storages
+	if profileId > math.MaxInt64 {
+		return status.Error(codes.InvalidArgument, "profile_id is out of range")
+	}
+
+	return nil
+}
+
+func validateUsername(username string) error {
+	if username == "" {
+		return status.Error(codes.InvalidArgument, "username is required")
+	}
+
+	if len(username) < usernameMinLen || len(username) > usernameMaxLen {
+		return status.Error(codes.InvalidArgument, "username length is not within the allowed range")
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package introspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// Path of the token introspection endpoint (RFC 7662).
+const Path = "/introspect"
+
+// Error codes of RFC 6749, section 5.2, and of RFC 6750, section 3.1
+const (
+	errInvalidRequest    = "invalid_request"
+	errInvalidClient     = "invalid_client"
+	errInvalidToken      = "invalid_token"
+	errInsufficientScope = "insufficient_scope"
+	errServerError       = "server_error"
+)
+
+var (
+	ErrInvalidClient        = errors.New("unknown client or client authentication failed")
+	ErrInvalidToken         = errors.New("access token is invalid, expired or revoked")
+	ErrNotEnoughPermissions = errors.New("requester is not granted the token:introspect permission")
+	ErrInternal             = errors.New("internal error")
+)
+
+// tokenTypeBearer is the OAuth 2.0 type of the access tokens (RFC 6749, section 7.1)
+const tokenTypeBearer = "Bearer"
+
+type handler struct {
+	log          *slog.Logger
+	introspector Introspector
+}
+
+// Introspector interface must be implemented by the service layer
+type Introspector interface {
+	IntrospectToken(ctx context.Context,
+		requester models.Requester,
+		token string,
+		tokenTypeHint string,
+	) (info models.Introspection, err error)
+}
+
+// response is the introspection response of RFC 7662, section 2.2.
+// TokenUse and Sid are the extensions telling the access token from the refresh one and the session of the token.
+type response struct {
+	Active    bool     `json:"active"`
+	TokenType string   `json:"token_type,omitempty"`
+	TokenUse  string   `json:"token_use,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientId  string   `json:"client_id,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Sid       string   `json:"sid,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func Register(mux *http.ServeMux, log *slog.Logger, introspector Introspector) {
+	mux.Handle(Path, &handler{log: log, introspector: introspector})
+}
+
+// ServeHTTP introspects the token posted as the form parameter.
+// The resource server authenticates with the credentials of its app, sent with HTTP Basic
+// or as the form parameters, or with the access token sent with the Authorization: Bearer header.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "introspect.ServeHTTP"
+
+	log := h.log.With(slog.String("op", op))
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	token := r.PostFormValue("token")
+
+	req, ok := requester(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="introspect"`)
+		writeJSON(log, w, http.StatusUnauthorized, errorResponse{
+			Error:            errInvalidClient,
+			ErrorDescription: "client authentication is required",
+		})
+
+		return
+	}
+
+	if token == "" {
+		writeJSON(log, w, http.StatusBadRequest, errorResponse{
+			Error:            errInvalidRequest,
+			ErrorDescription: "token is required",
+		})
+
+		return
+	}
+
+	info, err := h.introspector.IntrospectToken(r.Context(), req, token, r.PostFormValue("token_type_hint"))
+	switch err {
+	case nil: // Do nothing
+	case ErrInvalidClient:
+		w.Header().Set("WWW-Authenticate", `Basic realm="introspect"`)
+		writeJSON(log, w, http.StatusUnauthorized, errorResponse{
+			Error:            errInvalidClient,
+			ErrorDescription: err.Error(),
+		})
+
+		return
+	case ErrInvalidToken:
+		w.Header().Set("WWW-Authenticate", `Bearer error="`+errInvalidToken+`"`)
+		writeJSON(log, w, http.StatusUnauthorized, errorResponse{
+			Error:            errInvalidToken,
+			ErrorDescription: err.Error(),
+		})
+
+		return
+	case ErrNotEnoughPermissions:
+		writeJSON(log, w, http.StatusForbidden, errorResponse{
+			Error:            errInsufficientScope,
+			ErrorDescription: err.Error(),
+		})
+
+		return
+	default:
+		writeJSON(log, w, http.StatusInternalServerError, errorResponse{Error: errServerError})
+
+		return
+	}
+
+	if !info.Active {
+		writeJSON(log, w, http.StatusOK, response{Active: false})
+
+		return
+	}
+
+	resp := response{
+		Active:   true,
+		TokenUse: info.TokenType,
+		Scope:    strings.Join(info.Scopes, " "),
+		Sub:      strconv.FormatUint(info.UserId, 10),
+		Exp:      info.ExpiresAt.Unix(),
+		Aud:      info.Audience,
+		Iss:      info.Issuer,
+		Jti:      info.TokenId,
+	}
+	if info.TokenType == models.TokenTypeAccess {
+		resp.TokenType = tokenTypeBearer
+	}
+	if info.AppId != 0 {
+		resp.ClientId = strconv.FormatInt(int64(info.AppId), 10)
+	}
+	// The token of the client credentials grant has no user, its subject is the app
+	if info.UserId == 0 {
+		resp.Sub = resp.ClientId
+	}
+	if info.SessionId != 0 {
+		resp.Sid = strconv.FormatUint(info.SessionId, 10)
+	}
+	if !info.IssuedAt.IsZero() {
+		resp.Iat = info.IssuedAt.Unix()
+	}
+
+	writeJSON(log, w, http.StatusOK, resp)
+}
+
+// requester returns the credentials of the app sent with HTTP Basic or as the form parameters
+// or the access token sent with the Authorization: Bearer header.
+// The credentials of HTTP Basic are url-encoded (RFC 6749, section 2.3.1).
+func requester(r *http.Request) (models.Requester, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return models.Requester{Token: token}, token != ""
+	}
+
+	clientId, secret, basic := r.BasicAuth()
+	if basic {
+		var err1, err2 error
+
+		clientId, err1 = url.QueryUnescape(clientId)
+		secret, err2 = url.QueryUnescape(secret)
+
+		if err1 != nil || err2 != nil {
+			return models.Requester{}, false
+		}
+	} else {
+		clientId = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	appId, err := strconv.ParseInt(clientId, 10, 32)
+	if err != nil || appId <= 0 || secret == "" {
+		return models.Requester{}, false
+	}
+
+	return models.Requester{Client: models.ClientCredentials{AppId: int32(appId), Secret: secret}}, true
+}
+
+func writeJSON(log *slog.Logger, w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	// The token state must not be cached (RFC 7662, section 4)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(err.Error())
+	}
+}
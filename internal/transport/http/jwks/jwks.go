@@ -0,0 +1,48 @@
+package jwks
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/puregrade-group/sso/pkg/jwt"
+)
+
+// Path is the well-known location the resource servers fetch the public keys from.
+const Path = "/.well-known/jwks.json"
+
+// Public keys change rarely, so the resource servers may cache the document for a while
+const cacheControl = "public, max-age=300"
+
+type handler struct {
+	log  *slog.Logger
+	keys KeySet
+}
+
+// KeySet interface must be implemented by the signing keys of the service
+type KeySet interface {
+	JWKS() jwt.JWKS
+}
+
+func Register(mux *http.ServeMux, log *slog.Logger, keys KeySet) {
+	mux.Handle(Path, &handler{log: log, keys: keys})
+}
+
+// ServeHTTP writes the JSON Web Key Set document (RFC 7517).
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "jwks.ServeHTTP"
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if err := json.NewEncoder(w).Encode(h.keys.JWKS()); err != nil {
+		h.log.With(slog.String("op", op)).Error(err.Error())
+	}
+}
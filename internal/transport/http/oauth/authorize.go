@@ -0,0 +1,321 @@
+package oauth
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+const (
+	responseTypeCode = "code"
+	// codeChallengeMethodS256 is the only PKCE method supported, "plain" does not protect the code
+	codeChallengeMethodS256 = "S256"
+	// codeChallengeLen is the length of base64url encoded SHA-256 hash without padding
+	codeChallengeLen = 43
+	// consentApprove is the value of the consent the user approves the authorization with, any other one denies it
+	consentApprove = "approve"
+)
+
+// scopeDescriptions are shown to the user on the consent page, the scopes without description are shown as is.
+var scopeDescriptions = map[string]string{
+	models.ScopeOpenID:  "Verify your identity",
+	models.ScopeProfile: "See your name and birthdate",
+	models.ScopeEmail:   "See your email address",
+}
+
+// authorizeParams are the parameters of the authorization request (RFC 6749, section 4.1.1)
+// that the login form posts back to the authorization endpoint.
+var authorizeParams = []string{
+	"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method",
+}
+
+type authorizeHandler struct {
+	log     *slog.Logger
+	service OAuth
+}
+
+// loginPage is the data of the login form template.
+type loginPage struct {
+	Action    string
+	Params    map[string]string
+	CSRFToken string
+	Email     string
+	Error     string
+}
+
+// consentPage is the data of the consent form template.
+type consentPage struct {
+	Action      string
+	Params      map[string]string
+	CSRFToken   string
+	ConsentCode string
+	AppName     string
+	Scopes      []string // descriptions of the scopes
+}
+
+// ServeHTTP shows the login form on GET and authorizes the app on POST of the form.
+// If the user has to consent to the scopes of the third-party app, the consent form is shown
+// and the app is authorized on POST of that form.
+//
+// The user agent is redirected back to the app only when the app is known and the redirect URI is registered for it,
+// otherwise the error page is shown (RFC 6749, section 4.1.2.1).
+// The forms are posted with the anti-CSRF token, so another site can not sign the user in to the account
+// of the attacker or consent on behalf of the user.
+func (h *authorizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "oauth.authorize"
+
+	log := h.log.With(slog.String("op", op))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	// The login form must not be framed by other sites and the pages must not be cached
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(log, w, "request is malformed")
+
+		return
+	}
+
+	appId, err := parseClientId(r.Form.Get("client_id"))
+	if err != nil {
+		h.renderError(log, w, "client_id is not valid")
+
+		return
+	}
+
+	redirectURI := r.Form.Get("redirect_uri")
+	if redirectURI == "" {
+		h.renderError(log, w, "redirect_uri is required")
+
+		return
+	}
+
+	req := models.AuthorizationRequest{
+		AppId:         appId,
+		RedirectURI:   redirectURI,
+		CodeChallenge: r.Form.Get("code_challenge"),
+		Scopes:        strings.Fields(r.Form.Get("scope")),
+		Nonce:         r.Form.Get("nonce"),
+	}
+	state := r.Form.Get("state")
+
+	if err = h.service.CheckAuthorizationRequest(r.Context(), req); err != nil {
+		h.handleError(log, w, r, req, state, err)
+
+		return
+	}
+
+	if r.Form.Get("response_type") != responseTypeCode {
+		redirectError(w, r, redirectURI, state, errUnsupportedResponseType, "response_type must be code")
+
+		return
+	}
+
+	if r.Form.Get("code_challenge_method") != codeChallengeMethodS256 {
+		redirectError(w, r, redirectURI, state, errInvalidRequest, "code_challenge_method must be S256")
+
+		return
+	}
+
+	if !isPKCEString(req.CodeChallenge, codeChallengeLen, codeChallengeLen) {
+		redirectError(w, r, redirectURI, state, errInvalidRequest, "code_challenge is required")
+
+		return
+	}
+
+	params := make(map[string]string, len(authorizeParams))
+	for _, name := range authorizeParams {
+		if value := r.Form.Get(name); value != "" {
+			params[name] = value
+		}
+	}
+
+	if r.Method == http.MethodGet {
+		token, err := csrfToken(w, r)
+		if err != nil {
+			log.Error(err.Error())
+			redirectError(w, r, redirectURI, state, errServerError, "")
+
+			return
+		}
+
+		h.renderLogin(log, w, loginPage{Params: params, CSRFToken: token})
+
+		return
+	}
+
+	if !checkCSRF(r) {
+		log.Warn("form is posted without the anti-CSRF token")
+		renderPage(log, w, http.StatusForbidden, "error.html", csrfErrorDescription)
+
+		return
+	}
+
+	token := r.PostForm.Get(csrfField)
+
+	if consentCode := r.PostForm.Get("consent_code"); consentCode != "" {
+		approved := r.PostForm.Get("consent") == consentApprove
+
+		code, err := h.service.Consent(r.Context(), req, consentCode, approved)
+		if err != nil {
+			h.handleError(log, w, r, req, state, err)
+
+			return
+		}
+
+		redirect(w, r, redirectURI, url.Values{"code": {code}}, state)
+
+		return
+	}
+
+	creds := models.Credentials{
+		Email:    r.PostForm.Get("email"),
+		Password: r.PostForm.Get("password"),
+	}
+
+	if creds.Email == "" || creds.Password == "" {
+		h.renderLogin(
+			log, w,
+			loginPage{Params: params, CSRFToken: token, Email: creds.Email, Error: "email and password are required"},
+		)
+
+		return
+	}
+
+	device := models.Device{
+		UserAgent: r.UserAgent(),
+		IP:        remoteIP(r),
+	}
+
+	authorization, err := h.service.Authorize(r.Context(), creds, device, req)
+	if err == ErrWrongCredentials {
+		h.renderLogin(log, w, loginPage{Params: params, CSRFToken: token, Email: creds.Email, Error: err.Error()})
+
+		return
+	}
+	if err != nil {
+		h.handleError(log, w, r, req, state, err)
+
+		return
+	}
+
+	if authorization.ConsentRequired {
+		h.renderConsent(log, w, params, token, authorization)
+
+		return
+	}
+
+	redirect(w, r, redirectURI, url.Values{"code": {authorization.Code}}, state)
+}
+
+// handleError shows the error page if the app can not be trusted with the redirect, otherwise redirects the error to it.
+func (h *authorizeHandler) handleError(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	req models.AuthorizationRequest,
+	state string,
+	err error,
+) {
+	switch err {
+	case ErrInvalidClient, ErrInvalidRedirectURI:
+		h.renderError(log, w, err.Error())
+	case ErrUnauthorizedClient:
+		redirectError(w, r, req.RedirectURI, state, errUnauthorizedClient, err.Error())
+	case ErrAccessDenied:
+		redirectError(w, r, req.RedirectURI, state, errAccessDenied, err.Error())
+	case ErrInvalidConsent:
+		redirectError(w, r, req.RedirectURI, state, errInvalidRequest, err.Error())
+	default:
+		redirectError(w, r, req.RedirectURI, state, errServerError, "")
+	}
+}
+
+func (h *authorizeHandler) renderLogin(log *slog.Logger, w http.ResponseWriter, page loginPage) {
+	page.Action = AuthorizePath
+
+	renderPage(log, w, http.StatusOK, "login.html", page)
+}
+
+func (h *authorizeHandler) renderConsent(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	params map[string]string,
+	csrfToken string,
+	authorization models.Authorization,
+) {
+	page := consentPage{
+		Action:      AuthorizePath,
+		Params:      params,
+		CSRFToken:   csrfToken,
+		ConsentCode: authorization.Code,
+		AppName:     authorization.AppName,
+		Scopes:      describeScopes(authorization.Scopes),
+	}
+
+	renderPage(log, w, http.StatusOK, "consent.html", page)
+}
+
+func (h *authorizeHandler) renderError(log *slog.Logger, w http.ResponseWriter, description string) {
+	renderPage(log, w, http.StatusBadRequest, "error.html", description)
+}
+
+// describeScopes returns the descriptions of the scopes to show to the user.
+func describeScopes(scopes []string) []string {
+	res := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		if description, ok := scopeDescriptions[scope]; ok {
+			scope = description
+		}
+
+		res = append(res, scope)
+	}
+
+	return res
+}
+
+// redirectError redirects the error of the authorization request to the app (RFC 6749, section 4.1.2.1).
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state, code, description string) {
+	params := url.Values{"error": {code}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+
+	redirect(w, r, redirectURI, params, state)
+}
+
+// redirect redirects the user agent to the redirect URI with the parameters added to its query.
+// The state of the app is passed through unchanged.
+func redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values, state string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		// The redirect URI is registered for the app, so it has been parsed already
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	q := u.Query()
+	for name, values := range params {
+		q[name] = values
+	}
+
+	if state != "" {
+		q.Set("state", state)
+	}
+
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
@@ -0,0 +1,59 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// The hosted forms are protected by the double submit cookie: the anti-CSRF token is kept in the cookie
+// and every form posts it back in the field, the other sites can neither read the cookie nor send it.
+const (
+	csrfCookie   = "sso_csrf"
+	csrfField    = "csrf_token"
+	csrfTokenLen = 32
+
+	// csrfErrorDescription is shown instead of the form posted without the token
+	csrfErrorDescription = "the form has expired or is posted from another site, reload the page"
+)
+
+// csrfToken returns the anti-CSRF token of the user agent, the new one is generated and set in the cookie
+// if the user agent has none.
+func csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	b := make([]byte, csrfTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(
+		w, &http.Cookie{
+			Name:     csrfCookie,
+			Value:    token,
+			Path:     "/",
+			Secure:   r.TLS != nil,
+			HttpOnly: true,
+			// The cookie is not sent with the forms posted from the other sites
+			SameSite: http.SameSiteStrictMode,
+		},
+	)
+
+	return token, nil
+}
+
+// checkCSRF reports whether the form is posted with the anti-CSRF token of the cookie,
+// i.e. from the page of the service and not from another site.
+func checkCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostForm.Get(csrfField))) == 1
+}
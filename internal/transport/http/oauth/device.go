@@ -0,0 +1,229 @@
+package oauth
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+type deviceAuthorizationHandler struct {
+	log             *slog.Logger
+	service         OAuth
+	verificationURI string
+}
+
+// deviceAuthorizationResponse is the successful response of RFC 8628, section 3.2.
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// ServeHTTP issues the device code and the user code for the app authenticated like at the token endpoint.
+// The device shows the user code and the verification URI, then polls the token endpoint with the device code.
+func (h *deviceAuthorizationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "oauth.deviceAuthorization"
+
+	log := h.log.With(slog.String("op", op))
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(log, w, false, errInvalidRequest, "request is malformed")
+
+		return
+	}
+
+	client, basic, ok := clientCredentials(r)
+	if !ok {
+		writeError(log, w, basic, errInvalidClient, "client_id is not valid")
+
+		return
+	}
+
+	device := models.Device{
+		UserAgent: r.UserAgent(),
+		IP:        remoteIP(r),
+	}
+
+	code, err := h.service.AuthorizeDevice(r.Context(), client, strings.Fields(r.PostForm.Get("scope")), device)
+	switch err {
+	case nil: // Do nothing
+	case ErrInvalidClient:
+		writeError(log, w, basic, errInvalidClient, err.Error())
+
+		return
+	case ErrUnauthorizedClient:
+		writeError(log, w, basic, errUnauthorizedClient, err.Error())
+
+		return
+	default:
+		writeError(log, w, basic, errServerError, "")
+
+		return
+	}
+
+	userCode := formatUserCode(code.UserCode)
+
+	writeJSON(log, w, http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              code.DeviceCode,
+		UserCode:                userCode,
+		VerificationURI:         h.verificationURI,
+		VerificationURIComplete: h.verificationURI + "?" + url.Values{"user_code": {userCode}}.Encode(),
+		ExpiresIn:               int64(code.ExpiresAt.Sub(code.CreatedAt).Seconds()),
+		Interval:                int64(code.Interval.Seconds()),
+	})
+}
+
+type deviceHandler struct {
+	log     *slog.Logger
+	service OAuth
+}
+
+// devicePage is the data of the device approval form template.
+// The app and the scopes are set once the user signs in, the review code binds the approval to the sign-in.
+type devicePage struct {
+	Action     string
+	CSRFToken  string
+	UserCode   string
+	ReviewCode string
+	AppName    string
+	Scopes     []string // descriptions of the scopes
+	Email      string
+	Error      string
+}
+
+// ServeHTTP shows the form the user enters the user code and signs in with on GET (RFC 8628, section 3.3).
+// Once the user signs in, the app and the scopes the device asks for are shown to approve them,
+// so the user does not approve the device of somebody else unknowingly (RFC 8628, section 5.4).
+// Nothing of the device is shown before the sign-in and the failed sign-ins are limited,
+// so the page tells nothing of the user codes being guessed (RFC 8628, section 5.1).
+// The user code of the verification URI the device showed is filled in.
+func (h *deviceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "oauth.device"
+
+	log := h.log.With(slog.String("op", op))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	// The form must not be framed by other sites and the pages must not be cached
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		renderPage(log, w, http.StatusBadRequest, "error.html", "request is malformed")
+
+		return
+	}
+
+	page := devicePage{
+		Action:   DevicePath,
+		UserCode: r.Form.Get("user_code"),
+	}
+
+	if r.Method == http.MethodGet {
+		token, err := csrfToken(w, r)
+		if err != nil {
+			log.Error(err.Error())
+			renderPage(log, w, http.StatusInternalServerError, "error.html", "something went wrong, try again later")
+
+			return
+		}
+
+		page.CSRFToken = token
+		renderPage(log, w, http.StatusOK, "device.html", page)
+
+		return
+	}
+
+	if !checkCSRF(r) {
+		log.Warn("form is posted without the anti-CSRF token")
+		renderPage(log, w, http.StatusForbidden, "error.html", csrfErrorDescription)
+
+		return
+	}
+
+	page.CSRFToken = r.PostForm.Get(csrfField)
+	userCode := normalizeUserCode(page.UserCode)
+
+	if reviewCode := r.PostForm.Get("review_code"); reviewCode != "" {
+		err := h.service.ApproveDevice(r.Context(), userCode, reviewCode)
+		switch err {
+		case nil:
+			renderPage(log, w, http.StatusOK, "device_approved.html", nil)
+		case ErrInvalidUserCode:
+			page.Error = err.Error()
+			renderPage(log, w, http.StatusOK, "device.html", page)
+		default:
+			renderPage(log, w, http.StatusInternalServerError, "error.html", "something went wrong, try again later")
+		}
+
+		return
+	}
+
+	creds := models.Credentials{
+		Email:    r.PostForm.Get("email"),
+		Password: r.PostForm.Get("password"),
+	}
+	page.Email = creds.Email
+
+	if userCode == "" || creds.Email == "" || creds.Password == "" {
+		page.Error = "code, email and password are required"
+		renderPage(log, w, http.StatusOK, "device.html", page)
+
+		return
+	}
+
+	authorization, err := h.service.ReviewDevice(r.Context(), creds, userCode, remoteIP(r))
+	switch err {
+	case nil:
+		page.ReviewCode = authorization.Code
+		page.AppName = authorization.AppName
+		page.Scopes = describeScopes(authorization.Scopes)
+		renderPage(log, w, http.StatusOK, "device.html", page)
+	case ErrWrongCredentials, ErrInvalidUserCode:
+		page.Error = err.Error()
+		renderPage(log, w, http.StatusOK, "device.html", page)
+	case ErrTooManyAttempts:
+		page.Error = err.Error()
+		renderPage(log, w, http.StatusTooManyRequests, "device.html", page)
+	default:
+		renderPage(log, w, http.StatusInternalServerError, "error.html", "something went wrong, try again later")
+	}
+}
+
+// formatUserCode splits the user code in two halves with the dash, so it is easier to read and type.
+func formatUserCode(code string) string {
+	return code[:len(code)/2] + "-" + code[len(code)/2:]
+}
+
+// normalizeUserCode makes the user code typed by the user match the issued one:
+// the letters are upper cased and the dashes and the spaces are dropped (RFC 8628, section 6.1).
+func normalizeUserCode(code string) string {
+	return strings.Map(
+		func(r rune) rune {
+			if r == '-' || r == ' ' {
+				return -1
+			}
+
+			return r
+		},
+		strings.ToUpper(code),
+	)
+}
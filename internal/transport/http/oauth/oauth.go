@@ -0,0 +1,215 @@
+package oauth
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// Paths of the authorization and the token endpoints (RFC 6749, section 3),
+// of the device authorization endpoint and of the page the user approves the device on (RFC 8628, section 3).
+const (
+	AuthorizePath           = "/authorize"
+	TokenPath               = "/token"
+	DeviceAuthorizationPath = "/device_authorization"
+	DevicePath              = "/device"
+)
+
+// Error codes of RFC 6749, sections 4.1.2.1 and 5.2
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errUnauthorizedClient      = "unauthorized_client"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errAccessDenied            = "access_denied"
+	errServerError             = "server_error"
+)
+
+// Error codes of the device access token response (RFC 8628, section 3.5)
+const (
+	errAuthorizationPending = "authorization_pending"
+	errSlowDown             = "slow_down"
+	errExpiredToken         = "expired_token"
+)
+
+// Error codes of the token exchange (RFC 8693, section 2.2.2) and of the scope (RFC 6749, section 5.2)
+const (
+	errInvalidTarget = "invalid_target"
+	errInvalidScope  = "invalid_scope"
+)
+
+// Errors the service returns
+var (
+	ErrInvalidClient        = errors.New("unknown client or client authentication failed")
+	ErrInvalidRedirectURI   = errors.New("redirect uri is not registered for the client")
+	ErrUnauthorizedClient   = errors.New("client is not allowed to use this grant type")
+	ErrWrongCredentials     = errors.New("invalid email or password")
+	ErrAccessDenied         = errors.New("user denied the authorization")
+	ErrInvalidConsent       = errors.New("consent request is invalid or expired")
+	ErrInvalidGrant         = errors.New("authorization grant is invalid, expired or issued to another client")
+	ErrInvalidUserCode      = errors.New("code is invalid or expired")
+	ErrTooManyAttempts      = errors.New("too many failed attempts, try again later")
+	ErrAuthorizationPending = errors.New("user has not approved the device yet")
+	ErrSlowDown             = errors.New("device polls too often, the interval is increased")
+	ErrExpiredToken         = errors.New("device code has expired")
+	ErrInvalidSubjectToken  = errors.New("subject or actor token is invalid, expired, revoked or issued to another client")
+	ErrExchangeNotAllowed   = errors.New("client is not allowed to act on behalf of the user this way")
+	ErrInvalidTarget        = errors.New("audience is unknown or the client is not allowed to target it")
+	ErrInvalidScope         = errors.New("scope exceeds the scope of the subject token")
+	ErrInternal             = errors.New("internal error")
+	ErrUnknown              = errors.New("unknown error")
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// OAuth interface must be implemented by the service layer
+type OAuth interface {
+	CheckAuthorizationRequest(ctx context.Context,
+		req models.AuthorizationRequest,
+	) (err error)
+	Authorize(ctx context.Context,
+		creds models.Credentials,
+		device models.Device,
+		req models.AuthorizationRequest,
+	) (authorization models.Authorization, err error)
+	Consent(ctx context.Context,
+		req models.AuthorizationRequest,
+		code string,
+		approved bool,
+	) (redirectCode string, err error)
+	ExchangeAuthorizationCode(ctx context.Context,
+		client models.ClientCredentials,
+		code string,
+		redirectURI string,
+		codeVerifier string,
+	) (tokens models.Tokens, err error)
+	RefreshAppTokens(ctx context.Context,
+		client models.ClientCredentials,
+		refreshToken string,
+		ip net.IP,
+	) (tokens models.Tokens, err error)
+	IssueClientToken(ctx context.Context,
+		client models.ClientCredentials,
+	) (tokens models.Tokens, err error)
+	AuthorizeDevice(ctx context.Context,
+		client models.ClientCredentials,
+		scopes []string,
+		device models.Device,
+	) (code models.DeviceCode, err error)
+	ReviewDevice(ctx context.Context,
+		creds models.Credentials,
+		userCode string,
+		ip net.IP,
+	) (authorization models.Authorization, err error)
+	ApproveDevice(ctx context.Context,
+		userCode string,
+		reviewCode string,
+	) (err error)
+	ExchangeDeviceCode(ctx context.Context,
+		client models.ClientCredentials,
+		deviceCode string,
+	) (tokens models.Tokens, err error)
+	ExchangeToken(ctx context.Context,
+		client models.ClientCredentials,
+		req models.TokenExchange,
+		ip net.IP,
+	) (tokens models.Tokens, err error)
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Register registers the endpoints, the issuer is the public URL of the server the verification URI is built from.
+func Register(mux *http.ServeMux, log *slog.Logger, service OAuth, issuer string) {
+	verificationURI := strings.TrimSuffix(issuer, "/") + DevicePath
+
+	mux.Handle(AuthorizePath, &authorizeHandler{log: log, service: service})
+	mux.Handle(TokenPath, &tokenHandler{log: log, service: service})
+	mux.Handle(DeviceAuthorizationPath, &deviceAuthorizationHandler{
+		log:             log,
+		service:         service,
+		verificationURI: verificationURI,
+	})
+	mux.Handle(DevicePath, &deviceHandler{log: log, service: service})
+}
+
+// parseClientId parses client_id, it is the id of the app.
+func parseClientId(clientId string) (int32, error) {
+	id, err := strconv.ParseInt(clientId, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	if id <= 0 {
+		return 0, strconv.ErrRange
+	}
+
+	return int32(id), nil
+}
+
+// isPKCEString reports whether s consists of the unreserved characters of RFC 7636, section 4.1
+// and its length is within the bounds.
+func isPKCEString(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// remoteIP returns the IP address of the client or nil if it is unknown.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
+
+// renderPage writes the page of the template with the status.
+func renderPage(log *slog.Logger, w http.ResponseWriter, status int, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+func writeJSON(log *slog.Logger, w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	// The tokens must not be cached (RFC 6749, section 5.1)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(err.Error())
+	}
+}
@@ -0,0 +1,288 @@
+package oauth
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// Grant types of the token endpoint
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	grantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// tokenTypeAccessToken is the only token type the token exchange accepts and issues (RFC 8693, section 3)
+const tokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+// tokenTypeBearer is the OAuth 2.0 type of the access tokens (RFC 6749, section 7.1)
+const tokenTypeBearer = "Bearer"
+
+// Length bounds of the PKCE code verifier (RFC 7636, section 4.1)
+const (
+	codeVerifierMinLen = 43
+	codeVerifierMaxLen = 128
+)
+
+type tokenHandler struct {
+	log     *slog.Logger
+	service OAuth
+}
+
+// tokenResponse is the successful response of RFC 6749, section 5.1,
+// the ID token is added by OpenID Connect Core 1.0, section 3.1.3.3
+// and the issued token type of the token exchange by RFC 8693, section 2.2.1.
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	IDToken         string `json:"id_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+}
+
+// ServeHTTP issues the tokens for the authorization code, the refresh token or the device code
+// posted as the form parameters, the token of the app itself for the client credentials
+// or the token of the user for the exchanged one.
+//
+// The app authenticates with HTTP Basic or with client_id and client_secret parameters (RFC 6749, section 2.3.1).
+// The public apps send client_id only.
+func (h *tokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "oauth.token"
+
+	log := h.log.With(slog.String("op", op))
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(log, w, false, errInvalidRequest, "request is malformed")
+
+		return
+	}
+
+	client, basic, ok := clientCredentials(r)
+	if !ok {
+		writeError(log, w, basic, errInvalidClient, "client_id is not valid")
+
+		return
+	}
+
+	var (
+		tokens          models.Tokens
+		issuedTokenType string
+		err             error
+	)
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case grantTypeAuthorizationCode:
+		code := r.PostForm.Get("code")
+		redirectURI := r.PostForm.Get("redirect_uri")
+		codeVerifier := r.PostForm.Get("code_verifier")
+
+		switch {
+		case code == "":
+			writeError(log, w, basic, errInvalidRequest, "code is required")
+
+			return
+		case redirectURI == "":
+			writeError(log, w, basic, errInvalidRequest, "redirect_uri is required")
+
+			return
+		case !isPKCEString(codeVerifier, codeVerifierMinLen, codeVerifierMaxLen):
+			writeError(log, w, basic, errInvalidRequest, "code_verifier is not valid")
+
+			return
+		}
+
+		tokens, err = h.service.ExchangeAuthorizationCode(r.Context(), client, code, redirectURI, codeVerifier)
+	case grantTypeRefreshToken:
+		refreshToken := r.PostForm.Get("refresh_token")
+		if refreshToken == "" {
+			writeError(log, w, basic, errInvalidRequest, "refresh_token is required")
+
+			return
+		}
+
+		tokens, err = h.service.RefreshAppTokens(r.Context(), client, refreshToken, remoteIP(r))
+	case grantTypeClientCredentials:
+		tokens, err = h.service.IssueClientToken(r.Context(), client)
+	case grantTypeDeviceCode:
+		deviceCode := r.PostForm.Get("device_code")
+		if deviceCode == "" {
+			writeError(log, w, basic, errInvalidRequest, "device_code is required")
+
+			return
+		}
+
+		tokens, err = h.service.ExchangeDeviceCode(r.Context(), client, deviceCode)
+	case grantTypeTokenExchange:
+		req, description := tokenExchangeRequest(r.PostForm)
+		if description != "" {
+			writeError(log, w, basic, errInvalidRequest, description)
+
+			return
+		}
+
+		tokens, err = h.service.ExchangeToken(r.Context(), client, req, remoteIP(r))
+		issuedTokenType = tokenTypeAccessToken
+	case "":
+		writeError(log, w, basic, errInvalidRequest, "grant_type is required")
+
+		return
+	default:
+		writeError(log, w, basic, errUnsupportedGrantType, "")
+
+		return
+	}
+
+	switch err {
+	case nil: // Do nothing
+	case ErrInvalidClient:
+		writeError(log, w, basic, errInvalidClient, err.Error())
+
+		return
+	case ErrInvalidGrant:
+		writeError(log, w, basic, errInvalidGrant, err.Error())
+
+		return
+	case ErrUnauthorizedClient:
+		writeError(log, w, basic, errUnauthorizedClient, err.Error())
+
+		return
+	case ErrAuthorizationPending:
+		writeError(log, w, basic, errAuthorizationPending, err.Error())
+
+		return
+	case ErrSlowDown:
+		writeError(log, w, basic, errSlowDown, err.Error())
+
+		return
+	case ErrExpiredToken:
+		writeError(log, w, basic, errExpiredToken, err.Error())
+
+		return
+	case ErrAccessDenied:
+		writeError(log, w, basic, errAccessDenied, err.Error())
+
+		return
+	case ErrInvalidSubjectToken:
+		writeError(log, w, basic, errInvalidRequest, err.Error())
+
+		return
+	case ErrExchangeNotAllowed:
+		writeError(log, w, basic, errUnauthorizedClient, err.Error())
+
+		return
+	case ErrInvalidTarget:
+		writeError(log, w, basic, errInvalidTarget, err.Error())
+
+		return
+	case ErrInvalidScope:
+		writeError(log, w, basic, errInvalidScope, err.Error())
+
+		return
+	default:
+		writeError(log, w, basic, errServerError, "")
+
+		return
+	}
+
+	writeJSON(log, w, http.StatusOK, tokenResponse{
+		AccessToken:     tokens.AccessToken,
+		TokenType:       tokenTypeBearer,
+		ExpiresIn:       int64(tokens.ExpiresIn.Seconds()),
+		RefreshToken:    tokens.RefreshToken,
+		IDToken:         tokens.IDToken,
+		Scope:           strings.Join(tokens.Scopes, " "),
+		IssuedTokenType: issuedTokenType,
+	})
+}
+
+// tokenExchangeRequest reads the parameters of the token exchange (RFC 8693, section 2.1)
+// and returns the description of the error if they are not valid.
+// Only the access tokens are exchanged and the target services are set by the audience,
+// the resource parameter is not supported.
+func tokenExchangeRequest(form url.Values) (models.TokenExchange, string) {
+	req := models.TokenExchange{
+		SubjectToken: form.Get("subject_token"),
+		ActorToken:   form.Get("actor_token"),
+		Audience:     form["audience"],
+		Scopes:       strings.Fields(form.Get("scope")),
+	}
+
+	switch {
+	case req.SubjectToken == "":
+		return req, "subject_token is required"
+	case form.Get("subject_token_type") != tokenTypeAccessToken:
+		return req, "subject_token_type is not supported"
+	case req.ActorToken == "" && form.Get("actor_token_type") != "":
+		return req, "actor_token is required with actor_token_type"
+	case req.ActorToken != "" && form.Get("actor_token_type") != tokenTypeAccessToken:
+		return req, "actor_token_type is not supported"
+	case form.Get("requested_token_type") != "" && form.Get("requested_token_type") != tokenTypeAccessToken:
+		return req, "requested_token_type is not supported"
+	case len(form["resource"]) != 0:
+		return req, "resource is not supported, use audience"
+	case len(req.Audience) == 0:
+		return req, "audience is required"
+	}
+
+	return req, ""
+}
+
+// clientCredentials returns the credentials of the app and whether they were sent with HTTP Basic.
+// The credentials of HTTP Basic are url-encoded (RFC 6749, section 2.3.1).
+func clientCredentials(r *http.Request) (client models.ClientCredentials, basic bool, ok bool) {
+	clientId, secret, basic := r.BasicAuth()
+	if basic {
+		var err1, err2 error
+
+		clientId, err1 = url.QueryUnescape(clientId)
+		secret, err2 = url.QueryUnescape(secret)
+
+		if err1 != nil || err2 != nil {
+			return models.ClientCredentials{}, true, false
+		}
+	} else {
+		clientId = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	appId, err := parseClientId(clientId)
+	if err != nil {
+		return models.ClientCredentials{}, basic, false
+	}
+
+	return models.ClientCredentials{AppId: appId, Secret: secret}, basic, true
+}
+
+// writeError writes the error response of RFC 6749, section 5.2.
+// The failed authentication of the client is 401 with the challenge of the scheme the client used.
+func writeError(log *slog.Logger, w http.ResponseWriter, basic bool, code, description string) {
+	status := http.StatusBadRequest
+
+	switch code {
+	case errInvalidClient:
+		status = http.StatusUnauthorized
+
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		}
+	case errServerError:
+		status = http.StatusInternalServerError
+	}
+
+	writeJSON(log, w, status, errorResponse{Error: code, ErrorDescription: description})
+}
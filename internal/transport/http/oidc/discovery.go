@@ -0,0 +1,88 @@
+package oidc
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/internal/transport/http/introspect"
+	"github.com/puregrade-group/sso/internal/transport/http/jwks"
+	"github.com/puregrade-group/sso/internal/transport/http/oauth"
+)
+
+// DiscoveryPath is the well-known location of the provider metadata (OpenID Connect Discovery 1.0, section 4).
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// The metadata changes only with the configuration, so the relying parties may cache it for a while
+const cacheControl = "public, max-age=300"
+
+// grantTypes are the grant types of the token endpoint
+var grantTypes = []string{
+	models.GrantAuthorizationCode, models.GrantRefreshToken, models.GrantClientCredentials, models.GrantDeviceCode,
+	models.GrantTokenExchange,
+}
+
+// discovery is the provider metadata of OpenID Connect Discovery 1.0, section 3.
+type discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type discoveryHandler struct {
+	log      *slog.Logger
+	document discovery
+}
+
+func newDiscovery(provider Provider) discovery {
+	base := strings.TrimSuffix(provider.Issuer, "/")
+
+	return discovery{
+		Issuer:                            provider.Issuer,
+		AuthorizationEndpoint:             base + oauth.AuthorizePath,
+		TokenEndpoint:                     base + oauth.TokenPath,
+		UserInfoEndpoint:                  base + UserInfoPath,
+		JWKSURI:                           base + jwks.Path,
+		IntrospectionEndpoint:             base + introspect.Path,
+		DeviceAuthorizationEndpoint:       base + oauth.DeviceAuthorizationPath,
+		ScopesSupported:                   models.SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               grantTypes,
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{provider.SigningAlgorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce", "email", "given_name", "family_name", "birthdate",
+		},
+	}
+}
+
+// ServeHTTP writes the provider metadata.
+func (h *discoveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "oidc.discovery"
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+
+	writeJSON(h.log.With(slog.String("op", op)), w, http.StatusOK, h.document)
+}
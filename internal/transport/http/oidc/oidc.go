@@ -0,0 +1,56 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/puregrade-group/sso/pkg/jwt"
+)
+
+// Errors the service returns
+var (
+	ErrInvalidToken      = errors.New("access token is invalid, expired or revoked")
+	ErrInsufficientScope = errors.New("access token is not granted the openid scope")
+	ErrInternal          = errors.New("internal error")
+	ErrUnknown           = errors.New("unknown error")
+)
+
+// UserInfoProvider interface must be implemented by the service layer
+type UserInfoProvider interface {
+	UserInfo(ctx context.Context,
+		accessToken string,
+	) (info models.UserInfo, err error)
+}
+
+// Provider is the OpenID provider the discovery document describes.
+// The issuer must be the URL of the HTTP server, the endpoints are resolved against it.
+type Provider struct {
+	Issuer           string
+	SigningAlgorithm string
+}
+
+// Register serves the discovery and the userinfo endpoints if the ID tokens are signed with the asymmetric algorithm,
+// the relying parties can not verify the other ones without being able to forge them.
+func Register(mux *http.ServeMux, log *slog.Logger, service UserInfoProvider, provider Provider) {
+	if !jwt.Asymmetric(provider.SigningAlgorithm) {
+		log.Warn("OpenID Connect is disabled, the signing algorithm is not asymmetric")
+
+		return
+	}
+
+	mux.Handle(DiscoveryPath, &discoveryHandler{log: log, document: newDiscovery(provider)})
+	mux.Handle(UserInfoPath, &userInfoHandler{log: log, service: service})
+}
+
+func writeJSON(log *slog.Logger, w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(err.Error())
+	}
+}
@@ -0,0 +1,100 @@
+package oidc
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/puregrade-group/sso/internal/domain/models"
+)
+
+// UserInfoPath is the path of the userinfo endpoint (OpenID Connect Core 1.0, section 5.3).
+const UserInfoPath = "/userinfo"
+
+// Error codes of the bearer token usage (RFC 6750, section 3.1)
+const (
+	errInvalidToken      = "invalid_token"
+	errInsufficientScope = "insufficient_scope"
+)
+
+type userInfoHandler struct {
+	log     *slog.Logger
+	service UserInfoProvider
+}
+
+// userInfoResponse has the claims the scopes of the access token grant, the others are omitted.
+type userInfoResponse struct {
+	Sub        string `json:"sub"`
+	Email      string `json:"email,omitempty"`
+	GivenName  string `json:"given_name,omitempty"`
+	FamilyName string `json:"family_name,omitempty"`
+	Birthdate  string `json:"birthdate,omitempty"`
+}
+
+// ServeHTTP returns the claims about the user authenticated by the bearer access token.
+// The token is sent in the Authorization header (RFC 6750, section 2.1).
+func (h *userInfoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "oidc.userinfo"
+
+	log := h.log.With(slog.String("op", op))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	// The claims of the user must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+		return
+	}
+
+	info, err := h.service.UserInfo(r.Context(), token)
+	switch err {
+	case nil: // Do nothing
+	case ErrInvalidToken:
+		w.Header().Set("WWW-Authenticate", `Bearer error="`+errInvalidToken+`"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+
+		return
+	case ErrInsufficientScope:
+		w.Header().Set("WWW-Authenticate", `Bearer error="`+errInsufficientScope+`", scope="`+models.ScopeOpenID+`"`)
+		http.Error(w, err.Error(), http.StatusForbidden)
+
+		return
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	resp := userInfoResponse{
+		Sub:        strconv.FormatUint(info.UserId, 10),
+		Email:      info.Email,
+		GivenName:  info.FirstName,
+		FamilyName: info.LastName,
+	}
+	if !info.DateOfBirth.IsZero() {
+		resp.Birthdate = info.DateOfBirth.Format(models.BirthdateLayout)
+	}
+
+	writeJSON(log, w, http.StatusOK, resp)
+}
+
+// bearerToken returns the token of the Authorization header with the Bearer scheme.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
@@ -0,0 +1,10 @@
+// Package migrations embeds the migrations of every SQL storage into the binary,
+// so they can be applied on start without shipping the files.
+package migrations
+
+import "embed"
+
+// FS contains one directory of migrations per storage driver.
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1,5 @@
+drop table if exists user_roles;
+drop table if exists role_permissions;
+drop table if exists roles;
+drop table if exists permissions;
+drop table if exists apps;
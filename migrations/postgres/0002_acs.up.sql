@@ -0,0 +1,46 @@
+create table if not exists apps (
+    id serial primary key,
+    name varchar(64) not null unique,
+    secret text not null unique
+);
+
+create table if not exists permissions (
+    id serial primary key,
+    resource varchar(64) not null,
+    action varchar(64) not null,
+    description text not null,
+    constraint resource_action_uq unique (resource, action)
+);
+
+create table if not exists roles (
+    id serial primary key,
+    name varchar(64) not null unique,
+    description text not null default ''
+);
+
+create table if not exists role_permissions (
+    role_id int not null references roles (id) on delete cascade,
+    permission_id int not null references permissions (id) on delete cascade,
+    constraint role_permissions_pk primary key (role_id, permission_id)
+);
+
+create table if not exists user_roles (
+    user_id bytea not null,
+    role_id int not null references roles (id) on delete cascade,
+    constraint user_roles_pk primary key (user_id, role_id)
+);
+
+insert into permissions (resource, action, description) values
+    -- Permissions to act on permissions
+    ('permission', 'create', 'Permission to create new permissions'),
+    ('permission', 'delete', 'Permission to delete permissions'),
+    -- Permissions to act on role permissions
+    ('permission', 'grant', 'Permission to grant permissions to role'),
+    ('permission', 'revoke', 'Permission to revoke permissions from role'),
+    -- Permissions to act on roles
+    ('role', 'create', 'Permission to create new roles'),
+    ('role', 'delete', 'Permission to delete roles'),
+    -- Permissions to act on user roles
+    ('role', 'grant', 'Permission to grant roles to user'),
+    ('role', 'revoke', 'Permission to revoke roles from user')
+on conflict do nothing;
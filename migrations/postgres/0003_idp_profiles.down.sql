@@ -0,0 +1 @@
+drop table if exists idp_profiles;
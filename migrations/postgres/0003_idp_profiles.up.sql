@@ -0,0 +1,8 @@
+-- Profiles linked to the accounts of identity providers (Steam, Google, ...)
+create table if not exists idp_profiles (
+    id bytea primary key,
+    username varchar(32) not null,
+    avatar_hash text not null default '',
+    idp varchar(16) not null,
+    account_id text not null
+);
@@ -0,0 +1,19 @@
+-- Ids are stored back as 16-byte big-endian values with zero high bytes.
+alter table user_roles add column old_user_id bytea;
+
+update user_roles set old_user_id = decode(lpad(to_hex(user_id), 32, '0'), 'hex');
+
+alter table user_roles drop constraint user_roles_pk;
+alter table user_roles drop column user_id;
+alter table user_roles rename column old_user_id to user_id;
+alter table user_roles alter column user_id set not null;
+alter table user_roles add constraint user_roles_pk primary key (user_id, role_id);
+
+alter table idp_profiles add column old_id bytea;
+
+update idp_profiles set old_id = decode(lpad(to_hex(id), 32, '0'), 'hex');
+
+alter table idp_profiles drop constraint idp_profiles_pkey;
+alter table idp_profiles drop column id;
+alter table idp_profiles rename column old_id to id;
+alter table idp_profiles add primary key (id);
@@ -0,0 +1,34 @@
+-- User ids become the snowflake ids issued by Auth instead of 16-byte ids.
+
+-- Roles could only be granted by 16-byte ids, which never matched a user of Auth.
+-- Ids holding a big-endian snowflake id in the low 8 bytes (the high ones are zero)
+-- are converted, the other assignments are dropped.
+alter table user_roles add column new_user_id bigint;
+
+update user_roles
+set new_user_id = ('x' || encode(substring(user_id from 9 for 8), 'hex'))::bit(64)::bigint
+where length(user_id) = 16
+    and substring(user_id from 1 for 8) = decode('0000000000000000', 'hex')
+    and get_byte(user_id, 8) < 128;
+
+delete from user_roles where new_user_id is null;
+
+alter table user_roles drop constraint user_roles_pk;
+alter table user_roles drop column user_id;
+alter table user_roles rename column new_user_id to user_id;
+alter table user_roles alter column user_id set not null;
+alter table user_roles add constraint user_roles_pk primary key (user_id, role_id);
+
+-- Profiles are numbered in the order of their old ids,
+-- small numbers never collide with the snowflake ids.
+alter table idp_profiles add column new_id bigint;
+
+update idp_profiles p
+set new_id = n.num
+from (select id, row_number() over (order by id) as num from idp_profiles) n
+where p.id = n.id;
+
+alter table idp_profiles drop constraint idp_profiles_pkey;
+alter table idp_profiles drop column id;
+alter table idp_profiles rename column new_id to id;
+alter table idp_profiles add primary key (id);
@@ -0,0 +1,7 @@
+delete from refresh_tokens where revoked_at is not null;
+
+drop index if exists idx_refresh_tokens_active_user_id;
+alter table refresh_tokens add constraint refresh_tokens_user_id_key unique (user_id);
+
+alter table refresh_tokens drop column if exists revoked_by;
+alter table refresh_tokens drop column if exists revoked_at;
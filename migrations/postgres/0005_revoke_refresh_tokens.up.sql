@@ -0,0 +1,8 @@
+-- Revoked refresh tokens are kept for audit instead of being replaced.
+alter table refresh_tokens add column if not exists revoked_at timestamp;
+alter table refresh_tokens add column if not exists revoked_by varchar(45); -- IP address
+
+-- Only one unrevoked token per user
+alter table refresh_tokens drop constraint if exists refresh_tokens_user_id_key;
+create unique index if not exists idx_refresh_tokens_active_user_id on refresh_tokens (user_id)
+    where revoked_at is null;
@@ -0,0 +1,21 @@
+-- Only the last refreshed session of each user survives
+update refresh_tokens set revoked_at = now() at time zone 'utc'
+where revoked_at is null
+    and value not in (
+        select distinct on (user_id) value
+        from refresh_tokens
+        where revoked_at is null
+        order by user_id, created_at desc nulls last
+    );
+
+drop index if exists idx_refresh_tokens_user_id;
+drop index if exists idx_refresh_tokens_active_session_id;
+
+alter table refresh_tokens drop column if exists created_at;
+alter table refresh_tokens drop column if exists created_by;
+alter table refresh_tokens drop column if exists user_agent;
+alter table refresh_tokens drop column if exists device_name;
+alter table refresh_tokens drop column if exists session_id;
+
+create unique index if not exists idx_refresh_tokens_active_user_id on refresh_tokens (user_id)
+    where revoked_at is null;
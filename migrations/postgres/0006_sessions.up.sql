@@ -0,0 +1,19 @@
+-- Each login starts its own session, so the user can have many unrevoked tokens.
+drop index if exists idx_refresh_tokens_active_user_id;
+
+alter table refresh_tokens add column if not exists session_id bigint;
+alter table refresh_tokens add column if not exists device_name varchar(64) not null default '';
+alter table refresh_tokens add column if not exists user_agent text not null default '';
+alter table refresh_tokens add column if not exists created_by varchar(45); -- IP address
+alter table refresh_tokens add column if not exists created_at timestamp;
+
+-- There was one session per user, so the existing ones take the id of their user:
+-- the snowflake generator never issues the same id twice.
+update refresh_tokens set session_id = user_id where session_id is null;
+
+alter table refresh_tokens alter column session_id set not null;
+
+-- Only one unrevoked token per session
+create unique index if not exists idx_refresh_tokens_active_session_id on refresh_tokens (session_id)
+    where revoked_at is null;
+create index if not exists idx_refresh_tokens_user_id on refresh_tokens (user_id);
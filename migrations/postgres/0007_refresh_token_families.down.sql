@@ -0,0 +1,15 @@
+drop index if exists idx_security_events_user_id;
+drop table if exists security_events;
+
+-- The used tokens could not be refreshed anyway
+update refresh_tokens set revoked_at = used_at, revoked_by = used_by
+where used_at is not null and revoked_at is null;
+
+drop index if exists idx_refresh_tokens_active_session_id;
+
+alter table refresh_tokens drop column if exists used_by;
+alter table refresh_tokens drop column if exists used_at;
+
+-- Only one unrevoked token per session
+create unique index if not exists idx_refresh_tokens_active_session_id on refresh_tokens (session_id)
+    where revoked_at is null;
@@ -0,0 +1,10 @@
+-- Key ring of the access tokens. The private key is sealed with the master key if encrypted is set.
+create table if not exists signing_keys (
+    kid varchar(64) primary key,
+    algorithm varchar(16) not null,
+    private_key bytea not null,
+    encrypted boolean not null default false,
+    created_at timestamp not null,
+    activates_at timestamp not null, -- the newest activated key signs the tokens
+    expires_at timestamp -- the retired key verifies the tokens until then
+);
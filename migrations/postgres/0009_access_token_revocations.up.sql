@@ -0,0 +1,11 @@
+-- Denylist of the access tokens revoked before their expiration.
+-- The single token is revoked if jti is set, otherwise all the tokens of the session.
+create table if not exists access_token_revocations (
+    id bigserial primary key,
+    jti varchar(64),
+    session_id bigint not null,
+    user_id bigint not null,
+    revoked_at timestamp not null,
+    expires_at timestamp not null -- the revoked tokens expire by then
+);
+create index if not exists idx_access_token_revocations_expires_at on access_token_revocations (expires_at);
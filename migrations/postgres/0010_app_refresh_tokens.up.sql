@@ -0,0 +1,2 @@
+-- The refresh token can be refreshed only by the app it was issued for, null if it was issued without an app.
+alter table refresh_tokens add column if not exists app_id int;
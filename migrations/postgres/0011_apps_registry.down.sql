@@ -0,0 +1,15 @@
+delete from roles where name = 'admin';
+
+delete from permissions where resource = 'app';
+
+drop table if exists app_permissions;
+drop table if exists app_grant_types;
+drop table if exists app_redirect_uris;
+
+-- The secrets are lost, the apps get new random ones.
+alter table apps drop column if exists created_at;
+alter table apps drop column if exists secret_hash;
+alter table apps add column if not exists secret text;
+update apps set secret = md5(random()::text || id::text);
+alter table apps alter column secret set not null;
+alter table apps add constraint apps_secret_key unique (secret);
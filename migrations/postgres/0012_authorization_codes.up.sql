@@ -0,0 +1,14 @@
+-- Authorization codes of the authorization code grant (RFC 6749, section 4.1).
+-- The code is deleted when it is exchanged for the tokens, so it is used only once.
+create table if not exists authorization_codes (
+    code varchar(64) primary key,
+    app_id int not null references apps (id) on delete cascade,
+    user_id bigint not null,
+    redirect_uri text not null,
+    code_challenge varchar(64) not null, -- S256 challenge of PKCE (RFC 7636)
+    user_agent text not null default '',
+    created_by varchar(45), -- IP address
+    created_at timestamp not null,
+    expires_at timestamp not null
+);
+create index if not exists idx_authorization_codes_expires_at on authorization_codes (expires_at);
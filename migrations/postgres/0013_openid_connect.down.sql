@@ -0,0 +1,3 @@
+alter table authorization_codes drop column if exists nonce;
+alter table authorization_codes drop column if exists scope;
+alter table refresh_tokens drop column if exists scope;
@@ -0,0 +1,18 @@
+-- Device codes of the device authorization grant (RFC 8628).
+-- The user approves the device by the user code, then the device exchanges the device code for the tokens
+-- and the code is deleted, so it is used only once.
+create table if not exists device_codes (
+    device_code varchar(64) primary key,
+    user_code varchar(16) not null unique,
+    app_id int not null references apps (id) on delete cascade,
+    scope text not null default '',
+    user_id bigint, -- set when the user approves the device
+    poll_interval int not null, -- seconds
+    user_agent text not null default '',
+    created_by varchar(45), -- IP address
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    polled_at timestamp,
+    approved_at timestamp
+);
+create index if not exists idx_device_codes_expires_at on device_codes (expires_at);
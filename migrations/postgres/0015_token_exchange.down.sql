@@ -0,0 +1,4 @@
+delete from permissions where resource = 'token';
+
+alter table security_events drop column if exists actor;
+alter table security_events drop column if exists app_id;
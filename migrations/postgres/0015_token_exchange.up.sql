@@ -0,0 +1,14 @@
+-- The token exchange is audited with the app that exchanged the token and the actor it was delegated to.
+alter table security_events add column if not exists app_id int;
+alter table security_events add column if not exists actor text; -- "sub" of the actor token
+
+insert into permissions (resource, action, description) values
+    -- Permissions of the apps to exchange the tokens of the users (RFC 8693)
+    ('token', 'impersonate', 'Permission to exchange the token of the user for the one to act as the user'),
+    ('token', 'delegate', 'Permission to exchange the token of the user for the one to act on behalf of the user')
+on conflict do nothing;
+
+insert into role_permissions (role_id, permission_id)
+select r.id, p.id from roles r, permissions p
+where r.name = 'admin' and p.resource = 'token'
+on conflict do nothing;
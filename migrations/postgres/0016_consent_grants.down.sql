@@ -0,0 +1,4 @@
+drop table if exists grants;
+
+alter table authorization_codes drop column if exists consent_pending;
+alter table apps drop column if exists first_party;
@@ -0,0 +1,15 @@
+-- The first-party apps get the tokens without the consent of the user, the others ask for it.
+alter table apps add column if not exists first_party boolean not null default false;
+
+-- The code of the third-party app is pending until the user consents to the requested scopes.
+alter table authorization_codes add column if not exists consent_pending boolean not null default false;
+
+-- The scopes the user has consented to grant to the app.
+create table if not exists grants (
+    user_id bigint not null,
+    app_id int not null references apps (id) on delete cascade,
+    scope text not null default '', -- space-delimited
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    constraint grants_pk primary key (user_id, app_id)
+);
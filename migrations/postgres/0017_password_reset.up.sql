@@ -0,0 +1,11 @@
+-- Tokens that let the users who forgot the password set the new one.
+-- Only the SHA-256 hash of the token is stored, the token itself is sent to the email of the user.
+-- The token is deleted when it is used, so it is used only once.
+create table if not exists password_reset_tokens (
+    token_hash char(64) primary key, -- hex encoded
+    user_id bigint not null references profiles (id) on delete cascade,
+    created_by varchar(45), -- IP address
+    created_at timestamp not null,
+    expires_at timestamp not null
+);
+create index if not exists idx_password_reset_tokens_user_id on password_reset_tokens (user_id);
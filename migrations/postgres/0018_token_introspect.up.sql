@@ -0,0 +1,9 @@
+-- The resource servers must be granted the permission to introspect the tokens (RFC 7662, section 2.1).
+insert into permissions (resource, action, description) values
+    ('token', 'introspect', 'Permission to introspect the tokens of the users and the apps')
+on conflict do nothing;
+
+insert into role_permissions (role_id, permission_id)
+select r.id, p.id from roles r, permissions p
+where r.name = 'admin' and p.resource = 'token' and p.action = 'introspect'
+on conflict do nothing;
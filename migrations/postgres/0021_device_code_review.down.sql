@@ -0,0 +1,2 @@
+alter table device_codes drop column if exists review_code;
+alter table device_codes drop column if exists reviewed_by;
@@ -0,0 +1,4 @@
+-- The user signs in on the device page first and only then sees the app and the scopes to approve them,
+-- the code of the review binds the approval to the user that signed in.
+alter table device_codes add column if not exists reviewed_by bigint;
+alter table device_codes add column if not exists review_code varchar(64);
@@ -0,0 +1,10 @@
+drop table if exists user_roles;
+drop table if exists role_permissions;
+drop table if exists roles;
+drop table if exists permissions;
+drop table if exists apps;
+drop table if exists idp_profiles;
+drop index if exists idx_email;
+drop table if exists credentials;
+drop table if exists refresh_tokens;
+drop table if exists profiles;
@@ -0,0 +1,77 @@
+create table if not exists profiles (
+    id integer primary key,
+    first_name text not null,
+    last_name text,
+    date_of_birth datetime,
+    created_at datetime,
+    updated_at datetime
+);
+
+create table if not exists credentials (
+    id integer primary key,
+    email text not null unique,
+    pass_hash text not null, -- password (salted)
+    foreign key (id) references profiles (id) on delete cascade
+);
+
+create index if not exists idx_email on credentials (email);
+
+create table if not exists refresh_tokens (
+    value text primary key,
+    user_id integer not null unique,
+    expires_in datetime not null,
+    foreign key (user_id) references profiles (id) on delete cascade
+);
+
+-- Profiles linked to the accounts of identity providers (Steam, Google, ...)
+create table if not exists idp_profiles (
+    id blob primary key,
+    username text not null,
+    avatar_hash text not null default '',
+    idp text not null,
+    account_id text not null
+);
+
+create table if not exists apps (
+    id integer primary key autoincrement,
+    name text not null unique,
+    secret text not null unique
+);
+
+create table if not exists permissions (
+    id integer primary key autoincrement,
+    resource text not null,
+    action text not null,
+    description text not null,
+    constraint resource_action_uq unique (resource, action)
+);
+
+create table if not exists roles (
+    id integer primary key autoincrement,
+    name text not null unique,
+    description text not null default ''
+);
+
+create table if not exists role_permissions (
+    role_id integer not null references roles (id) on delete cascade,
+    permission_id integer not null references permissions (id) on delete cascade,
+    constraint role_permissions_pk primary key (role_id, permission_id)
+);
+
+create table if not exists user_roles (
+    user_id blob not null,
+    role_id integer not null references roles (id) on delete cascade,
+    constraint user_roles_pk primary key (user_id, role_id)
+);
+
+insert into permissions (resource, action, description) values
+    -- Permissions to act on permissions
+    ('permission', 'create', 'Permission to create new permissions'),
+    ('permission', 'delete', 'Permission to delete permissions'),
+    -- Permissions to act on role permissions
+    ('permission', 'grant', 'Permission to grant permissions to role'),
+    ('permission', 'revoke', 'Permission to revoke permissions from role'),
+    -- Permissions to act on roles
+    ('role', 'create', 'Permission to create new roles'),
+    ('role', 'delete', 'Permission to delete roles')
+on conflict do nothing;
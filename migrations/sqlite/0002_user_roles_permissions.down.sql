@@ -0,0 +1 @@
+delete from permissions where resource = 'role' and action in ('grant', 'revoke');
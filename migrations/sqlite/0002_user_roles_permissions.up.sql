@@ -0,0 +1,5 @@
+insert into permissions (resource, action, description) values
+    -- Permissions to act on user roles
+    ('role', 'grant', 'Permission to grant roles to user'),
+    ('role', 'revoke', 'Permission to revoke roles from user')
+on conflict do nothing;
@@ -0,0 +1,27 @@
+-- Ids are stored back as 16-byte big-endian values with zero high bytes.
+create table user_roles_old (
+    user_id blob not null,
+    role_id integer not null references roles (id) on delete cascade,
+    constraint user_roles_pk primary key (user_id, role_id)
+);
+
+insert into user_roles_old (user_id, role_id)
+select unhex(printf('%032X', user_id)), role_id from user_roles;
+
+drop table user_roles;
+alter table user_roles_old rename to user_roles;
+
+create table idp_profiles_old (
+    id blob primary key,
+    username text not null,
+    avatar_hash text not null default '',
+    idp text not null,
+    account_id text not null
+);
+
+insert into idp_profiles_old (id, username, avatar_hash, idp, account_id)
+select unhex(printf('%032X', id)), username, avatar_hash, idp, account_id
+from idp_profiles;
+
+drop table idp_profiles;
+alter table idp_profiles_old rename to idp_profiles;
@@ -0,0 +1,45 @@
+-- User ids become the snowflake ids issued by Auth instead of 16-byte ids.
+
+-- Roles could only be granted by 16-byte ids, which never matched a user of Auth.
+-- Ids holding a big-endian snowflake id in the low 8 bytes (the high ones are zero)
+-- are converted, the other assignments are dropped.
+create table user_roles_new (
+    user_id integer not null,
+    role_id integer not null references roles (id) on delete cascade,
+    constraint user_roles_pk primary key (user_id, role_id)
+);
+
+with recursive conv (role_id, digits, value) as (
+    select role_id, hex(substr(user_id, 9, 8)), 0
+    from user_roles
+    where length(user_id) = 16
+        and substr(user_id, 1, 8) = zeroblob(8)
+        and hex(substr(user_id, 9, 1)) < '80'
+    union all
+    select role_id, substr(digits, 2), value * 16 + instr('0123456789ABCDEF', substr(digits, 1, 1)) - 1
+    from conv
+    where digits != ''
+)
+insert into user_roles_new (user_id, role_id)
+select value, role_id from conv where digits = ''
+on conflict do nothing;
+
+drop table user_roles;
+alter table user_roles_new rename to user_roles;
+
+-- Profiles are numbered in the order of their old ids,
+-- small numbers never collide with the snowflake ids.
+create table idp_profiles_new (
+    id integer primary key,
+    username text not null,
+    avatar_hash text not null default '',
+    idp text not null,
+    account_id text not null
+);
+
+insert into idp_profiles_new (id, username, avatar_hash, idp, account_id)
+select row_number() over (order by id), username, avatar_hash, idp, account_id
+from idp_profiles;
+
+drop table idp_profiles;
+alter table idp_profiles_new rename to idp_profiles;
@@ -0,0 +1,12 @@
+create table refresh_tokens_old (
+    value text primary key,
+    user_id integer not null unique,
+    expires_in datetime not null,
+    foreign key (user_id) references profiles (id) on delete cascade
+);
+
+insert into refresh_tokens_old (value, user_id, expires_in)
+select value, user_id, expires_in from refresh_tokens where revoked_at is null;
+
+drop table refresh_tokens;
+alter table refresh_tokens_old rename to refresh_tokens;
@@ -0,0 +1,20 @@
+-- Revoked refresh tokens are kept for audit instead of being replaced,
+-- so the table is rebuilt without the unique constraint on user_id.
+create table refresh_tokens_new (
+    value text primary key,
+    user_id integer not null,
+    expires_in datetime not null,
+    revoked_at datetime,
+    revoked_by text, -- IP address
+    foreign key (user_id) references profiles (id) on delete cascade
+);
+
+insert into refresh_tokens_new (value, user_id, expires_in)
+select value, user_id, expires_in from refresh_tokens;
+
+drop table refresh_tokens;
+alter table refresh_tokens_new rename to refresh_tokens;
+
+-- Only one unrevoked token per user
+create unique index if not exists idx_refresh_tokens_active_user_id on refresh_tokens (user_id)
+    where revoked_at is null;
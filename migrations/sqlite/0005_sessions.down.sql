@@ -0,0 +1,26 @@
+create table refresh_tokens_old (
+    value text primary key,
+    user_id integer not null,
+    expires_in datetime not null,
+    revoked_at datetime,
+    revoked_by text, -- IP address
+    foreign key (user_id) references profiles (id) on delete cascade
+);
+
+-- Only the last refreshed session of each user survives
+insert into refresh_tokens_old (value, user_id, expires_in, revoked_at, revoked_by)
+select value, user_id, expires_in,
+    case when rn = 1 then revoked_at else coalesce(revoked_at, datetime('now')) end,
+    revoked_by
+from (
+    select *, case when revoked_at is null
+        then row_number() over (partition by user_id, revoked_at is null order by created_at desc)
+        end as rn
+    from refresh_tokens
+);
+
+drop table refresh_tokens;
+alter table refresh_tokens_old rename to refresh_tokens;
+
+create unique index if not exists idx_refresh_tokens_active_user_id on refresh_tokens (user_id)
+    where revoked_at is null;
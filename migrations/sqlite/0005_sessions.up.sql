@@ -0,0 +1,27 @@
+-- Each login starts its own session, so the user can have many unrevoked tokens.
+create table refresh_tokens_new (
+    value text primary key,
+    session_id integer not null,
+    user_id integer not null,
+    device_name text not null default '',
+    user_agent text not null default '',
+    created_by text, -- IP address
+    created_at datetime,
+    expires_in datetime not null,
+    revoked_at datetime,
+    revoked_by text, -- IP address
+    foreign key (user_id) references profiles (id) on delete cascade
+);
+
+-- There was one session per user, so the existing ones take the id of their user:
+-- the snowflake generator never issues the same id twice.
+insert into refresh_tokens_new (value, session_id, user_id, expires_in, revoked_at, revoked_by)
+select value, user_id, user_id, expires_in, revoked_at, revoked_by from refresh_tokens;
+
+drop table refresh_tokens;
+alter table refresh_tokens_new rename to refresh_tokens;
+
+-- Only one unrevoked token per session
+create unique index if not exists idx_refresh_tokens_active_session_id on refresh_tokens (session_id)
+    where revoked_at is null;
+create index if not exists idx_refresh_tokens_user_id on refresh_tokens (user_id);
@@ -0,0 +1,19 @@
+-- The rotated refresh token is marked as used instead of being revoked,
+-- so its reuse can be told apart from the logout.
+alter table refresh_tokens add column used_at datetime;
+alter table refresh_tokens add column used_by text; -- IP address
+
+-- Only one unused and unrevoked token per session
+drop index if exists idx_refresh_tokens_active_session_id;
+create unique index if not exists idx_refresh_tokens_active_session_id on refresh_tokens (session_id)
+    where revoked_at is null and used_at is null;
+
+create table if not exists security_events (
+    id integer primary key autoincrement,
+    type text not null,
+    user_id integer not null,
+    session_id integer,
+    ip text, -- IP address
+    created_at datetime not null
+);
+create index if not exists idx_security_events_user_id on security_events (user_id);
@@ -0,0 +1,10 @@
+-- Key ring of the access tokens. The private key is sealed with the master key if encrypted is set.
+create table if not exists signing_keys (
+    kid text primary key,
+    algorithm text not null,
+    private_key blob not null,
+    encrypted boolean not null default false,
+    created_at datetime not null,
+    activates_at datetime not null, -- the newest activated key signs the tokens
+    expires_at datetime -- the retired key verifies the tokens until then
+);
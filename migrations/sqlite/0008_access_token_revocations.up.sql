@@ -0,0 +1,11 @@
+-- Denylist of the access tokens revoked before their expiration.
+-- The single token is revoked if jti is set, otherwise all the tokens of the session.
+create table if not exists access_token_revocations (
+    id integer primary key autoincrement,
+    jti text,
+    session_id integer not null,
+    user_id integer not null,
+    revoked_at datetime not null,
+    expires_at datetime not null -- the revoked tokens expire by then
+);
+create index if not exists idx_access_token_revocations_expires_at on access_token_revocations (expires_at);
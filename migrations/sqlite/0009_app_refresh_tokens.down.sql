@@ -0,0 +1 @@
+alter table refresh_tokens drop column app_id;
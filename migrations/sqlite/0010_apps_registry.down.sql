@@ -0,0 +1,19 @@
+delete from roles where name = 'admin';
+
+delete from permissions where resource = 'app';
+
+drop table if exists app_permissions;
+drop table if exists app_grant_types;
+drop table if exists app_redirect_uris;
+
+-- The secrets are lost, the apps get new random ones.
+create table apps_old (
+    id integer primary key autoincrement,
+    name text not null unique,
+    secret text not null unique
+);
+
+insert into apps_old (id, name, secret) select id, name, lower(hex(randomblob(32))) from apps;
+
+drop table apps;
+alter table apps_old rename to apps;
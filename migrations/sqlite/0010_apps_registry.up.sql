@@ -0,0 +1,49 @@
+-- Apps keep the hash of their secret instead of the secret itself.
+-- The plain secrets can not be hashed here, so the apps have to reset them.
+create table apps_new (
+    id integer primary key autoincrement,
+    name text not null unique,
+    secret_hash blob, -- bcrypt, null until the secret is reset
+    created_at datetime not null default current_timestamp
+);
+
+insert into apps_new (id, name) select id, name from apps;
+
+drop table apps;
+alter table apps_new rename to apps;
+
+create table if not exists app_redirect_uris (
+    app_id integer not null references apps (id) on delete cascade,
+    uri text not null,
+    constraint app_redirect_uris_pk primary key (app_id, uri)
+);
+
+create table if not exists app_grant_types (
+    app_id integer not null references apps (id) on delete cascade,
+    grant_type text not null,
+    constraint app_grant_types_pk primary key (app_id, grant_type)
+);
+
+-- The permissions the tokens issued for the app may carry.
+create table if not exists app_permissions (
+    app_id integer not null references apps (id) on delete cascade,
+    permission_id integer not null references permissions (id) on delete cascade,
+    constraint app_permissions_pk primary key (app_id, permission_id)
+);
+
+insert into permissions (resource, action, description) values
+    -- Permissions to act on apps
+    ('app', 'create', 'Permission to register new apps'),
+    ('app', 'read', 'Permission to read apps'),
+    ('app', 'update', 'Permission to update apps and reset their secrets'),
+    ('app', 'delete', 'Permission to delete apps')
+on conflict do nothing;
+
+-- The admins get every base permission, the first ones are granted it on registration (see admin_emails).
+insert into roles (name, description) values ('admin', 'Administrators of the service')
+on conflict do nothing;
+
+insert into role_permissions (role_id, permission_id)
+select r.id, p.id from roles r, permissions p
+where r.name = 'admin'
+on conflict do nothing;
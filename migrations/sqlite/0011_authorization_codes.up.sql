@@ -0,0 +1,14 @@
+-- Authorization codes of the authorization code grant (RFC 6749, section 4.1).
+-- The code is deleted when it is exchanged for the tokens, so it is used only once.
+create table if not exists authorization_codes (
+    code text primary key,
+    app_id integer not null references apps (id) on delete cascade,
+    user_id integer not null,
+    redirect_uri text not null,
+    code_challenge text not null, -- S256 challenge of PKCE (RFC 7636)
+    user_agent text not null default '',
+    created_by text, -- IP address
+    created_at datetime not null,
+    expires_at datetime not null
+);
+create index if not exists idx_authorization_codes_expires_at on authorization_codes (expires_at);
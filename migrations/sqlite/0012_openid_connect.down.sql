@@ -0,0 +1,3 @@
+alter table authorization_codes drop column nonce;
+alter table authorization_codes drop column scope;
+alter table refresh_tokens drop column scope;
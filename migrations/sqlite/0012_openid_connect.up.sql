@@ -0,0 +1,5 @@
+-- Space-delimited scopes granted to the app, the tokens of the session keep them after the rotation.
+alter table refresh_tokens add column scope text not null default '';
+-- The scopes requested by the app and the nonce of OpenID Connect are bound to the authorization code.
+alter table authorization_codes add column scope text not null default '';
+alter table authorization_codes add column nonce text not null default '';
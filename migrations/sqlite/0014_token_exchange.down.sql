@@ -0,0 +1,4 @@
+delete from permissions where resource = 'token';
+
+alter table security_events drop column actor;
+alter table security_events drop column app_id;
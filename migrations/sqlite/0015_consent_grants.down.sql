@@ -0,0 +1,4 @@
+drop table if exists grants;
+
+alter table authorization_codes drop column consent_pending;
+alter table apps drop column first_party;
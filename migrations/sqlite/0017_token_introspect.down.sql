@@ -0,0 +1 @@
+delete from permissions where resource = 'token' and action = 'introspect';
@@ -0,0 +1 @@
+delete from permissions where resource = 'revocation' and action = 'watch';
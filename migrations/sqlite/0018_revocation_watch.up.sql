@@ -0,0 +1,8 @@
+-- The resource servers must be granted the permission to watch the revocations of the access tokens.
+insert into permissions (resource, action, description) values
+    ('revocation', 'watch', 'Permission to watch the revocations of the access tokens')
+on conflict do nothing;
+insert into role_permissions (role_id, permission_id)
+select r.id, p.id from roles r, permissions p
+where r.name = 'admin' and p.resource = 'revocation' and p.action = 'watch'
+on conflict do nothing;
@@ -0,0 +1,11 @@
+-- The profiles are managed by the users and the apps granted these permissions.
+insert into permissions (resource, action, description) values
+    ('profile', 'create', 'Permission to create new profiles'),
+    ('profile', 'read', 'Permission to read profiles'),
+    ('profile', 'update', 'Permission to update profiles'),
+    ('profile', 'delete', 'Permission to delete profiles')
+on conflict do nothing;
+insert into role_permissions (role_id, permission_id)
+select r.id, p.id from roles r, permissions p
+where r.name = 'admin' and p.resource = 'profile'
+on conflict do nothing;
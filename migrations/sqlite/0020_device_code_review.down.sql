@@ -0,0 +1,2 @@
+alter table device_codes drop column review_code;
+alter table device_codes drop column reviewed_by;
@@ -0,0 +1,169 @@
+package jwt
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+)
+
+// Key types and curves of RFC 7518 and RFC 8037
+const (
+	ktyRSA = "RSA"
+	ktyEC  = "EC"
+	ktyOKP = "OKP"
+
+	crvP256    = "P-256"
+	crvEd25519 = "Ed25519"
+
+	useSig = "sig"
+
+	p256CoordinateLen = 32
+)
+
+var ErrWrongJWK = errors.New("wrong JWK")
+
+// JWK is the public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the JSON Web Key Set document served to the resource servers.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public part of the key. Symmetric keys have none.
+func (k *Key) JWK() (JWK, bool) {
+	jwk := JWK{
+		Use: useSig,
+		Alg: k.Method.Alg(),
+		Kid: k.Id,
+	}
+
+	switch p := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = ktyRSA
+		jwk.N = encode(p.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(p.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = ktyEC
+		jwk.Crv = crvP256
+		jwk.X = encode(p.X.FillBytes(make([]byte, p256CoordinateLen)))
+		jwk.Y = encode(p.Y.FillBytes(make([]byte, p256CoordinateLen)))
+	case ed25519.PublicKey:
+		jwk.Kty = ktyOKP
+		jwk.Crv = crvEd25519
+		jwk.X = encode(p)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// Find returns the key with the given id.
+func (s JWKS) Find(kid string) (JWK, bool) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+
+	return JWK{}, false
+}
+
+// PublicKey decodes the public key, so it can be used to verify the token signature.
+func (k JWK) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case ktyRSA:
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case ktyEC:
+		if k.Crv != crvP256 {
+			return nil, ErrWrongJWK
+		}
+
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != p256CoordinateLen || len(y) != p256CoordinateLen {
+			return nil, ErrWrongJWK
+		}
+
+		// The point must be on the curve, the uncompressed form is 0x04 || X || Y
+		point := append(append([]byte{4}, x...), y...)
+		if _, err = ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, ErrWrongJWK
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	case ktyOKP:
+		if k.Crv != crvEd25519 {
+			return nil, ErrWrongJWK
+		}
+
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, ErrWrongJWK
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrWrongJWK
+	}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, ErrWrongJWK
+	}
+
+	return b, nil
+}
@@ -2,95 +2,199 @@ package jwt
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt"
-	"github.com/puregrade-group/sso/internal/domain/models"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+const tokenIdLen = 16
+
 var (
-	ErrWrongClaims  = errors.New("wrong claims")
-	ErrWrongMethod  = errors.New("wrong sign method")
-	ErrInvalidToken = errors.New("invalid token")
-	ErrTokenExpired = errors.New("token has expired")
-	ErrUnknownApp   = errors.New("app with this appId is unknown")
+	ErrWrongClaims   = errors.New("wrong claims")
+	ErrWrongMethod   = errors.New("wrong sign method")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrTokenExpired  = errors.New("token has expired")
+	ErrWrongAudience = errors.New("token is not intended for this audience")
+	ErrUnknownApp    = errors.New("app with this appId is unknown")
 )
 
+// DefaultClaims are the claims of the tokens issued by the service.
+// UID and SessionId are snowflake ids, they are encoded as decimal strings
+// because JSON numbers lose precision above 2^53 in most clients.
+// The registered claims are set too: "sub" duplicates UID for the off-the-shelf middleware
+// and "jti" is the token id, so the token can be revoked.
+// Scope is the space-delimited list of the granted scopes (RFC 9068, section 2.2.3): the ones of OpenID Connect
+// and the "resource:action" permissions of the user the resource servers may authorise the coarse checks by.
+// The token of the client credentials grant has no user and no session, its subject is the app itself.
+// Act is set in the token obtained by the delegation, it is the party that acts on behalf of the user.
 type DefaultClaims struct {
-	AppId int32  `json:"appId"`
-	UID   uint64 `json:"UID"`
-	jwt.StandardClaims
+	AppId     int32  `json:"appId"`
+	UID       uint64 `json:"UID,string"`
+	SessionId uint64 `json:"sid,string,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Act       *Actor `json:"act,omitempty"`
+	jwt.RegisteredClaims
 }
 
-// NewToken creates new JWT token for given user.
-func NewToken(userId uint64, duration time.Duration, secret []byte) (string, error) {
-	claims := DefaultClaims{
-		UID: userId,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(duration).Unix(),
-		},
-	}
+// Actor is the "act" claim of RFC 8693, section 4.1. Subject is the "sub" claim of the token of the actor.
+// The prior actors of the chain of the delegation are nested, the current one is the outermost.
+type Actor struct {
+	Subject string `json:"sub"`
+	Act     *Actor `json:"act,omitempty"`
+}
 
-	// Creating new token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// IDClaims are the claims of the ID token of OpenID Connect (OpenID Connect Core 1.0, sections 2 and 5.1).
+// The claims of the user are set only if the scopes granted to the app include them.
+type IDClaims struct {
+	Nonce      string `json:"nonce,omitempty"`
+	Email      string `json:"email,omitempty"`
+	GivenName  string `json:"given_name,omitempty"`
+	FamilyName string `json:"family_name,omitempty"`
+	Birthdate  string `json:"birthdate,omitempty"` // YYYY-MM-DD
+	jwt.RegisteredClaims
+}
+
+// Validation is the way the registered claims of the token are validated.
+type Validation struct {
+	// The "iss" claim must be equal to it, if it is set
+	Issuer string
+	// The "aud" claim must contain any of them or the audience of the app the token was issued for.
+	// Nothing disables the check.
+	Audience []string
+	// Clock skew allowed when "exp", "nbf" and "iat" are checked
+	Leeway time.Duration
+}
 
-	// Signing token with a secret
-	tokenString, err := token.SignedString(secret)
+// NewToken creates new JWT token for given user and session signed with the key of the service.
+// Every token gets the unique id, the revoked tokens are told apart by it.
+// The scopes granted to the user are put in the "scope" claim.
+func NewToken(
+	issuer string,
+	audience []string,
+	userId, sessionId uint64,
+	scopes []string,
+	duration time.Duration,
+	key *Key,
+) (string, error) {
+	claims := newClaims(issuer, audience, userId, duration)
+	claims.SessionId = sessionId
+	claims.Scope = strings.Join(scopes, " ")
 
-	return tokenString, err
+	return key.Sign(claims)
 }
 
-// NewToken1 creates new JWT token for given user and app.
-func NewToken1(userId uint64, app models.App, duration time.Duration) (string, error) {
-	claims := DefaultClaims{
-		UID:   userId,
-		AppId: app.Id,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(duration).Unix(),
-		},
-	}
+// NewAppToken creates new JWT token for given user and session intended for the app,
+// it is signed with the key of the service like the tokens issued without an app.
+// The scopes granted to the app are put in the "scope" claim.
+func NewAppToken(
+	issuer string,
+	userId, sessionId uint64,
+	appId int32,
+	scopes []string,
+	duration time.Duration,
+	key *Key,
+) (string, error) {
+	claims := newClaims(issuer, []string{AppAudience(appId)}, userId, duration)
+	claims.SessionId = sessionId
+	claims.AppId = appId
+	claims.Scope = strings.Join(scopes, " ")
 
-	// create new token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return key.Sign(claims)
+}
 
-	// sign with a secret
-	tokenString, err := token.SignedString([]byte(app.Secret))
-	if err != nil {
-		return "", err
-	}
+// NewClientToken creates new JWT token whose subject is the app itself, it is issued by the client credentials grant.
+// Like in RFC 9068, section 2.2, "sub" is the client_id of the app and "UID" is not set.
+func NewClientToken(
+	issuer string,
+	appId int32,
+	duration time.Duration,
+	key *Key,
+) (string, error) {
+	claims := newClaims(issuer, []string{AppAudience(appId)}, 0, duration)
+	claims.AppId = appId
+	claims.Subject = AppAudience(appId)
 
-	return tokenString, nil
+	return key.Sign(claims)
+}
+
+// NewExchangedToken creates new JWT token of the user and the session obtained by the token exchange (RFC 8693),
+// it is issued to the app for the audience the app asked for. The actor is put in the "act" claim, nil means
+// the app impersonates the user. The duration must not outlive the exchanged token.
+func NewExchangedToken(
+	issuer string,
+	audience []string,
+	userId, sessionId uint64,
+	appId int32,
+	scopes []string,
+	actor *Actor,
+	duration time.Duration,
+	key *Key,
+) (string, error) {
+	claims := newClaims(issuer, audience, userId, duration)
+	claims.SessionId = sessionId
+	claims.AppId = appId
+	claims.Scope = strings.Join(scopes, " ")
+	claims.Act = actor
+
+	return key.Sign(claims)
+}
+
+// NewIDToken creates the ID token of the user for the app, its audience is the app like the client_id of OAuth.
+// The registered claims are set by it, the claims of the user are taken as they are.
+func NewIDToken(
+	issuer string,
+	userId uint64,
+	appId int32,
+	claims IDClaims,
+	duration time.Duration,
+	key *Key,
+) (string, error) {
+	claims.RegisteredClaims = newClaims(issuer, []string{AppAudience(appId)}, userId, duration).RegisteredClaims
+
+	return key.Sign(claims)
+}
+
+// AppAudience returns the audience of the tokens issued for the app, it is the id of the app like the client_id of OAuth.
+func AppAudience(appId int32) string {
+	return strconv.FormatInt(int64(appId), 10)
 }
 
 // ParseToken function checks the validity of the token and parses data from its payload.
-// The "Secret" parameter is a function that allows you to obtain the JWT secret key by application ID.
+// The "Key" parameter is a function that allows you to obtain the verification key by the "kid" header of the token.
+// The registered claims are validated as the validation sets, "exp" and "iat" are required.
 func ParseToken(tokenString string,
-	secret func(ctx context.Context, appId int32) (string, error),
+	key func(ctx context.Context, kid string) (*Key, error),
+	validation Validation,
 ) (*jwt.Token, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&DefaultClaims{},
 		func(token *jwt.Token) (interface{}, error) {
-			if token.Method != jwt.SigningMethodHS256 {
-				return nil, ErrWrongMethod
+			kid, _ := token.Header["kid"].(string)
+			if kid == "" {
+				return nil, ErrUnknownKey
 			}
-			if claims, ok := token.Claims.(*DefaultClaims); ok {
-				if claims.ExpiresAt < time.Now().Unix() {
-					return nil, ErrTokenExpired
-				}
-
-				s, err := secret(context.Background(), claims.AppId)
-				if err != nil {
-					return nil, ErrUnknownApp
-				}
-				return s, nil
+
+			k, err := key(context.Background(), kid)
+			if err != nil {
+				return nil, ErrUnknownKey
 			}
-			return nil, ErrWrongClaims
+
+			return k.verificationKey(token)
 		},
+		validation.parserOptions()...,
 	)
 
 	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+
 		return nil, err
 	}
 
@@ -98,33 +202,94 @@ func ParseToken(tokenString string,
 		return nil, ErrInvalidToken
 	}
 
+	claims, ok := token.Claims.(*DefaultClaims)
+	if !ok {
+		return nil, ErrWrongClaims
+	}
+
+	if err = validation.validate(claims); err != nil {
+		return nil, err
+	}
+
 	return token, nil
 }
 
-func ParseAppToken(tokenString string, secret string) (*jwt.Token, error) {
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&DefaultClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			if token.Method != jwt.SigningMethodHS256 {
-				return nil, ErrWrongMethod
-			}
-			if claims, ok := token.Claims.(*DefaultClaims); ok {
-				if claims.ExpiresAt < time.Now().Unix() {
-					return nil, ErrTokenExpired
-				}
-			}
-			return secret, nil
+// IsClient reports whether the token was issued to the app itself and not to the user.
+func (c *DefaultClaims) IsClient() bool {
+	return c.UID == 0 && c.AppId != 0
+}
+
+// SubjectId returns the "sub" claim the token should have: the id of the user or the client_id of the app.
+func (c *DefaultClaims) SubjectId() string {
+	if c.IsClient() {
+		return AppAudience(c.AppId)
+	}
+
+	return strconv.FormatUint(c.UID, 10)
+}
+
+func newClaims(issuer string, audience []string, userId uint64, duration time.Duration) DefaultClaims {
+	now := time.Now()
+
+	return DefaultClaims{
+		UID: userId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatUint(userId, 10),
+			Audience:  audience,
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        newTokenId(),
 		},
-	)
+	}
+}
 
-	if err != nil {
-		return nil, err
+func (v Validation) parserOptions() []jwt.ParserOption {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgES256, AlgEdDSA}),
+		jwt.WithLeeway(v.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
 	}
 
-	if !token.Valid {
-		return nil, ErrInvalidToken
+	if v.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.Issuer))
 	}
 
-	return token, nil
+	return opts
+}
+
+// validate checks the claims the parser does not know about.
+// The audience is checked here, since the audience of the app token is known only after parsing.
+func (v Validation) validate(claims *DefaultClaims) error {
+	if claims.Subject != "" && claims.Subject != claims.SubjectId() {
+		return ErrWrongClaims
+	}
+
+	if len(v.Audience) == 0 {
+		return nil
+	}
+
+	audience := v.Audience
+	if claims.AppId != 0 {
+		audience = append([]string{AppAudience(claims.AppId)}, audience...)
+	}
+
+	for _, aud := range claims.Audience {
+		for _, expected := range audience {
+			if aud == expected {
+				return nil
+			}
+		}
+	}
+
+	return ErrWrongAudience
+}
+
+func newTokenId() string {
+	b := make([]byte, tokenIdLen)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
 }
@@ -0,0 +1,197 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported by the service
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+const (
+	rsaKeyBits    = 2048
+	hmacSecretLen = 32
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown signing algorithm")
+	ErrWrongKey         = errors.New("key does not match the signing algorithm")
+	ErrUnknownKey       = errors.New("token was signed with an unknown key")
+)
+
+// Key is the key the service signs its tokens with.
+// Asymmetric keys are published as JWK, so the tokens can be verified without any secret.
+type Key struct {
+	Id     string
+	Method jwt.SigningMethod
+
+	private interface{} // HMAC secret or private key
+	public  interface{} // HMAC secret or public key
+}
+
+// NewKey creates the signing key of the given algorithm.
+// For HS256 the data is the shared secret, for other algorithms it is the PEM encoded private key.
+func NewKey(id, algorithm string, data []byte) (*Key, error) {
+	const op = "jwt.NewKey"
+
+	var (
+		private interface{}
+		err     error
+	)
+
+	switch algorithm {
+	case AlgHS256:
+		if len(data) == 0 {
+			return nil, fmt.Errorf("%s: %w", op, ErrWrongKey)
+		}
+		private = data
+	case AlgRS256:
+		private, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+	case AlgES256:
+		private, err = jwt.ParseECPrivateKeyFromPEM(data)
+	case AlgEdDSA:
+		private, err = jwt.ParseEdPrivateKeyFromPEM(data)
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newKey(op, id, algorithm, private)
+}
+
+// GenerateKey generates the new signing key of the given algorithm.
+func GenerateKey(id, algorithm string) (*Key, error) {
+	const op = "jwt.GenerateKey"
+
+	var (
+		private interface{}
+		err     error
+	)
+
+	switch algorithm {
+	case AlgHS256:
+		secret := make([]byte, hmacSecretLen)
+		_, err = rand.Read(secret)
+		private = secret
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newKey(op, id, algorithm, private)
+}
+
+func newKey(op, id, algorithm string, private interface{}) (*Key, error) {
+	k := &Key{
+		Id:      id,
+		Method:  jwt.GetSigningMethod(algorithm),
+		private: private,
+	}
+
+	switch p := private.(type) {
+	case []byte:
+		k.public = p
+	case *rsa.PrivateKey:
+		k.public = &p.PublicKey
+	case *ecdsa.PrivateKey:
+		// ES256 is defined for the P-256 curve only
+		if p.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%s: %w", op, ErrWrongKey)
+		}
+		k.public = &p.PublicKey
+	case ed25519.PrivateKey:
+		k.public = p.Public()
+	default:
+		return nil, fmt.Errorf("%s: %w", op, ErrWrongKey)
+	}
+
+	return k, nil
+}
+
+// Sign signs the claims and sets the id of the key to the "kid" header of the token.
+func (k *Key) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.Id
+
+	return token.SignedString(k.private)
+}
+
+// Asymmetric reports whether the keys of the algorithm are verified with the public keys,
+// so the tokens signed with them can be verified by the apps, but not forged.
+func Asymmetric(algorithm string) bool {
+	return algorithm != AlgHS256
+}
+
+// Symmetric reports whether the key is the shared secret, which must never be published.
+func (k *Key) Symmetric() bool {
+	_, ok := k.private.([]byte)
+
+	return ok
+}
+
+// MarshalPrivate returns the data the key can be restored from by NewKey:
+// the HMAC secret or the PEM encoded PKCS #8 private key.
+func (k *Key) MarshalPrivate() ([]byte, error) {
+	const op = "jwt.MarshalPrivate"
+
+	if secret, ok := k.private.([]byte); ok {
+		return secret, nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// verificationKey returns the key the token must be verified with.
+func (k *Key) verificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, ErrWrongMethod
+	}
+
+	if kid, _ := token.Header["kid"].(string); kid != k.Id {
+		return nil, ErrUnknownKey
+	}
+
+	return k.public, nil
+}
+
+// NewJWKS returns the public keys document. Symmetric keys are never published.
+func NewJWKS(keys ...*Key) JWKS {
+	set := JWKS{Keys: []JWK{}}
+
+	for _, k := range keys {
+		if jwk, ok := k.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The credentials the admin is registered with
+const (
+	adminEmail    = "admin@sso.test"
+	adminPassword = "admin-password"
+)
+
+// registerAdmin makes sure the admin is registered once per test binary
+var registerAdmin sync.Once
+
+func TestApps_Manage(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	token := adminToken(ctx, t, st)
+
+	perm, err := st.PermsClient.Create(
+		ctx, &acs.CreatePermissionRequest{
+			RequesterToken: token,
+			Permission:     &acs.Permission{Resource: gofakeit.UUID(), Action: "read"},
+		},
+	)
+	require.NoError(t, err)
+
+	app := &acs.App{
+		Name:         gofakeit.UUID(),
+		RedirectUris: []string{"https://app.example.com/callback", "http://127.0.0.1:8000/callback"},
+		GrantTypes:   []string{"password", "refresh_token"},
+		Permissions:  []*acs.Permission{{PermissionId: &perm.PermissionId}},
+	}
+
+	createResp, err := st.AppsClient.Create(ctx, &acs.CreateAppRequest{RequesterToken: token, App: app})
+	require.NoError(t, err)
+	require.NotZero(t, createResp.GetAppId())
+	assert.NotEmpty(t, createResp.GetSecret())
+
+	// The same name can not be taken twice
+	_, err = st.AppsClient.Create(ctx, &acs.CreateAppRequest{RequesterToken: token, App: app})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	getResp, err := st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: token, AppId: createResp.GetAppId()})
+	require.NoError(t, err)
+	assert.Equal(t, app.GetName(), getResp.GetApp().GetName())
+	assert.ElementsMatch(t, app.GetRedirectUris(), getResp.GetApp().GetRedirectUris())
+	assert.ElementsMatch(t, app.GetGrantTypes(), getResp.GetApp().GetGrantTypes())
+	require.Len(t, getResp.GetApp().GetPermissions(), 1)
+	assert.Equal(t, perm.GetPermissionId(), getResp.GetApp().GetPermissions()[0].GetPermissionId())
+	assert.WithinDuration(t, time.Now(), getResp.GetApp().GetCreatedAt().AsTime(), time.Minute)
+
+	listResp, err := st.AppsClient.List(ctx, &acs.ListAppsRequest{RequesterToken: token})
+	require.NoError(t, err)
+
+	var listed bool
+	for _, a := range listResp.GetApps() {
+		listed = listed || a.GetAppId() == createResp.GetAppId()
+	}
+	assert.True(t, listed)
+
+	// Everything but the secret is replaced
+	appId := createResp.GetAppId()
+	updated := &acs.App{
+		AppId:      &appId,
+		Name:       gofakeit.UUID(),
+		GrantTypes: []string{"password"},
+	}
+
+	_, err = st.AppsClient.Update(ctx, &acs.UpdateAppRequest{RequesterToken: token, App: updated})
+	require.NoError(t, err)
+
+	getResp, err = st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: token, AppId: appId})
+	require.NoError(t, err)
+	assert.Equal(t, updated.GetName(), getResp.GetApp().GetName())
+	assert.Empty(t, getResp.GetApp().GetRedirectUris())
+	assert.Equal(t, updated.GetGrantTypes(), getResp.GetApp().GetGrantTypes())
+	assert.Empty(t, getResp.GetApp().GetPermissions())
+
+	resetResp, err := st.AppsClient.ResetSecret(ctx, &acs.ResetAppSecretRequest{RequesterToken: token, AppId: appId})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resetResp.GetSecret())
+	assert.NotEqual(t, createResp.GetSecret(), resetResp.GetSecret())
+
+	_, err = st.AppsClient.Delete(ctx, &acs.DeleteAppRequest{RequesterToken: token, AppId: appId})
+	require.NoError(t, err)
+
+	_, err = st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: token, AppId: appId})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.AppsClient.Delete(ctx, &acs.DeleteAppRequest{RequesterToken: token, AppId: appId})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestApps_OnlyAdmins(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	token := loginResp.GetAccessToken()
+
+	_, err = st.AppsClient.Create(
+		ctx, &acs.CreateAppRequest{
+			RequesterToken: token,
+			App:            &acs.App{Name: gofakeit.UUID()},
+		},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AppsClient.List(ctx, &acs.ListAppsRequest{RequesterToken: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AppsClient.ResetSecret(ctx, &acs.ResetAppSecretRequest{RequesterToken: token, AppId: 1})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestApps_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	tests := []struct {
+		name         string
+		app          *acs.App
+		expectedCode codes.Code
+		expectedErr  string
+	}{
+		{
+			name:         "Create without name",
+			app:          &acs.App{},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "app name is required",
+		},
+		{
+			name:         "Create with relative redirect uri",
+			app:          &acs.App{Name: "app", RedirectUris: []string{"/callback"}},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "must be absolute and without fragment",
+		},
+		{
+			name:         "Create with redirect uri fragment",
+			app:          &acs.App{Name: "app", RedirectUris: []string{"https://app.example.com/callback#frag"}},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "must be absolute and without fragment",
+		},
+		{
+			name:         "Create with unknown grant type",
+			app:          &acs.App{Name: "app", GrantTypes: []string{"implicit"}},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "unknown grant type",
+		},
+		{
+			name:         "Create with invalid requester token",
+			app:          &acs.App{Name: "app"},
+			expectedCode: codes.Unauthenticated,
+			expectedErr:  "requester token is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := st.AppsClient.Create(
+					ctx, &acs.CreateAppRequest{
+						RequesterToken: gofakeit.UUID(),
+						App:            tt.app,
+					},
+				)
+
+				require.Error(t, err)
+				require.Equal(t, tt.expectedCode, status.Code(err))
+				require.Contains(t, err.Error(), tt.expectedErr)
+			},
+		)
+	}
+
+	_, err := st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: gofakeit.UUID()})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// adminToken returns the access token of the admin, who is registered and granted the admin role once.
+func adminToken(ctx context.Context, t *testing.T, st *suite.Suite) string {
+	t.Helper()
+
+	creds := &auth.Credentials{
+		Email:    adminEmail,
+		Password: adminPassword,
+	}
+
+	// The persistent storages may have the admin registered by the previous run
+	registerAdmin.Do(
+		func() {
+			resp, err := st.AuthClient.Register(
+				ctx, &auth.RegisterRequest{
+					Creds: creds,
+					Profile: &auth.BriefProfile{
+						FirstName:   "Admin",
+						DateOfBirth: timestamppb.New(time.Now().AddDate(-30, 0, 0)),
+					},
+				},
+			)
+			if status.Code(err) == codes.AlreadyExists {
+				return
+			}
+			require.NoError(t, err)
+
+			st.GrantAdmin(ctx, resp.GetUserId())
+		},
+	)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	return loginResp.GetAccessToken()
+}
+
+// testApp is the app the tests register with the redirect URI,
+// the zero value is the third-party app which may use no grant type and is granted no permission.
+type testApp struct {
+	grantTypes    []string
+	permissionIds []int32
+	firstParty    bool
+}
+
+// createApp registers the app and returns its id and secret.
+func createApp(ctx context.Context, t *testing.T, st *suite.Suite, app testApp) (int32, string) {
+	t.Helper()
+
+	req := &acs.App{
+		Name:         gofakeit.UUID(),
+		RedirectUris: []string{redirectURI},
+		GrantTypes:   app.grantTypes,
+		FirstParty:   app.firstParty,
+	}
+	for i := range app.permissionIds {
+		req.Permissions = append(req.Permissions, &acs.Permission{PermissionId: &app.permissionIds[i]})
+	}
+
+	resp, err := st.AppsClient.Create(
+		ctx, &acs.CreateAppRequest{
+			RequesterToken: adminToken(ctx, t, st),
+			App:            req,
+		},
+	)
+	require.NoError(t, err)
+
+	return resp.GetAppId(), resp.GetSecret()
+}
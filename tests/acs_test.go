@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPermissions_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	tests := []struct {
+		name         string
+		req          *acs.CreatePermissionRequest
+		expectedCode codes.Code
+		expectedErr  string
+	}{
+		{
+			name: "Create with empty requester token",
+			req: &acs.CreatePermissionRequest{
+				Permission: &acs.Permission{Resource: "resource", Action: "action"},
+			},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "requester_token is required",
+		},
+		{
+			name: "Create with empty resource",
+			req: &acs.CreatePermissionRequest{
+				RequesterToken: gofakeit.UUID(),
+				Permission:     &acs.Permission{Action: "action"},
+			},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "resource is required",
+		},
+		{
+			name: "Create with invalid requester token",
+			req: &acs.CreatePermissionRequest{
+				RequesterToken: gofakeit.UUID(),
+				Permission:     &acs.Permission{Resource: "resource", Action: "action"},
+			},
+			expectedCode: codes.Unauthenticated,
+			expectedErr:  "requester token is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := st.PermsClient.Create(ctx, tt.req)
+
+				require.Error(t, err)
+				require.Equal(t, tt.expectedCode, status.Code(err))
+				require.Contains(t, err.Error(), tt.expectedErr)
+			},
+		)
+	}
+}
+
+func TestRoles_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.RolesClient.Create(
+		ctx, &acs.CreateRoleRequest{
+			RequesterToken: gofakeit.UUID(),
+			Role:           &acs.Role{},
+		},
+	)
+	require.Error(t, err)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Contains(t, err.Error(), "role name is required")
+
+	_, err = st.RolesClient.Add(
+		ctx, &acs.AddRoleRequest{
+			RequesterToken: gofakeit.UUID(),
+			RoleId:         1,
+		},
+	)
+	require.Error(t, err)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Contains(t, err.Error(), "user_id is required")
+
+	_, err = st.RolesClient.Add(
+		ctx, &acs.AddRoleRequest{
+			RequesterToken: gofakeit.UUID(),
+			UserId:         uint64(gofakeit.Uint32()) + 1,
+			RoleId:         1,
+		},
+	)
+	require.Error(t, err)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestACS_LoginToken_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	// The token issued by Login is accepted and the user id from it is checked
+	checkResp, err := st.PermsClient.CheckPermissions(
+		ctx, &acs.CheckPermissionsRequest{
+			RequesterToken: loginResp.GetAccessToken(),
+			UserId:         userId,
+			Resource:       "permission",
+			Action:         "create",
+		},
+	)
+	require.NoError(t, err)
+	assert.False(t, checkResp.GetOk())
+
+	rolesResp, err := st.RolesClient.GetUserRoles(
+		ctx, &acs.GetUserRolesRequest{
+			RequesterToken: loginResp.GetAccessToken(),
+			UserId:         userId,
+		},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, rolesResp.GetRoles())
+
+	// A freshly registered user has no roles, so it is authenticated but not authorized
+	_, err = st.PermsClient.Create(
+		ctx, &acs.CreatePermissionRequest{
+			RequesterToken: loginResp.GetAccessToken(),
+			Permission:     &acs.Permission{Resource: gofakeit.Noun(), Action: gofakeit.Verb()},
+		},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
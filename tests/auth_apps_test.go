@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// unknownAppId is the id no app is registered with
+const unknownAppId = math.MaxInt32
+
+func TestAuthApps_UnknownApp(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	_, err := st.AuthClient.Register(
+		ctx, &auth.RegisterRequest{
+			Creds: &auth.Credentials{
+				Email:    gofakeit.Email(),
+				Password: gofakeit.Password(true, true, true, true, false, passDefaultLen),
+			},
+			Profile: &auth.BriefProfile{
+				FirstName:   gofakeit.FirstName(),
+				DateOfBirth: timestamppb.New(time.Now().AddDate(-20, 0, 0)),
+			},
+			AppId: unknownAppId,
+		},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, AppId: unknownAppId})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, AppId: -1})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAuthApps_RefreshBoundToApp(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	// The token issued without an app can not be refreshed by an app
+	_, err = st.AuthClient.Refresh(
+		ctx, &auth.RefreshRequest{
+			RefreshToken: loginResp.GetRefreshToken(),
+			AppId:        unknownAppId,
+		},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// The rejected request does not rotate the token
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+}
+
+func TestAuthApps_GrantTypes(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	passwordOnly, _ := createApp(ctx, t, st, testApp{grantTypes: []string{"password"}})
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, AppId: passwordOnly})
+	require.NoError(t, err)
+
+	info := introspect(ctx, t, st, loginResp.GetAccessToken())
+	assert.Equal(t, passwordOnly, info.GetAppId())
+	assert.Equal(t, []string{strconv.FormatInt(int64(passwordOnly), 10)}, info.GetAudience())
+
+	// The app is not allowed to refresh the tokens
+	_, err = st.AuthClient.Refresh(
+		ctx, &auth.RefreshRequest{
+			RefreshToken: loginResp.GetRefreshToken(),
+			AppId:        passwordOnly,
+		},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	refreshOnly, _ := createApp(ctx, t, st, testApp{grantTypes: []string{"refresh_token"}})
+
+	// Nor is the other app allowed to log the users in
+	_, err = st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, AppId: refreshOnly})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	both, _ := createApp(ctx, t, st, testApp{grantTypes: []string{"password", "refresh_token"}})
+
+	loginResp, err = st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, AppId: both})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Refresh(
+		ctx, &auth.RefreshRequest{
+			RefreshToken: loginResp.GetRefreshToken(),
+			AppId:        both,
+		},
+	)
+	require.NoError(t, err)
+}
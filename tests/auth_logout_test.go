@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestLogout_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Logout(ctx, &auth.LogoutRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	// The revoked token can not be used anymore
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.Logout(ctx, &auth.LogoutRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestLogoutAll_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	refreshResp, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	// The rotated token can not be refreshed again
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = st.AuthClient.LogoutAll(ctx, &auth.LogoutAllRequest{RefreshToken: refreshResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: refreshResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.LogoutAll(ctx, &auth.LogoutAllRequest{RefreshToken: refreshResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestLogout_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AuthClient.Logout(ctx, &auth.LogoutRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.Logout(ctx, &auth.LogoutRequest{RefreshToken: gofakeit.UUID()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.AuthClient.LogoutAll(ctx, &auth.LogoutAllRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.LogoutAll(ctx, &auth.LogoutAllRequest{RefreshToken: gofakeit.UUID()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// register registers a new random user and returns its id and credentials.
+func register(ctx context.Context, t *testing.T, st *suite.Suite) (uint64, *auth.Credentials) {
+	t.Helper()
+
+	creds := &auth.Credentials{
+		Email:    gofakeit.Email(),
+		Password: gofakeit.Password(true, true, true, true, false, passDefaultLen),
+	}
+
+	regResp, err := st.AuthClient.Register(
+		ctx, &auth.RegisterRequest{
+			Creds: creds,
+			Profile: &auth.BriefProfile{
+				FirstName:   gofakeit.FirstName(),
+				DateOfBirth: timestamppb.New(gofakeit.DateRange(time.Now().AddDate(-90, 0, 0), time.Now().AddDate(-10, 0, 0))),
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	return regResp.GetUserId(), creds
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/internal/notifier"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	resetTokenTimeout      = 5 * time.Second
+	resetTokenPollInterval = 10 * time.Millisecond
+)
+
+func TestPasswordReset(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+
+	first, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	second, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	token := requestPasswordReset(ctx, t, st, creds.GetEmail())
+
+	newPassword := gofakeit.Password(true, true, true, true, false, passDefaultLen)
+
+	_, err = st.AuthClient.ResetPassword(ctx, &auth.ResetPasswordRequest{Token: token, Password: newPassword})
+	require.NoError(t, err)
+
+	// All the sessions of the user are revoked
+	for _, login := range []*auth.LoginResponse{first, second} {
+		_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: login.GetRefreshToken()})
+		require.Error(t, err)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		info := introspect(ctx, t, st, login.GetAccessToken())
+		assert.False(t, info.GetActive())
+	}
+
+	// The old password does not work anymore
+	_, err = st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	login, err := st.AuthClient.Login(
+		ctx, &auth.LoginRequest{
+			Creds: &auth.Credentials{Email: creds.GetEmail(), Password: newPassword},
+		},
+	)
+	require.NoError(t, err)
+
+	info := introspect(ctx, t, st, login.GetAccessToken())
+	assert.Equal(t, userId, info.GetUserId())
+
+	// The token is used only once
+	_, err = st.AuthClient.ResetPassword(
+		ctx, &auth.ResetPasswordRequest{
+			Token:    token,
+			Password: gofakeit.Password(true, true, true, true, false, passDefaultLen),
+		},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPasswordReset_LastTokenOnly(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	previous := requestPasswordReset(ctx, t, st, creds.GetEmail())
+	last := requestPasswordReset(ctx, t, st, creds.GetEmail())
+	require.NotEqual(t, previous, last)
+
+	password := gofakeit.Password(true, true, true, true, false, passDefaultLen)
+
+	_, err := st.AuthClient.ResetPassword(ctx, &auth.ResetPasswordRequest{Token: previous, Password: password})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.ResetPassword(ctx, &auth.ResetPasswordRequest{Token: last, Password: password})
+	require.NoError(t, err)
+}
+
+func TestPasswordReset_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	// The unknown email gets the same response, but nothing is sent to it
+	email := gofakeit.Email()
+
+	_, err := st.AuthClient.RequestPasswordReset(ctx, &auth.RequestPasswordResetRequest{Email: email})
+	require.NoError(t, err)
+	assert.Empty(t, sentResetTokens(t, st, email))
+
+	_, err = st.AuthClient.RequestPasswordReset(ctx, &auth.RequestPasswordResetRequest{Email: "not-an-email"})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, creds := register(ctx, t, st)
+	token := requestPasswordReset(ctx, t, st, creds.GetEmail())
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+	}{
+		{
+			name:     "Empty token",
+			token:    "",
+			password: gofakeit.Password(true, true, true, true, false, passDefaultLen),
+		},
+		{
+			name:     "Unknown token",
+			token:    gofakeit.UUID(),
+			password: gofakeit.Password(true, true, true, true, false, passDefaultLen),
+		},
+		{
+			name:     "Empty password",
+			token:    token,
+			password: "",
+		},
+		{
+			name:     "Too short password",
+			token:    token,
+			password: gofakeit.Password(true, true, true, true, false, 4),
+		},
+		{
+			name:     "Too long password",
+			token:    token,
+			password: gofakeit.Password(true, true, true, true, false, 64),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := st.AuthClient.ResetPassword(
+					ctx, &auth.ResetPasswordRequest{Token: tt.token, Password: tt.password},
+				)
+				require.Error(t, err)
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		)
+	}
+
+	// The token is not used by the rejected requests
+	_, err = st.AuthClient.ResetPassword(
+		ctx, &auth.ResetPasswordRequest{
+			Token:    token,
+			Password: gofakeit.Password(true, true, true, true, false, passDefaultLen),
+		},
+	)
+	require.NoError(t, err)
+}
+
+// requestPasswordReset requests the password reset for the email and returns the token sent to it.
+// The token is sent after the response, so the outbox is polled until it comes.
+func requestPasswordReset(ctx context.Context, t *testing.T, st *suite.Suite, email string) string {
+	t.Helper()
+
+	sent := len(sentResetTokens(t, st, email))
+
+	_, err := st.AuthClient.RequestPasswordReset(ctx, &auth.RequestPasswordResetRequest{Email: email})
+	require.NoError(t, err)
+
+	var tokens []string
+
+	require.Eventually(
+		t, func() bool {
+			tokens = sentResetTokens(t, st, email)
+
+			return len(tokens) > sent
+		},
+		resetTokenTimeout, resetTokenPollInterval, "password reset token is expected to be sent",
+	)
+
+	return tokens[len(tokens)-1]
+}
+
+// sentResetTokens reads the password reset tokens sent to the email from the outbox of the notifier,
+// the last sent token is the last one.
+func sentResetTokens(t *testing.T, st *suite.Suite, email string) []string {
+	t.Helper()
+
+	f, err := os.Open(st.Cfg.Notifier.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var tokens []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg notifier.Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+
+		if msg.Type == notifier.TypePasswordReset && msg.Email == email {
+			tokens = append(tokens, msg.Token)
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	return tokens
+}
@@ -1,11 +1,12 @@
 package tests
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 	myjwt "github.com/puregrade-group/sso/pkg/jwt"
 	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
 	"github.com/puregrade-group/sso/tests/suite"
@@ -15,8 +16,6 @@ import (
 )
 
 const (
-	accessTokenSecret = "secret"
-
 	passDefaultLen = 10
 	passMinLen     = 8
 	passMaxlen     = 36
@@ -36,7 +35,7 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 			},
 			Profile: &auth.BriefProfile{
 				FirstName:   "first_name",
-				DateOfBirth: timestamppb.New(gofakeit.Date()),
+				DateOfBirth: timestamppb.New(gofakeit.DateRange(time.Now().AddDate(-90, 0, 0), time.Now().AddDate(-10, 0, 0))),
 			},
 		},
 	)
@@ -58,11 +57,7 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	token := loginResp.GetAccessToken()
 	require.NotEmpty(t, token)
 
-	parsedToken, err := jwt.ParseWithClaims(
-		token, &myjwt.DefaultClaims{}, func(*jwt.Token) (interface{}, error) {
-			return []byte(accessTokenSecret), nil
-		},
-	)
+	parsedToken, err := jwt.ParseWithClaims(token, &myjwt.DefaultClaims{}, verificationKey(ctx, t, st))
 	require.NoError(t, err)
 
 	claims, ok := parsedToken.Claims.(*myjwt.DefaultClaims)
@@ -70,9 +65,18 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 
 	assert.Equal(t, regResp.GetUserId(), claims.UID)
 
+	// The registered claims are set for the off-the-shelf middleware
+	assert.Equal(t, st.Cfg.JWT.Issuer, claims.Issuer)
+	assert.Equal(t, st.Cfg.JWT.Audience, []string(claims.Audience))
+	assert.Equal(t, strconv.FormatUint(regResp.GetUserId(), 10), claims.Subject)
+	assert.NotEmpty(t, claims.ID)
+	assert.NotZero(t, claims.SessionId)
+
 	const deltaSeconds = 1
 
-	assert.InDelta(t, loginTime.Add(st.Cfg.AccessTokenTTL).Unix(), claims.ExpiresAt, deltaSeconds)
+	assert.InDelta(t, loginTime.Add(st.Cfg.AccessTokenTTL).Unix(), claims.ExpiresAt.Unix(), deltaSeconds)
+	assert.InDelta(t, loginTime.Unix(), claims.IssuedAt.Unix(), deltaSeconds)
+	assert.InDelta(t, loginTime.Unix(), claims.NotBefore.Unix(), deltaSeconds)
 }
 
 func TestRegisterLogin_DuplicatedRegistration(t *testing.T) {
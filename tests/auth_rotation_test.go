@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRotation_ConcurrentRefresh(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	refreshResp, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	// The same client presents the rotated token within the grace window
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	// The session stays alive
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: refreshResp.GetRefreshToken()})
+	require.NoError(t, err)
+}
+
+func TestRotation_ReuseRevokesFamily(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, DeviceName: "laptop"})
+	require.NoError(t, err)
+
+	otherResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, DeviceName: "phone"})
+	require.NoError(t, err)
+
+	refreshResp, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	time.Sleep(st.Cfg.RefreshTokenReuseGrace + 100*time.Millisecond)
+
+	// The rotated token is presented again after the grace window
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The newest token of the family is revoked too
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: refreshResp.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Other sessions of the user are not affected
+	listResp, err := st.AuthClient.ListSessions(ctx, &auth.ListSessionsRequest{RefreshToken: otherResp.GetRefreshToken()})
+	require.NoError(t, err)
+	require.Len(t, listResp.GetSessions(), 1)
+	assert.Equal(t, "phone", listResp.GetSessions()[0].GetDeviceName())
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopes_Login(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+	granted, roleId := grantPermission(ctx, t, st, userId)
+	notGranted, _ := grantPermission(ctx, t, st, 0)
+
+	login, err := st.AuthClient.Login(
+		ctx, &auth.LoginRequest{
+			Creds:  creds,
+			Scopes: []string{notGranted, granted, "unknown:scope", "not-a-permission", granted},
+		},
+	)
+	require.NoError(t, err)
+
+	// Only the permissions of the roles of the user are put in the token
+	assert.Equal(t, granted, exchangedClaims(ctx, t, st, login.GetAccessToken()).Scope)
+
+	info := introspect(ctx, t, st, login.GetAccessToken())
+	assert.Equal(t, []string{granted}, info.GetScopes())
+
+	// The token without the requested scopes carries none
+	plain, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+	assert.Empty(t, exchangedClaims(ctx, t, st, plain.GetAccessToken()).Scope)
+
+	refreshed, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: login.GetRefreshToken()})
+	require.NoError(t, err)
+	assert.Equal(t, granted, exchangedClaims(ctx, t, st, refreshed.GetAccessToken()).Scope)
+
+	// The permission the user has lost is not put in the refreshed token
+	_, err = st.RolesClient.Remove(
+		ctx, &acs.RemoveRoleRequest{
+			RequesterToken: adminToken(ctx, t, st),
+			UserId:         userId,
+			RoleId:         roleId,
+		},
+	)
+	require.NoError(t, err)
+
+	refreshed, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: refreshed.GetRefreshToken()})
+	require.NoError(t, err)
+	assert.Empty(t, exchangedClaims(ctx, t, st, refreshed.GetAccessToken()).Scope)
+}
+
+func TestScopes_AuthorizationCode(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+	granted, _ := grantPermission(ctx, t, st, userId)
+	notGranted, _ := grantPermission(ctx, t, st, 0)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code"},
+		firstParty: true,
+	})
+	verifier, challenge := pkcePair(t)
+
+	params := withParam(
+		authorizeParams(appId, challenge),
+		"scope", strings.Join([]string{"openid", granted, notGranted}, " "),
+	)
+
+	code := authorize(ctx, t, st, params, creds)
+	tokens := exchangeCode(
+		ctx, t, st,
+		exchangeForm(strconv.Itoa(int(appId)), secret, code, redirectURI, verifier),
+		http.StatusOK,
+	)
+	assert.Equal(t, "openid "+granted, tokens.Scope)
+	assert.Equal(t, "openid "+granted, exchangedClaims(ctx, t, st, tokens.AccessToken).Scope)
+}
+
+// grantPermission creates new permission and the role with it and returns the scope of the permission
+// and the id of the role. The role is granted to the user unless the user id is 0.
+func grantPermission(ctx context.Context, t *testing.T, st *suite.Suite, userId uint64) (string, int32) {
+	t.Helper()
+
+	token := adminToken(ctx, t, st)
+	resource, action := strings.ToLower(gofakeit.LetterN(12)), strings.ToLower(gofakeit.LetterN(6))
+
+	perm, err := st.PermsClient.Create(
+		ctx, &acs.CreatePermissionRequest{
+			RequesterToken: token,
+			Permission:     &acs.Permission{Resource: resource, Action: action},
+		},
+	)
+	require.NoError(t, err)
+
+	role, err := st.RolesClient.Create(
+		ctx, &acs.CreateRoleRequest{
+			RequesterToken: token,
+			Role:           &acs.Role{Name: gofakeit.UUID()},
+		},
+	)
+	require.NoError(t, err)
+
+	_, err = st.PermsClient.Add(
+		ctx, &acs.AddPermissionRequest{
+			RequesterToken: token,
+			RoleId:         role.GetRoleId(),
+			PermissionId:   perm.GetPermissionId(),
+		},
+	)
+	require.NoError(t, err)
+
+	if userId != 0 {
+		_, err = st.RolesClient.Add(
+			ctx, &acs.AddRoleRequest{
+				RequesterToken: token,
+				UserId:         userId,
+				RoleId:         role.GetRoleId(),
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	return resource + ":" + action, role.GetRoleId()
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSessions_MultipleDevices_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	laptop, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, DeviceName: "laptop"})
+	require.NoError(t, err)
+
+	phone, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, DeviceName: "phone"})
+	require.NoError(t, err)
+
+	// Login on the phone keeps the session on the laptop alive
+	laptopRefresh, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: laptop.GetRefreshToken()})
+	require.NoError(t, err)
+
+	listResp, err := st.AuthClient.ListSessions(ctx, &auth.ListSessionsRequest{RefreshToken: phone.GetRefreshToken()})
+	require.NoError(t, err)
+	require.Len(t, listResp.GetSessions(), 2)
+
+	sessions := make(map[string]*auth.Session)
+	for _, s := range listResp.GetSessions() {
+		sessions[s.GetDeviceName()] = s
+	}
+
+	require.Contains(t, sessions, "laptop")
+	require.Contains(t, sessions, "phone")
+	assert.True(t, sessions["phone"].GetCurrent())
+	assert.False(t, sessions["laptop"].GetCurrent())
+	assert.NotEmpty(t, sessions["laptop"].GetUserAgent())
+	assert.NotEmpty(t, sessions["laptop"].GetIp())
+	assert.NotEqual(t, sessions["laptop"].GetSessionId(), sessions["phone"].GetSessionId())
+
+	// The phone terminates the session on the laptop
+	_, err = st.AuthClient.RevokeSession(
+		ctx, &auth.RevokeSessionRequest{
+			RefreshToken: phone.GetRefreshToken(),
+			SessionId:    sessions["laptop"].GetSessionId(),
+		},
+	)
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: laptopRefresh.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	listResp, err = st.AuthClient.ListSessions(ctx, &auth.ListSessionsRequest{RefreshToken: phone.GetRefreshToken()})
+	require.NoError(t, err)
+	require.Len(t, listResp.GetSessions(), 1)
+	assert.Equal(t, sessions["phone"].GetSessionId(), listResp.GetSessions()[0].GetSessionId())
+}
+
+func TestSessions_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	login, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	// The session of another user can not be revoked
+	_, otherCreds := register(ctx, t, st)
+
+	otherLogin, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: otherCreds})
+	require.NoError(t, err)
+
+	otherList, err := st.AuthClient.ListSessions(ctx, &auth.ListSessionsRequest{RefreshToken: otherLogin.GetRefreshToken()})
+	require.NoError(t, err)
+	require.Len(t, otherList.GetSessions(), 1)
+
+	tests := []struct {
+		name         string
+		req          *auth.RevokeSessionRequest
+		expectedCode codes.Code
+	}{
+		{
+			name:         "Revoke without refresh token",
+			req:          &auth.RevokeSessionRequest{SessionId: 1},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Revoke without session id",
+			req:          &auth.RevokeSessionRequest{RefreshToken: login.GetRefreshToken()},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Revoke with unknown refresh token",
+			req:          &auth.RevokeSessionRequest{RefreshToken: gofakeit.UUID(), SessionId: 1},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "Revoke session of another user",
+			req: &auth.RevokeSessionRequest{
+				RefreshToken: login.GetRefreshToken(),
+				SessionId:    otherList.GetSessions()[0].GetSessionId(),
+			},
+			expectedCode: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := st.AuthClient.RevokeSession(ctx, tt.req)
+
+				require.Error(t, err)
+				require.Equal(t, tt.expectedCode, status.Code(err))
+			},
+		)
+	}
+
+	_, err = st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: otherLogin.GetRefreshToken()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(
+		ctx, &auth.LoginRequest{
+			Creds:      creds,
+			DeviceName: gofakeit.LetterN(65),
+		},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
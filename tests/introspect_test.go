@@ -0,0 +1,359 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const introspectPath = "/introspect"
+
+func TestIntrospect_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	loginTime := time.Now()
+
+	accessInfo := introspect(ctx, t, st, loginResp.GetAccessToken())
+	assert.True(t, accessInfo.GetActive())
+	assert.Equal(t, "access_token", accessInfo.GetTokenType())
+	assert.Equal(t, userId, accessInfo.GetUserId())
+	assert.Zero(t, accessInfo.GetAppId())
+	assert.Equal(t, st.Cfg.JWT.Issuer, accessInfo.GetIssuer())
+	assert.Equal(t, st.Cfg.JWT.Audience, accessInfo.GetAudience())
+	assert.InDelta(t, loginTime.Add(st.Cfg.AccessTokenTTL).Unix(), accessInfo.GetExpiresAt().GetSeconds(), 1)
+
+	// The hint only changes the order of the lookup
+	refreshInfo, err := st.AuthClient.Introspect(
+		ctx, &auth.IntrospectRequest{
+			Token:          loginResp.GetRefreshToken(),
+			TokenTypeHint:  "refresh_token",
+			RequesterToken: adminToken(ctx, t, st),
+		},
+	)
+	require.NoError(t, err)
+	assert.True(t, refreshInfo.GetActive())
+	assert.Equal(t, "refresh_token", refreshInfo.GetTokenType())
+	assert.Equal(t, userId, refreshInfo.GetUserId())
+	assert.NotZero(t, refreshInfo.GetSessionId())
+	assert.InDelta(t, loginTime.Add(st.Cfg.RefreshTokenTTL).Unix(), refreshInfo.GetExpiresAt().GetSeconds(), 1)
+
+	refreshResp, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	// The rotated token is inactive, but the introspection is not taken for its reuse
+	usedInfo := introspect(ctx, t, st, loginResp.GetRefreshToken())
+	assert.False(t, usedInfo.GetActive())
+	assert.Zero(t, usedInfo.GetUserId())
+
+	newInfo := introspect(ctx, t, st, refreshResp.GetRefreshToken())
+	assert.True(t, newInfo.GetActive())
+	assert.Equal(t, refreshInfo.GetSessionId(), newInfo.GetSessionId())
+
+	_, err = st.AuthClient.Logout(ctx, &auth.LogoutRequest{RefreshToken: refreshResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	revokedInfo := introspect(ctx, t, st, refreshResp.GetRefreshToken())
+	assert.False(t, revokedInfo.GetActive())
+}
+
+func TestIntrospect_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AuthClient.Introspect(ctx, &auth.IntrospectRequest{RequesterToken: adminToken(ctx, t, st)})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	for _, token := range []string{"not-a-token", "a.b.c"} {
+		info := introspect(ctx, t, st, token)
+		assert.False(t, info.GetActive())
+	}
+}
+
+func TestIntrospect_Requester(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	token := loginResp.GetAccessToken()
+
+	// The resource server introspects the tokens with the token of its app granted the permission
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes:    []string{"client_credentials"},
+		permissionIds: []int32{basePermissionId(ctx, t, st, "token", "introspect")},
+	})
+	appTokens := postToken(ctx, t, st, strconv.Itoa(int(appId)), secret, clientCredentialsForm, http.StatusOK)
+
+	info, err := st.AuthClient.Introspect(
+		ctx, &auth.IntrospectRequest{Token: token, RequesterToken: appTokens.AccessToken},
+	)
+	require.NoError(t, err)
+	assert.True(t, info.GetActive())
+
+	otherAppId, otherSecret := createApp(ctx, t, st, testApp{grantTypes: []string{"client_credentials"}})
+	otherTokens := postToken(
+		ctx, t, st, strconv.Itoa(int(otherAppId)), otherSecret, clientCredentialsForm, http.StatusOK,
+	)
+
+	tests := []struct {
+		name           string
+		requesterToken string
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "Without requester token",
+			requesterToken: "",
+			expectedCode:   codes.Unauthenticated,
+		},
+		{
+			name:           "Invalid requester token",
+			requesterToken: "not-a-token",
+			expectedCode:   codes.Unauthenticated,
+		},
+		{
+			name:           "User is not granted the permission",
+			requesterToken: token,
+			expectedCode:   codes.PermissionDenied,
+		},
+		{
+			name:           "App is not granted the permission",
+			requesterToken: otherTokens.AccessToken,
+			expectedCode:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := st.AuthClient.Introspect(
+					ctx, &auth.IntrospectRequest{Token: token, RequesterToken: tt.requesterToken},
+				)
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+			},
+		)
+	}
+}
+
+func TestIntrospect_HTTP(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	// The resource server authenticates with the credentials of its app
+	appId, secret := createApp(ctx, t, st, testApp{
+		permissionIds: []int32{basePermissionId(ctx, t, st, "token", "introspect")},
+	})
+	clientId := strconv.Itoa(int(appId))
+
+	tests := []struct {
+		name         string
+		form         url.Values
+		expectedCode int
+		expected     map[string]interface{}
+	}{
+		{
+			name:         "Access token",
+			form:         url.Values{"token": {loginResp.GetAccessToken()}},
+			expectedCode: http.StatusOK,
+			expected: map[string]interface{}{
+				"active":     true,
+				"token_type": "Bearer",
+				"token_use":  "access_token",
+				"sub":        strconv.FormatUint(userId, 10),
+			},
+		},
+		{
+			name: "Refresh token",
+			form: url.Values{
+				"token":           {loginResp.GetRefreshToken()},
+				"token_type_hint": {"refresh_token"},
+			},
+			expectedCode: http.StatusOK,
+			expected: map[string]interface{}{
+				"active":    true,
+				"token_use": "refresh_token",
+				"sub":       strconv.FormatUint(userId, 10),
+			},
+		},
+		{
+			name:         "Unknown token",
+			form:         url.Values{"token": {"not-a-token"}},
+			expectedCode: http.StatusOK,
+			expected:     map[string]interface{}{"active": false},
+		},
+		{
+			name:         "Without token",
+			form:         url.Values{},
+			expectedCode: http.StatusBadRequest,
+			expected:     map[string]interface{}{"error": "invalid_request"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				resp, err := st.HTTPPostFormBasicAuth(ctx, introspectPath, tt.form, clientId, secret)
+				require.NoError(t, err)
+				defer func() { _ = resp.Body.Close() }()
+
+				require.Equal(t, tt.expectedCode, resp.StatusCode)
+				assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+				for k, v := range tt.expected {
+					assert.Equal(t, v, body[k], k)
+				}
+
+				if active, _ := body["active"].(bool); !active {
+					assert.NotContains(t, body, "sub")
+				}
+			},
+		)
+	}
+
+	resp, err := st.HTTPGet(ctx, introspectPath)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestIntrospect_HTTPRequester(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	form := url.Values{"token": {loginResp.GetAccessToken()}}
+
+	appId, secret := createApp(ctx, t, st, testApp{
+		permissionIds: []int32{basePermissionId(ctx, t, st, "token", "introspect")},
+	})
+	clientId := strconv.Itoa(int(appId))
+	otherAppId, otherSecret := createApp(ctx, t, st, testApp{})
+
+	// The credentials of the app may be sent as the form parameters
+	resp, err := st.HTTPPostForm(
+		ctx, introspectPath, withParam(withParam(form, "client_id", clientId), "client_secret", secret),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+
+	tests := []struct {
+		name          string
+		post          func() (*http.Response, error)
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:          "Without credentials",
+			post:          func() (*http.Response, error) { return st.HTTPPostForm(ctx, introspectPath, form) },
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: "invalid_client",
+		},
+		{
+			name: "Wrong secret",
+			post: func() (*http.Response, error) {
+				return st.HTTPPostFormBasicAuth(ctx, introspectPath, form, clientId, gofakeit.UUID())
+			},
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: "invalid_client",
+		},
+		{
+			name: "App is not granted the permission",
+			post: func() (*http.Response, error) {
+				return st.HTTPPostFormBasicAuth(ctx, introspectPath, form, strconv.Itoa(int(otherAppId)), otherSecret)
+			},
+			expectedCode:  http.StatusForbidden,
+			expectedError: "insufficient_scope",
+		},
+		{
+			name: "Invalid bearer token",
+			post: func() (*http.Response, error) {
+				return st.HTTPPostFormBearer(ctx, introspectPath, form, "not-a-token")
+			},
+			expectedCode:  http.StatusUnauthorized,
+			expectedError: "invalid_token",
+		},
+		{
+			name: "User is not granted the permission",
+			post: func() (*http.Response, error) {
+				return st.HTTPPostFormBearer(ctx, introspectPath, form, loginResp.GetAccessToken())
+			},
+			expectedCode:  http.StatusForbidden,
+			expectedError: "insufficient_scope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				resp, err := tt.post()
+				require.NoError(t, err)
+				defer func() { _ = resp.Body.Close() }()
+
+				require.Equal(t, tt.expectedCode, resp.StatusCode)
+
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.Equal(t, tt.expectedError, body["error"])
+				assert.NotContains(t, body, "active")
+			},
+		)
+	}
+}
+
+// introspect introspects the token with the Introspect RPC on behalf of the admin,
+// whose role is granted the token:introspect permission.
+func introspect(ctx context.Context, t *testing.T, st *suite.Suite, token string) *auth.IntrospectResponse {
+	t.Helper()
+
+	info, err := st.AuthClient.Introspect(
+		ctx, &auth.IntrospectRequest{Token: token, RequesterToken: adminToken(ctx, t, st)},
+	)
+	require.NoError(t, err)
+
+	return info
+}
+
+// introspectHTTP introspects the token at the introspection endpoint with the bearer token of the admin.
+func introspectHTTP(ctx context.Context, t *testing.T, st *suite.Suite, token string) map[string]interface{} {
+	t.Helper()
+
+	resp, err := st.HTTPPostFormBearer(ctx, introspectPath, url.Values{"token": {token}}, adminToken(ctx, t, st))
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	return body
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	myjwt "github.com/puregrade-group/sso/pkg/jwt"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jwksPath = "/.well-known/jwks.json"
+
+func TestJWKS_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	if st.Cfg.JWT.Algorithm == myjwt.AlgHS256 {
+		t.Skip("the shared secret is never published")
+	}
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	jwks := fetchJWKS(ctx, t, st)
+	require.NotEmpty(t, jwks.Keys)
+
+	jwk, ok := jwks.Find(tokenKeyId(t, loginResp.GetAccessToken()))
+	require.True(t, ok)
+	assert.Equal(t, st.Cfg.JWT.Algorithm, jwk.Alg)
+	assert.Equal(t, "sig", jwk.Use)
+
+	// The resource server verifies the token with the published key only
+	token, err := jwt.ParseWithClaims(
+		loginResp.GetAccessToken(), &myjwt.DefaultClaims{}, func(token *jwt.Token) (interface{}, error) {
+			assert.Equal(t, jwk.Alg, token.Method.Alg())
+			assert.Equal(t, jwk.Kid, token.Header["kid"])
+
+			return jwk.PublicKey()
+		},
+	)
+	require.NoError(t, err)
+	assert.True(t, token.Valid)
+}
+
+func TestJWKS_MethodNotAllowed(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	// The server must be up before the POST request is sent
+	resp, err := st.HTTPGet(ctx, jwksPath)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resp.Request.URL.String(), nil)
+	require.NoError(t, err)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func fetchJWKS(ctx context.Context, t *testing.T, st *suite.Suite) myjwt.JWKS {
+	t.Helper()
+
+	resp, err := st.HTTPGet(ctx, jwksPath)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var jwks myjwt.JWKS
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+
+	return jwks
+}
+
+// tokenKeyId returns the "kid" header of the token without verifying it.
+func tokenKeyId(t *testing.T, token string) string {
+	t.Helper()
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &myjwt.DefaultClaims{})
+	require.NoError(t, err)
+
+	kid, _ := parsed.Header["kid"].(string)
+	require.NotEmpty(t, kid)
+
+	return kid
+}
+
+// verificationKey returns the function that finds the key of the access token the way the resource servers do.
+func verificationKey(ctx context.Context, t *testing.T, st *suite.Suite) jwt.Keyfunc {
+	t.Helper()
+
+	if st.Cfg.JWT.Algorithm == myjwt.AlgHS256 {
+		return func(*jwt.Token) (interface{}, error) {
+			return []byte(st.Cfg.AccessTokenSecret), nil
+		}
+	}
+
+	jwks := fetchJWKS(ctx, t, st)
+
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		jwk, ok := jwks.Find(kid)
+		if !ok {
+			return nil, myjwt.ErrUnknownKey
+		}
+
+		return jwk.PublicKey()
+	}
+}
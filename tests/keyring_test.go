@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	myjwt "github.com/puregrade-group/sso/pkg/jwt"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRing_ScheduledRotation(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	if st.Cfg.JWT.RotationPeriod == 0 {
+		t.Skip("scheduled rotation is disabled")
+	}
+
+	userId, creds := register(ctx, t, st)
+
+	oldResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	oldKid := tokenKeyId(t, oldResp.GetAccessToken())
+
+	// Sooner or later the tokens are signed with the new key
+	var newKid string
+
+	require.Eventually(
+		t, func() bool {
+			resp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+			require.NoError(t, err)
+
+			newKid = tokenKeyId(t, resp.GetAccessToken())
+
+			return newKid != oldKid
+		},
+		st.Cfg.JWT.RotationPeriod+st.Cfg.JWT.PromotionDelay+2*st.Cfg.JWT.RefreshInterval+time.Second,
+		st.Cfg.JWT.RefreshInterval,
+	)
+
+	// The retired key keeps verifying the tokens it has signed
+	checkResp, err := st.PermsClient.CheckPermissions(
+		ctx, &acs.CheckPermissionsRequest{
+			RequesterToken: oldResp.GetAccessToken(),
+			UserId:         userId,
+			Resource:       "permission",
+			Action:         "create",
+		},
+	)
+	require.NoError(t, err)
+	assert.False(t, checkResp.GetOk())
+
+	if st.Cfg.JWT.Algorithm == myjwt.AlgHS256 {
+		return // The shared secret is never published
+	}
+
+	jwks := fetchJWKS(ctx, t, st)
+
+	_, ok := jwks.Find(oldKid)
+	assert.True(t, ok)
+
+	_, ok = jwks.Find(newKid)
+	assert.True(t, ok)
+}
@@ -0,0 +1 @@
+DELETE FROM apps WHERE id = 1;
@@ -1,3 +1,7 @@
-INSERT INTO apps (id, name, secret)
-VALUES (1, 'test', 'test-secret')
-ON CONFLICT DO NOTHING;
\ No newline at end of file
+INSERT INTO apps (id, name)
+VALUES (1, 'test')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO app_grant_types (app_id, grant_type)
+VALUES (1, 'password'), (1, 'refresh_token')
+ON CONFLICT DO NOTHING;
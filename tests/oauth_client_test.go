@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var clientCredentialsForm = url.Values{"grant_type": {"client_credentials"}}
+
+func TestOAuth_ClientCredentials(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	token := adminToken(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes:    []string{"client_credentials"},
+		permissionIds: []int32{basePermissionId(ctx, t, st, "app", "read")},
+	})
+	clientId := strconv.Itoa(int(appId))
+
+	tokens := postToken(ctx, t, st, clientId, secret, clientCredentialsForm, http.StatusOK)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(st.Cfg.AccessTokenTTL.Seconds()), tokens.ExpiresIn)
+	// The app obtains the new access token with its credentials, so no refresh token is issued
+	assert.Empty(t, tokens.RefreshToken)
+
+	// The subject of the token is the app itself
+	introspection := introspectHTTP(ctx, t, st, tokens.AccessToken)
+	assert.Equal(t, true, introspection["active"])
+	assert.Equal(t, clientId, introspection["sub"])
+	assert.Equal(t, clientId, introspection["client_id"])
+	assert.NotContains(t, introspection, "sid")
+
+	grpcIntrospection := introspect(ctx, t, st, tokens.AccessToken)
+	assert.Zero(t, grpcIntrospection.GetUserId())
+	assert.Equal(t, appId, grpcIntrospection.GetAppId())
+
+	// ACS checks the permissions of the app
+	getResp, err := st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: tokens.AccessToken, AppId: appId})
+	require.NoError(t, err)
+	assert.Equal(t, appId, getResp.GetApp().GetAppId())
+
+	_, err = st.AppsClient.Delete(ctx, &acs.DeleteAppRequest{RequesterToken: tokens.AccessToken, AppId: appId})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// The backend jobs check the permissions of the users with the token of the app
+	userId, _ := register(ctx, t, st)
+
+	checkResp, err := st.PermsClient.CheckPermissions(
+		ctx, &acs.CheckPermissionsRequest{
+			RequesterToken: tokens.AccessToken,
+			UserId:         userId,
+			Resource:       "app",
+			Action:         "read",
+		},
+	)
+	require.NoError(t, err)
+	assert.False(t, checkResp.GetOk())
+
+	// The tokens of the deleted app are not valid anymore
+	_, err = st.AppsClient.Delete(ctx, &acs.DeleteAppRequest{RequesterToken: token, AppId: appId})
+	require.NoError(t, err)
+
+	_, err = st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: tokens.AccessToken, AppId: appId})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	introspection = introspectHTTP(ctx, t, st, tokens.AccessToken)
+	assert.Equal(t, false, introspection["active"])
+}
+
+func TestOAuth_ClientCredentialsFailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	appId, secret := createApp(ctx, t, st, testApp{grantTypes: []string{"client_credentials"}})
+	otherAppId, otherSecret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code"},
+		firstParty: true,
+	})
+
+	tests := []struct {
+		name           string
+		clientId       string
+		secret         string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Public client",
+			clientId:       strconv.Itoa(int(appId)),
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name:           "Wrong client secret",
+			clientId:       strconv.Itoa(int(appId)),
+			secret:         gofakeit.UUID(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name:           "Grant is not allowed",
+			clientId:       strconv.Itoa(int(otherAppId)),
+			secret:         otherSecret,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unauthorized_client",
+		},
+		{
+			name:           "Unknown client",
+			clientId:       strconv.Itoa(int(gofakeit.Int32())),
+			secret:         secret,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				resp := postToken(ctx, t, st, tt.clientId, tt.secret, clientCredentialsForm, tt.expectedStatus)
+				assert.Equal(t, tt.expectedError, resp.Error)
+				assert.Empty(t, resp.AccessToken)
+			},
+		)
+	}
+}
+
+// basePermissionId returns the id of the base permission the admin role is granted.
+func basePermissionId(ctx context.Context, t *testing.T, st *suite.Suite, resource, action string) int32 {
+	t.Helper()
+
+	token := adminToken(ctx, t, st)
+
+	introspection := introspect(ctx, t, st, token)
+
+	rolesResp, err := st.RolesClient.GetUserRoles(
+		ctx, &acs.GetUserRolesRequest{
+			RequesterToken: token,
+			UserId:         introspection.GetUserId(),
+		},
+	)
+	require.NoError(t, err)
+
+	for _, role := range rolesResp.GetRoles() {
+		for _, p := range role.GetPermissions() {
+			if p.GetResource() == resource && p.GetAction() == action {
+				return p.GetPermissionId()
+			}
+		}
+	}
+
+	require.FailNow(t, "admin is not granted the permission", resource+":"+action)
+
+	return 0
+}
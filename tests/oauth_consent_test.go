@@ -0,0 +1,274 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	consentCodeRe = regexp.MustCompile(`name="consent_code" value="([^"]+)"`)
+	csrfTokenRe   = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+)
+
+func TestOAuth_Consent(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code", "refresh_token"},
+	})
+	clientId := strconv.Itoa(int(appId))
+
+	app, err := st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: adminToken(ctx, t, st), AppId: appId})
+	require.NoError(t, err)
+	assert.False(t, app.GetApp().GetFirstParty())
+
+	// The third-party app is authorized only after the user consents to its scopes
+	verifier, challenge := pkcePair(t)
+	params := withParam(authorizeParams(appId, challenge), "scope", "openid profile")
+
+	consentCode, body := loginForConsent(ctx, t, st, params, creds)
+	assert.Contains(t, body, app.GetApp().GetName())
+	assert.Contains(t, body, "See your name and birthdate")
+	assert.NotContains(t, body, "See your email address")
+
+	// The pending code can not be exchanged
+	failed := exchangeCode(
+		ctx, t, st,
+		exchangeForm(clientId, secret, consentCode, redirectURI, verifier),
+		http.StatusBadRequest,
+	)
+	assert.Equal(t, "invalid_grant", failed.Error)
+
+	verifier, challenge = pkcePair(t)
+	params = withParam(authorizeParams(appId, challenge), "scope", "openid profile")
+
+	consentCode, _ = loginForConsent(ctx, t, st, params, creds)
+	location := consent(ctx, t, st, params, consentCode, "approve")
+	require.Empty(t, location.Query().Get("error"))
+	assert.Equal(t, params.Get("state"), location.Query().Get("state"))
+
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	tokens := exchangeCode(ctx, t, st, exchangeForm(clientId, secret, code, redirectURI, verifier), http.StatusOK)
+	assert.Equal(t, "openid profile", tokens.Scope)
+
+	// The consented scopes are not asked for again
+	verifier, challenge = pkcePair(t)
+	params = withParam(authorizeParams(appId, challenge), "scope", "profile")
+
+	code = authorize(ctx, t, st, params, creds)
+	exchangeCode(ctx, t, st, exchangeForm(clientId, secret, code, redirectURI, verifier), http.StatusOK)
+
+	// A new scope is asked for
+	_, challenge = pkcePair(t)
+	params = withParam(authorizeParams(appId, challenge), "scope", "openid email")
+
+	consentCode, body = loginForConsent(ctx, t, st, params, creds)
+	assert.Contains(t, body, "See your email address")
+
+	location = consent(ctx, t, st, params, consentCode, "approve")
+	require.NotEmpty(t, location.Query().Get("code"))
+
+	login, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	grants, err := st.AuthClient.ListGrants(ctx, &auth.ListGrantsRequest{RefreshToken: login.GetRefreshToken()})
+	require.NoError(t, err)
+	require.Len(t, grants.GetGrants(), 1)
+
+	grant := grants.GetGrants()[0]
+	assert.Equal(t, appId, grant.GetAppId())
+	assert.Equal(t, app.GetApp().GetName(), grant.GetAppName())
+	assert.ElementsMatch(t, []string{"openid", "profile", "email"}, grant.GetScopes())
+	assert.False(t, grant.GetUpdatedAt().AsTime().Before(grant.GetCreatedAt().AsTime()))
+
+	sessions, err := st.AuthClient.ListSessions(ctx, &auth.ListSessionsRequest{RefreshToken: login.GetRefreshToken()})
+	require.NoError(t, err)
+
+	var appSessions int
+	for _, session := range sessions.GetSessions() {
+		if session.GetAppId() == appId {
+			appSessions++
+		}
+	}
+	assert.Equal(t, 2, appSessions)
+
+	// Revoking the grant revokes the sessions of the user in the app
+	_, err = st.AuthClient.RevokeGrant(
+		ctx, &auth.RevokeGrantRequest{RefreshToken: login.GetRefreshToken(), AppId: appId},
+	)
+	require.NoError(t, err)
+
+	refreshed := exchangeCode(ctx, t, st, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {clientId},
+		"client_secret": {secret},
+		"refresh_token": {tokens.RefreshToken},
+	}, http.StatusBadRequest)
+	assert.Equal(t, "invalid_grant", refreshed.Error)
+
+	info := introspect(ctx, t, st, tokens.AccessToken)
+	assert.False(t, info.GetActive())
+
+	// The session of the user outside the app stays
+	info = introspect(ctx, t, st, login.GetAccessToken())
+	assert.True(t, info.GetActive())
+	assert.Equal(t, userId, info.GetUserId())
+
+	grants, err = st.AuthClient.ListGrants(ctx, &auth.ListGrantsRequest{RefreshToken: login.GetRefreshToken()})
+	require.NoError(t, err)
+	assert.Empty(t, grants.GetGrants())
+
+	// The consent is asked for again
+	_, challenge = pkcePair(t)
+	loginForConsent(ctx, t, st, withParam(authorizeParams(appId, challenge), "scope", "profile"), creds)
+}
+
+func TestOAuth_ConsentDenied(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{grantTypes: []string{"authorization_code"}})
+	verifier, challenge := pkcePair(t)
+	params := withParam(authorizeParams(appId, challenge), "scope", "email")
+
+	consentCode, _ := loginForConsent(ctx, t, st, params, creds)
+
+	location := consent(ctx, t, st, params, consentCode, "deny")
+	assert.Equal(t, "access_denied", location.Query().Get("error"))
+	assert.Equal(t, params.Get("state"), location.Query().Get("state"))
+	assert.Empty(t, location.Query().Get("code"))
+
+	// The denied code is deleted
+	failed := exchangeCode(
+		ctx, t, st,
+		exchangeForm(strconv.Itoa(int(appId)), secret, consentCode, redirectURI, verifier),
+		http.StatusBadRequest,
+	)
+	assert.Equal(t, "invalid_grant", failed.Error)
+
+	login, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	grants, err := st.AuthClient.ListGrants(ctx, &auth.ListGrantsRequest{RefreshToken: login.GetRefreshToken()})
+	require.NoError(t, err)
+	assert.Empty(t, grants.GetGrants())
+}
+
+func TestOAuth_ConsentFailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	appId, _ := createApp(ctx, t, st, testApp{grantTypes: []string{"authorization_code"}})
+	otherAppId, _ := createApp(ctx, t, st, testApp{grantTypes: []string{"authorization_code"}})
+	_, challenge := pkcePair(t)
+	params := authorizeParams(appId, challenge)
+
+	// The code can not be consented to for another app
+	consentCode, _ := loginForConsent(ctx, t, st, params, creds)
+
+	location := consent(ctx, t, st, authorizeParams(otherAppId, challenge), consentCode, "approve")
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+
+	// and is deleted then
+	location = consent(ctx, t, st, params, consentCode, "approve")
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+
+	location = consent(ctx, t, st, params, gofakeit.UUID(), "approve")
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+
+	// The code can be consented to only once
+	consentCode, _ = loginForConsent(ctx, t, st, params, creds)
+
+	location = consent(ctx, t, st, params, consentCode, "approve")
+	require.NotEmpty(t, location.Query().Get("code"))
+
+	location = consent(ctx, t, st, params, consentCode, "approve")
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+
+	login, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RevokeGrant(
+		ctx, &auth.RevokeGrantRequest{RefreshToken: login.GetRefreshToken(), AppId: otherAppId},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.AuthClient.RevokeGrant(ctx, &auth.RevokeGrantRequest{RefreshToken: login.GetRefreshToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.ListGrants(ctx, &auth.ListGrantsRequest{RefreshToken: gofakeit.UUID()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// loginForConsent posts the login form of the third-party app and returns the code the consent form carries
+// and the body of the form.
+func loginForConsent(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	params url.Values,
+	creds *auth.Credentials,
+) (string, string) {
+	t.Helper()
+
+	form := loginForm(ctx, t, st, params)
+	form.Set("email", creds.GetEmail())
+	form.Set("password", creds.GetPassword())
+
+	resp, err := st.HTTPPostForm(ctx, authorizePath, form)
+	require.NoError(t, err)
+
+	body := readBody(t, resp)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	match := consentCodeRe.FindStringSubmatch(body)
+	require.Len(t, match, 2, "consent form is expected")
+
+	return match[1], body
+}
+
+// consent posts the consent form with the decision and returns the URI the user agent is redirected to.
+func consent(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	params url.Values,
+	consentCode string,
+	decision string,
+) *url.URL {
+	t.Helper()
+
+	form := loginForm(ctx, t, st, params)
+	form.Set("consent_code", consentCode)
+	form.Set("consent", decision)
+
+	resp, err := st.HTTPPostForm(ctx, authorizePath, form)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, redirectURI, location.Scheme+"://"+location.Host+location.Path)
+
+	return location
+}
@@ -0,0 +1,360 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	deviceAuthorizationPath = "/device_authorization"
+	devicePath              = "/device"
+	deviceCodeGrant         = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+var reviewCodeRe = regexp.MustCompile(`name="review_code" value="([^"]+)"`)
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+	Error                   string `json:"error"`
+}
+
+func TestOAuth_DeviceFlow(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{deviceCodeGrant, "refresh_token"},
+		firstParty: true,
+	})
+	clientId := strconv.Itoa(int(appId))
+
+	code := authorizeDevice(
+		ctx, t, st,
+		url.Values{"client_id": {clientId}, "client_secret": {secret}, "scope": {"openid profile"}},
+		http.StatusOK,
+	)
+	require.NotEmpty(t, code.DeviceCode)
+	assert.Regexp(t, `^[B-DF-HJ-NP-TV-XZ]{4}-[B-DF-HJ-NP-TV-XZ]{4}$`, code.UserCode)
+	assert.Equal(t, strings.TrimSuffix(st.Cfg.JWT.Issuer, "/")+devicePath, code.VerificationURI)
+	assert.Equal(t, code.VerificationURI+"?user_code="+code.UserCode, code.VerificationURIComplete)
+	assert.Equal(t, int64(st.Cfg.DeviceCodeTTL.Seconds()), code.ExpiresIn)
+	assert.Equal(t, int64(st.Cfg.DeviceCodeInterval.Seconds()), code.Interval)
+
+	app, err := st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: adminToken(ctx, t, st), AppId: appId})
+	require.NoError(t, err)
+
+	// The verification URI shown by the device fills the user code in,
+	// nothing of the device is shown before the user signs in
+	resp, err := st.HTTPGet(ctx, devicePath+"?user_code="+code.UserCode)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+
+	body := readBody(t, resp)
+	assert.Contains(t, body, `value="`+code.UserCode+`"`)
+	assert.Contains(t, body, `name="password"`)
+	assert.NotContains(t, body, app.GetApp().GetName())
+
+	// The user sees the app and the scopes the device asks for once signed in,
+	// the code may be typed in lower case and without the dash
+	typed := strings.ToLower(strings.ReplaceAll(code.UserCode, "-", ""))
+	body = reviewDevice(ctx, t, st, typed, creds, http.StatusOK)
+	assert.Contains(t, body, app.GetApp().GetName())
+	assert.Contains(t, body, "Verify your identity")
+	assert.Contains(t, body, "See your name and birthdate")
+
+	match := reviewCodeRe.FindStringSubmatch(body)
+	require.Len(t, match, 2, "review code is expected")
+
+	form := deviceForm(ctx, t, st, typed)
+	form.Set("review_code", match[1])
+	assert.Contains(t, postDeviceForm(ctx, t, st, form, http.StatusOK), "Device connected")
+
+	tokens := pollDevice(ctx, t, st, clientId, secret, code.DeviceCode, http.StatusOK)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.NotEmpty(t, tokens.IDToken)
+	assert.Equal(t, "openid profile", tokens.Scope)
+
+	introspection := introspect(ctx, t, st, tokens.AccessToken)
+	assert.True(t, introspection.GetActive())
+	assert.Equal(t, appId, introspection.GetAppId())
+
+	// The device code is exchanged only once
+	resp2 := pollDevice(ctx, t, st, clientId, secret, code.DeviceCode, http.StatusBadRequest)
+	assert.Equal(t, "invalid_grant", resp2.Error)
+
+	// The approved user code can not be approved again
+	assert.Contains(t, approveDevice(ctx, t, st, code.UserCode, creds), "code is invalid or expired")
+}
+
+func TestOAuth_DevicePolling(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{deviceCodeGrant},
+		firstParty: true,
+	})
+	otherAppId, otherSecret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{deviceCodeGrant},
+		firstParty: true,
+	})
+	clientId := strconv.Itoa(int(appId))
+
+	code := authorizeDevice(ctx, t, st, url.Values{"client_id": {clientId}, "client_secret": {secret}}, http.StatusOK)
+
+	// The device code is bound to the app
+	resp := pollDevice(ctx, t, st, strconv.Itoa(int(otherAppId)), otherSecret, code.DeviceCode, http.StatusBadRequest)
+	assert.Equal(t, "invalid_grant", resp.Error)
+
+	resp = pollDevice(ctx, t, st, clientId, secret, code.DeviceCode, http.StatusBadRequest)
+	assert.Equal(t, "authorization_pending", resp.Error)
+
+	// The next poll comes before the interval passes
+	resp = pollDevice(ctx, t, st, clientId, secret, code.DeviceCode, http.StatusBadRequest)
+	assert.Equal(t, "slow_down", resp.Error)
+
+	resp = pollDevice(ctx, t, st, clientId, secret, gofakeit.UUID(), http.StatusBadRequest)
+	assert.Equal(t, "invalid_grant", resp.Error)
+}
+
+func TestOAuth_DeviceFailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code"},
+		firstParty: true,
+	})
+	deviceAppId, deviceSecret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{deviceCodeGrant},
+		firstParty: true,
+	})
+	deviceClientId := strconv.Itoa(int(deviceAppId))
+
+	// The app must be allowed the grant
+	code := authorizeDevice(
+		ctx, t, st, url.Values{"client_id": {strconv.Itoa(int(appId))}, "client_secret": {secret}}, http.StatusBadRequest,
+	)
+	assert.Equal(t, "unauthorized_client", code.Error)
+
+	code = authorizeDevice(ctx, t, st, url.Values{"client_id": {"unknown"}}, http.StatusUnauthorized)
+	assert.Equal(t, "invalid_client", code.Error)
+
+	// The app must present its secret
+	code = authorizeDevice(ctx, t, st, url.Values{"client_id": {deviceClientId}}, http.StatusUnauthorized)
+	assert.Equal(t, "invalid_client", code.Error)
+
+	code = authorizeDevice(
+		ctx, t, st, url.Values{"client_id": {deviceClientId}, "client_secret": {deviceSecret}}, http.StatusOK,
+	)
+
+	// The page does not tell whether the code is issued before the user signs in
+	resp, err := st.HTTPGet(ctx, devicePath+"?user_code=BCDF-GHJK")
+	require.NoError(t, err)
+	assert.NotContains(t, readBody(t, resp), "code is invalid or expired")
+
+	wrongCreds := &auth.Credentials{Email: creds.GetEmail(), Password: gofakeit.UUID()}
+
+	assert.Contains(t, reviewDevice(ctx, t, st, "BCDF-GHJK", creds, http.StatusOK), "code is invalid or expired")
+	assert.Contains(t, reviewDevice(ctx, t, st, "BCDF-GHJK", wrongCreds, http.StatusOK), "invalid email or password")
+	assert.Contains(t, reviewDevice(ctx, t, st, "", creds, http.StatusOK), "code, email and password are required")
+
+	// The form must be posted from the page
+	resp, err = st.HTTPPostForm(
+		ctx, devicePath, url.Values{
+			"user_code": {code.UserCode},
+			"email":     {creds.GetEmail()},
+			"password":  {creds.GetPassword()},
+		},
+	)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// The sign-in shows the device, but does not approve it
+	assert.Regexp(t, reviewCodeRe, reviewDevice(ctx, t, st, code.UserCode, creds, http.StatusOK))
+
+	// The device is approved only with the code of the review
+	form := deviceForm(ctx, t, st, code.UserCode)
+	form.Set("review_code", gofakeit.UUID())
+	assert.Contains(t, postDeviceForm(ctx, t, st, form, http.StatusOK), "code is invalid or expired")
+
+	// The code is refused after the failed sign-ins, even with the right credentials
+	for i := 0; i < 5; i++ {
+		assert.Contains(
+			t, reviewDevice(ctx, t, st, code.UserCode, wrongCreds, http.StatusOK), "invalid email or password",
+		)
+	}
+
+	body := reviewDevice(ctx, t, st, code.UserCode, creds, http.StatusTooManyRequests)
+	assert.Contains(t, body, "too many failed attempts")
+	assert.NotContains(t, body, `name="review_code"`)
+
+	// The failed attempts do not approve the device
+	pollResp := pollDevice(ctx, t, st, deviceClientId, deviceSecret, code.DeviceCode, http.StatusBadRequest)
+	assert.Equal(t, "authorization_pending", pollResp.Error)
+}
+
+func TestOAuth_DeviceConsent(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{grantTypes: []string{deviceCodeGrant}})
+	clientId := strconv.Itoa(int(appId))
+	form := url.Values{"client_id": {clientId}, "client_secret": {secret}, "scope": {"openid profile"}}
+
+	// The approval of the device of the third-party app is the consent to its scopes
+	code := authorizeDevice(ctx, t, st, form, http.StatusOK)
+	assert.Contains(t, approveDevice(ctx, t, st, code.UserCode, creds), "Device connected")
+
+	tokens := pollDevice(ctx, t, st, clientId, secret, code.DeviceCode, http.StatusOK)
+	assert.Equal(t, "openid profile", tokens.Scope)
+
+	login, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	grants, err := st.AuthClient.ListGrants(ctx, &auth.ListGrantsRequest{RefreshToken: login.GetRefreshToken()})
+	require.NoError(t, err)
+	require.Len(t, grants.GetGrants(), 1)
+	assert.Equal(t, appId, grants.GetGrants()[0].GetAppId())
+	assert.ElementsMatch(t, []string{"openid", "profile"}, grants.GetGrants()[0].GetScopes())
+
+	// The device does not get the tokens if the grant is revoked after the approval
+	code = authorizeDevice(ctx, t, st, form, http.StatusOK)
+	assert.Contains(t, approveDevice(ctx, t, st, code.UserCode, creds), "Device connected")
+
+	_, err = st.AuthClient.RevokeGrant(
+		ctx, &auth.RevokeGrantRequest{RefreshToken: login.GetRefreshToken(), AppId: appId},
+	)
+	require.NoError(t, err)
+
+	resp := pollDevice(ctx, t, st, clientId, secret, code.DeviceCode, http.StatusBadRequest)
+	assert.Equal(t, "access_denied", resp.Error)
+}
+
+// authorizeDevice posts the form to the device authorization endpoint and checks the status of the response.
+func authorizeDevice(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	form url.Values,
+	expectedStatus int,
+) deviceAuthorizationResponse {
+	t.Helper()
+
+	resp, err := st.HTTPPostForm(ctx, deviceAuthorizationPath, form)
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var code deviceAuthorizationResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&code))
+	require.Equal(t, expectedStatus, resp.StatusCode, code.Error)
+
+	return code
+}
+
+// deviceForm opens the verification page with the user code and returns the form with the anti-CSRF token.
+func deviceForm(ctx context.Context, t *testing.T, st *suite.Suite, userCode string) url.Values {
+	t.Helper()
+
+	resp, err := st.HTTPGet(ctx, devicePath+"?"+url.Values{"user_code": {userCode}}.Encode())
+	require.NoError(t, err)
+
+	body := readBody(t, resp)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	match := csrfTokenRe.FindStringSubmatch(body)
+	require.Len(t, match, 2, "anti-CSRF token is expected")
+
+	return url.Values{"user_code": {userCode}, "csrf_token": {match[1]}}
+}
+
+// postDeviceForm posts the form of the verification page and returns the page.
+func postDeviceForm(ctx context.Context, t *testing.T, st *suite.Suite, form url.Values, expectedStatus int) string {
+	t.Helper()
+
+	resp, err := st.HTTPPostForm(ctx, devicePath, form)
+	require.NoError(t, err)
+
+	body := readBody(t, resp)
+	require.Equal(t, expectedStatus, resp.StatusCode)
+
+	return body
+}
+
+// reviewDevice signs the user in on the verification page with the user code and returns the page,
+// it shows the app and the scopes the device asks for to approve them.
+func reviewDevice(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	userCode string,
+	creds *auth.Credentials,
+	expectedStatus int,
+) string {
+	t.Helper()
+
+	form := deviceForm(ctx, t, st, userCode)
+	form.Set("email", creds.GetEmail())
+	form.Set("password", creds.GetPassword())
+
+	return postDeviceForm(ctx, t, st, form, expectedStatus)
+}
+
+// approveDevice signs the user in on the verification page and approves the user code,
+// it returns the page of the approval or the one of the failed sign-in.
+func approveDevice(ctx context.Context, t *testing.T, st *suite.Suite, userCode string, creds *auth.Credentials) string {
+	t.Helper()
+
+	body := reviewDevice(ctx, t, st, userCode, creds, http.StatusOK)
+
+	match := reviewCodeRe.FindStringSubmatch(body)
+	if len(match) != 2 {
+		return body
+	}
+
+	form := deviceForm(ctx, t, st, userCode)
+	form.Set("review_code", match[1])
+
+	return postDeviceForm(ctx, t, st, form, http.StatusOK)
+}
+
+// pollDevice exchanges the device code for the tokens as the app on the device does.
+func pollDevice(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	clientId, secret, deviceCode string,
+	expectedStatus int,
+) tokenResponse {
+	t.Helper()
+
+	return exchangeCode(
+		ctx, t, st, url.Values{
+			"grant_type":    {deviceCodeGrant},
+			"client_id":     {clientId},
+			"client_secret": {secret},
+			"device_code":   {deviceCode},
+		}, expectedStatus,
+	)
+}
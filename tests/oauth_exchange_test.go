@@ -0,0 +1,439 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	myjwt "github.com/puregrade-group/sso/pkg/jwt"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tokenExchangeGrant = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType    = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+func TestOAuth_TokenExchangeImpersonation(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	// The internal service the gateway may narrow the tokens of the users to
+	serviceAppId, _ := createApp(ctx, t, st, testApp{})
+	serviceId := strconv.Itoa(int(serviceAppId))
+
+	gatewayId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{deviceCodeGrant, "refresh_token", tokenExchangeGrant},
+		permissionIds: []int32{
+			basePermissionId(ctx, t, st, "token", "impersonate"),
+			audiencePermissionId(ctx, t, st, serviceId),
+		},
+		firstParty: true,
+	})
+	gatewayClientId := strconv.Itoa(int(gatewayId))
+
+	// The user signs in to the gateway on the device
+	_, creds := register(ctx, t, st)
+	code := authorizeDevice(
+		ctx, t, st,
+		url.Values{"client_id": {gatewayClientId}, "client_secret": {secret}, "scope": {"openid profile"}},
+		http.StatusOK,
+	)
+	approveDevice(ctx, t, st, code.UserCode, creds)
+	userTokens := pollDevice(ctx, t, st, gatewayClientId, secret, code.DeviceCode, http.StatusOK)
+
+	// The gateway narrows the token of the user to the internal service
+	tokens := postToken(
+		ctx, t, st, gatewayClientId, secret, url.Values{
+			"grant_type":         {tokenExchangeGrant},
+			"subject_token":      {userTokens.AccessToken},
+			"subject_token_type": {accessTokenType},
+			"audience":           {serviceId},
+			"scope":              {"profile"},
+		}, http.StatusOK,
+	)
+	require.NotEmpty(t, tokens.AccessToken)
+	assert.Equal(t, accessTokenType, tokens.IssuedTokenType)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, "profile", tokens.Scope)
+	assert.LessOrEqual(t, tokens.ExpiresIn, int64(st.Cfg.AccessTokenTTL.Seconds()))
+	assert.Empty(t, tokens.RefreshToken)
+
+	claims := exchangedClaims(ctx, t, st, tokens.AccessToken)
+	assert.Equal(t, jwt.ClaimStrings{serviceId}, claims.Audience)
+	assert.Equal(t, gatewayId, claims.AppId)
+	// The gateway acts as the user, so there is no actor
+	assert.Nil(t, claims.Act)
+
+	userIntrospection := introspect(ctx, t, st, userTokens.AccessToken)
+
+	introspection := introspect(ctx, t, st, tokens.AccessToken)
+	assert.True(t, introspection.GetActive())
+	assert.Equal(t, userIntrospection.GetUserId(), introspection.GetUserId())
+	assert.Equal(t, userIntrospection.GetSessionId(), introspection.GetSessionId())
+
+	// The token is not intended for ACS
+	_, err := st.AppsClient.Get(ctx, &acs.GetAppRequest{RequesterToken: tokens.AccessToken, AppId: gatewayId})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The token belongs to the session of the user and is revoked with it
+	_, err = st.AuthClient.Logout(ctx, &auth.LogoutRequest{RefreshToken: userTokens.RefreshToken})
+	require.NoError(t, err)
+
+	introspection = introspect(ctx, t, st, tokens.AccessToken)
+	assert.False(t, introspection.GetActive())
+
+	resp := postToken(
+		ctx, t, st, gatewayClientId, secret, url.Values{
+			"grant_type":         {tokenExchangeGrant},
+			"subject_token":      {userTokens.AccessToken},
+			"subject_token_type": {accessTokenType},
+			"audience":           {serviceId},
+		}, http.StatusBadRequest,
+	)
+	assert.Equal(t, "invalid_request", resp.Error)
+}
+
+func TestOAuth_TokenExchangeDelegation(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	serviceAppId, _ := createApp(ctx, t, st, testApp{})
+	serviceId := strconv.Itoa(int(serviceAppId))
+
+	toolId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{tokenExchangeGrant, "password", "client_credentials"},
+		permissionIds: []int32{
+			basePermissionId(ctx, t, st, "token", "delegate"),
+			audiencePermissionId(ctx, t, st, serviceId),
+		},
+	})
+	toolClientId := strconv.Itoa(int(toolId))
+
+	// The user and the support engineers sign in to the tool
+	userId, creds := register(ctx, t, st)
+	userToken := loginToApp(ctx, t, st, creds, toolId)
+
+	supportId, supportCreds := register(ctx, t, st)
+	supportToken := loginToApp(ctx, t, st, supportCreds, toolId)
+
+	seniorId, seniorCreds := register(ctx, t, st)
+	seniorToken := loginToApp(ctx, t, st, seniorCreds, toolId)
+
+	// The support engineer acts on behalf of the user
+	tokens := postToken(
+		ctx, t, st, toolClientId, secret, url.Values{
+			"grant_type":         {tokenExchangeGrant},
+			"subject_token":      {userToken},
+			"subject_token_type": {accessTokenType},
+			"actor_token":        {supportToken},
+			"actor_token_type":   {accessTokenType},
+			"audience":           {serviceId},
+		}, http.StatusOK,
+	)
+	require.NotEmpty(t, tokens.AccessToken)
+
+	claims := exchangedClaims(ctx, t, st, tokens.AccessToken)
+	assert.Equal(t, strconv.FormatUint(userId, 10), claims.Subject)
+	require.NotNil(t, claims.Act)
+	assert.Equal(t, strconv.FormatUint(supportId, 10), claims.Act.Subject)
+	assert.Nil(t, claims.Act.Act)
+
+	// The delegated token is delegated further, the prior actor stays in the chain
+	tokens = postToken(
+		ctx, t, st, toolClientId, secret, url.Values{
+			"grant_type":         {tokenExchangeGrant},
+			"subject_token":      {tokens.AccessToken},
+			"subject_token_type": {accessTokenType},
+			"actor_token":        {seniorToken},
+			"actor_token_type":   {accessTokenType},
+			"audience":           {serviceId},
+		}, http.StatusOK,
+	)
+
+	claims = exchangedClaims(ctx, t, st, tokens.AccessToken)
+	assert.Equal(t, strconv.FormatUint(userId, 10), claims.Subject)
+	require.NotNil(t, claims.Act)
+	assert.Equal(t, strconv.FormatUint(seniorId, 10), claims.Act.Subject)
+	require.NotNil(t, claims.Act.Act)
+	assert.Equal(t, strconv.FormatUint(supportId, 10), claims.Act.Act.Subject)
+
+	// The tool itself acts on behalf of the user with its client credentials token
+	clientTokens := postToken(ctx, t, st, toolClientId, secret, clientCredentialsForm, http.StatusOK)
+
+	tokens = postToken(
+		ctx, t, st, toolClientId, secret, url.Values{
+			"grant_type":         {tokenExchangeGrant},
+			"subject_token":      {userToken},
+			"subject_token_type": {accessTokenType},
+			"actor_token":        {clientTokens.AccessToken},
+			"actor_token_type":   {accessTokenType},
+			"audience":           {serviceId},
+		}, http.StatusOK,
+	)
+
+	claims = exchangedClaims(ctx, t, st, tokens.AccessToken)
+	require.NotNil(t, claims.Act)
+	assert.Equal(t, toolClientId, claims.Act.Subject)
+}
+
+func TestOAuth_TokenExchangeFailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	serviceAppId, _ := createApp(ctx, t, st, testApp{})
+	serviceId := strconv.Itoa(int(serviceAppId))
+	otherServiceAppId, _ := createApp(ctx, t, st, testApp{})
+	deletedAppId, _ := createApp(ctx, t, st, testApp{})
+	deletedId := strconv.Itoa(int(deletedAppId))
+
+	grantTypes := []string{tokenExchangeGrant, "password"}
+	audienceIds := []int32{audiencePermissionId(ctx, t, st, serviceId), audiencePermissionId(ctx, t, st, deletedId)}
+
+	impersonateId, impersonateSecret := createApp(ctx, t, st, testApp{
+		grantTypes:    grantTypes,
+		permissionIds: append([]int32{basePermissionId(ctx, t, st, "token", "impersonate")}, audienceIds...),
+	})
+	delegateId, delegateSecret := createApp(ctx, t, st, testApp{
+		grantTypes:    grantTypes,
+		permissionIds: append([]int32{basePermissionId(ctx, t, st, "token", "delegate")}, audienceIds...),
+	})
+	otherId, otherSecret := createApp(ctx, t, st, testApp{
+		grantTypes:    []string{"client_credentials"},
+		permissionIds: []int32{basePermissionId(ctx, t, st, "token", "impersonate")},
+	})
+
+	_, err := st.AppsClient.Delete(
+		ctx, &acs.DeleteAppRequest{RequesterToken: adminToken(ctx, t, st), AppId: deletedAppId},
+	)
+	require.NoError(t, err)
+
+	_, creds := register(ctx, t, st)
+	_, actorCreds := register(ctx, t, st)
+
+	// The tokens the user signed in to the service without an app with
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	form := url.Values{
+		"grant_type":         {tokenExchangeGrant},
+		"subject_token":      {loginToApp(ctx, t, st, creds, impersonateId)},
+		"subject_token_type": {accessTokenType},
+		"audience":           {serviceId},
+	}
+	delegationForm := withParam(
+		withParam(
+			withParam(form, "subject_token", loginToApp(ctx, t, st, creds, delegateId)),
+			"actor_token", loginToApp(ctx, t, st, actorCreds, delegateId),
+		),
+		"actor_token_type", accessTokenType,
+	)
+
+	tests := []struct {
+		name           string
+		clientId       int32
+		secret         string
+		form           url.Values
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Public client",
+			clientId:       impersonateId,
+			form:           form,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name:           "Grant is not allowed",
+			clientId:       otherId,
+			secret:         otherSecret,
+			form:           form,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unauthorized_client",
+		},
+		{
+			name:           "Impersonation is not allowed",
+			clientId:       delegateId,
+			secret:         delegateSecret,
+			form:           form,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unauthorized_client",
+		},
+		{
+			name:           "Delegation is not allowed",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           delegationForm,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unauthorized_client",
+		},
+		{
+			name:           "Unknown audience",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "audience", gofakeit.UUID()),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_target",
+		},
+		{
+			name:           "Unknown app audience",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "audience", "2147483647"),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_target",
+		},
+		{
+			name:           "Audience is not allowed to the client",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "audience", strconv.Itoa(int(otherServiceAppId))),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_target",
+		},
+		{
+			name:           "Audience app is deleted",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "audience", deletedId),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_target",
+		},
+		{
+			name:           "Service audience is not allowed to the client",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "audience", st.Cfg.JWT.Audience[0]),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_target",
+		},
+		{
+			name:           "Scope is not granted to the subject token",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "scope", "openid"),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_scope",
+		},
+		{
+			name:           "Invalid subject token",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "subject_token", gofakeit.UUID()),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "Subject token is issued without an app",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "subject_token", loginResp.GetAccessToken()),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "Subject token is issued to another app",
+			clientId:       delegateId,
+			secret:         delegateSecret,
+			form:           withParam(delegationForm, "subject_token", form.Get("subject_token")),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "Actor token is issued to another app",
+			clientId:       delegateId,
+			secret:         delegateSecret,
+			form:           withParam(delegationForm, "actor_token", loginResp.GetAccessToken()),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "Invalid actor token",
+			clientId:       delegateId,
+			secret:         delegateSecret,
+			form:           withParam(delegationForm, "actor_token", gofakeit.UUID()),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "Without audience",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "audience", ""),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "Unsupported subject token type",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "subject_token_type", "urn:ietf:params:oauth:token-type:refresh_token"),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "Unsupported requested token type",
+			clientId:       impersonateId,
+			secret:         impersonateSecret,
+			form:           withParam(form, "requested_token_type", "urn:ietf:params:oauth:token-type:id_token"),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				resp := postToken(ctx, t, st, strconv.Itoa(int(tt.clientId)), tt.secret, tt.form, tt.expectedStatus)
+				assert.Equal(t, tt.expectedError, resp.Error)
+				assert.Empty(t, resp.AccessToken)
+			},
+		)
+	}
+}
+
+// loginToApp signs the user in to the app with the password grant and returns the access token issued to the app.
+func loginToApp(ctx context.Context, t *testing.T, st *suite.Suite, creds *auth.Credentials, appId int32) string {
+	t.Helper()
+
+	resp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds, AppId: appId})
+	require.NoError(t, err)
+
+	return resp.GetAccessToken()
+}
+
+// audiencePermissionId creates the permission to exchange the tokens for the audience and returns its id.
+func audiencePermissionId(ctx context.Context, t *testing.T, st *suite.Suite, audience string) int32 {
+	t.Helper()
+
+	resp, err := st.PermsClient.Create(
+		ctx, &acs.CreatePermissionRequest{
+			RequesterToken: adminToken(ctx, t, st),
+			Permission:     &acs.Permission{Resource: "audience", Action: audience},
+		},
+	)
+	require.NoError(t, err)
+
+	return resp.GetPermissionId()
+}
+
+// exchangedClaims verifies the exchanged token the way the resource servers do and returns its claims.
+func exchangedClaims(ctx context.Context, t *testing.T, st *suite.Suite, token string) *myjwt.DefaultClaims {
+	t.Helper()
+
+	claims := &myjwt.DefaultClaims{}
+
+	_, err := jwt.ParseWithClaims(token, claims, verificationKey(ctx, t, st))
+	require.NoError(t, err)
+
+	return claims
+}
@@ -0,0 +1,470 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	authorizePath = "/authorize"
+	tokenPath     = "/token"
+	redirectURI   = "https://app.example.com/callback"
+)
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	IDToken          string `json:"id_token"`
+	Scope            string `json:"scope"`
+	IssuedTokenType  string `json:"issued_token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func TestOAuth_AuthorizationCodeFlow(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code", "refresh_token"},
+		firstParty: true,
+	})
+	verifier, challenge := pkcePair(t)
+
+	params := authorizeParams(appId, challenge)
+
+	// The login form carries the parameters of the request
+	resp, err := st.HTTPGet(ctx, authorizePath+"?"+params.Encode())
+	require.NoError(t, err)
+
+	body := readBody(t, resp)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	assert.Contains(t, body, `name="code_challenge" value="`+challenge+`"`)
+	assert.Contains(t, body, `name="password"`)
+
+	code := authorize(ctx, t, st, params, creds)
+
+	// The app authenticates with client_secret in the form
+	tokens := exchangeCode(ctx, t, st, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(int(appId))},
+		"client_secret": {secret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}, http.StatusOK)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(st.Cfg.AccessTokenTTL.Seconds()), tokens.ExpiresIn)
+	require.NotEmpty(t, tokens.RefreshToken)
+
+	// The tokens are the ones Login issues for the app
+	info := introspect(ctx, t, st, tokens.AccessToken)
+	assert.True(t, info.GetActive())
+	assert.Equal(t, userId, info.GetUserId())
+	assert.Equal(t, appId, info.GetAppId())
+
+	// The code is single-use
+	failed := exchangeCode(ctx, t, st, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(int(appId))},
+		"client_secret": {secret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}, http.StatusBadRequest)
+	assert.Equal(t, "invalid_grant", failed.Error)
+
+	// or with HTTP Basic
+	resp, err = st.HTTPPostFormBasicAuth(
+		ctx, tokenPath, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {tokens.RefreshToken},
+		},
+		strconv.Itoa(int(appId)), secret,
+	)
+	require.NoError(t, err)
+
+	var refreshed tokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&refreshed))
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode, refreshed.ErrorDescription)
+	assert.NotEmpty(t, refreshed.AccessToken)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+}
+
+func TestOAuth_AuthorizeFailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	appId, _ := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code"},
+		firstParty: true,
+	})
+	passwordAppId, _ := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"password"},
+		firstParty: true,
+	})
+	_, challenge := pkcePair(t)
+
+	tests := []struct {
+		name          string
+		params        url.Values
+		expectedError string // empty for the error page that does not redirect
+	}{
+		{
+			name:   "Unknown client",
+			params: withParam(authorizeParams(appId, challenge), "client_id", "2147483647"),
+		},
+		{
+			name:   "Unregistered redirect uri",
+			params: withParam(authorizeParams(appId, challenge), "redirect_uri", "https://evil.example.com/callback"),
+		},
+		{
+			name:   "Without redirect uri",
+			params: withParam(authorizeParams(appId, challenge), "redirect_uri", ""),
+		},
+		{
+			name:          "Without code challenge",
+			params:        withParam(authorizeParams(appId, challenge), "code_challenge", ""),
+			expectedError: "invalid_request",
+		},
+		{
+			name:          "Plain code challenge method",
+			params:        withParam(authorizeParams(appId, challenge), "code_challenge_method", "plain"),
+			expectedError: "invalid_request",
+		},
+		{
+			name:          "Token response type",
+			params:        withParam(authorizeParams(appId, challenge), "response_type", "token"),
+			expectedError: "unsupported_response_type",
+		},
+		{
+			name:          "App without the grant",
+			params:        authorizeParams(passwordAppId, challenge),
+			expectedError: "unauthorized_client",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				resp, err := st.HTTPGet(ctx, authorizePath+"?"+tt.params.Encode())
+				require.NoError(t, err)
+				require.NoError(t, resp.Body.Close())
+
+				if tt.expectedError == "" {
+					assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+					assert.Empty(t, resp.Header.Get("Location"))
+
+					return
+				}
+
+				require.Equal(t, http.StatusFound, resp.StatusCode)
+
+				location, err := url.Parse(resp.Header.Get("Location"))
+				require.NoError(t, err)
+				assert.Equal(t, redirectURI, location.Scheme+"://"+location.Host+location.Path)
+				assert.Equal(t, tt.expectedError, location.Query().Get("error"))
+				assert.Equal(t, tt.params.Get("state"), location.Query().Get("state"))
+			},
+		)
+	}
+
+	// The wrong password shows the form again instead of redirecting
+	form := loginForm(ctx, t, st, authorizeParams(appId, challenge))
+	form.Set("email", creds.GetEmail())
+	form.Set("password", "wrong-password")
+
+	resp, err := st.HTTPPostForm(ctx, authorizePath, form)
+	require.NoError(t, err)
+
+	body := readBody(t, resp)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "invalid email or password")
+	assert.Contains(t, body, `name="csrf_token" value="`+form.Get("csrf_token")+`"`)
+
+	// The form posted from another site has no anti-CSRF token or a wrong one
+	form.Set("password", creds.GetPassword())
+
+	for _, token := range []string{"", gofakeit.UUID()} {
+		resp, err = st.HTTPPostForm(ctx, authorizePath, withParam(form, "csrf_token", token))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+	}
+}
+
+func TestOAuth_TokenFailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code"},
+		firstParty: true,
+	})
+	otherAppId, otherSecret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code"},
+		firstParty: true,
+	})
+	verifier, challenge := pkcePair(t)
+	otherVerifier, _ := pkcePair(t)
+
+	clientId := strconv.Itoa(int(appId))
+
+	tests := []struct {
+		name           string
+		form           func(code string) url.Values
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name: "Wrong code verifier",
+			form: func(code string) url.Values {
+				return exchangeForm(clientId, secret, code, redirectURI, otherVerifier)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+		},
+		{
+			name: "Another redirect uri",
+			form: func(code string) url.Values {
+				return exchangeForm(clientId, secret, code, "https://app.example.com/other", verifier)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+		},
+		{
+			name: "Another client",
+			form: func(code string) url.Values {
+				return exchangeForm(strconv.Itoa(int(otherAppId)), otherSecret, code, redirectURI, verifier)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+		},
+		{
+			name: "Wrong client secret",
+			form: func(code string) url.Values {
+				return withParam(exchangeForm(clientId, secret, code, redirectURI, verifier), "client_secret", gofakeit.UUID())
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name: "Without client secret",
+			form: func(code string) url.Values {
+				return withParam(exchangeForm(clientId, secret, code, redirectURI, verifier), "client_secret", "")
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name: "Without code verifier",
+			form: func(code string) url.Values {
+				return exchangeForm(clientId, secret, code, redirectURI, "")
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name: "Unsupported grant type",
+			form: func(code string) url.Values {
+				return withParam(exchangeForm(clientId, secret, code, redirectURI, verifier), "grant_type", "password")
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unsupported_grant_type",
+		},
+		{
+			name: "Unknown code",
+			form: func(string) url.Values {
+				return exchangeForm(clientId, secret, gofakeit.UUID(), redirectURI, verifier)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				code := authorize(ctx, t, st, authorizeParams(appId, challenge), creds)
+
+				resp := exchangeCode(ctx, t, st, tt.form(code), tt.expectedStatus)
+				assert.Equal(t, tt.expectedError, resp.Error)
+				assert.Empty(t, resp.AccessToken)
+			},
+		)
+	}
+}
+
+// pkcePair returns the code verifier and its S256 code challenge.
+func pkcePair(t *testing.T) (verifier, challenge string) {
+	t.Helper()
+
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+
+	verifier = base64.RawURLEncoding.EncodeToString(b)
+	sum := sha256.Sum256([]byte(verifier))
+
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizeParams(appId int32, challenge string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(int(appId))},
+		"redirect_uri":          {redirectURI},
+		"state":                 {gofakeit.UUID()},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func exchangeForm(clientId, secret, code, redirectURI, verifier string) url.Values {
+	return url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientId},
+		"client_secret": {secret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+}
+
+// withParam returns the copy of the parameters with the one replaced, the empty value removes it.
+func withParam(params url.Values, name, value string) url.Values {
+	res := make(url.Values, len(params))
+	for k, v := range params {
+		res[k] = v
+	}
+
+	if value == "" {
+		res.Del(name)
+	} else {
+		res.Set(name, value)
+	}
+
+	return res
+}
+
+// loginForm opens the login form and returns the parameters of the request with the anti-CSRF token of the form,
+// the cookie of the token is kept by the suite.
+func loginForm(ctx context.Context, t *testing.T, st *suite.Suite, params url.Values) url.Values {
+	t.Helper()
+
+	resp, err := st.HTTPGet(ctx, authorizePath+"?"+params.Encode())
+	require.NoError(t, err)
+
+	body := readBody(t, resp)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	match := csrfTokenRe.FindStringSubmatch(body)
+	require.Len(t, match, 2, "anti-CSRF token is expected")
+
+	return withParam(params, "csrf_token", match[1])
+}
+
+// authorize posts the login form and returns the code the user agent is redirected with.
+func authorize(ctx context.Context, t *testing.T, st *suite.Suite, params url.Values, creds *auth.Credentials) string {
+	t.Helper()
+
+	form := loginForm(ctx, t, st, params)
+	form.Set("email", creds.GetEmail())
+	form.Set("password", creds.GetPassword())
+
+	resp, err := st.HTTPPostForm(ctx, authorizePath, form)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, params.Get("state"), location.Query().Get("state"))
+
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	return code
+}
+
+// exchangeCode posts the form to the token endpoint and checks the status of the response.
+func exchangeCode(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	form url.Values,
+	expectedStatus int,
+) tokenResponse {
+	t.Helper()
+
+	resp, err := st.HTTPPostForm(ctx, tokenPath, form)
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	var tokens tokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	require.Equal(t, expectedStatus, resp.StatusCode, tokens.ErrorDescription)
+
+	return tokens
+}
+
+// postToken posts the form to the token endpoint with the client credentials sent with HTTP Basic
+// and checks the status of the response, the empty secret is sent as the client_id parameter alone.
+func postToken(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	clientId, secret string,
+	form url.Values,
+	expectedStatus int,
+) tokenResponse {
+	t.Helper()
+
+	if secret == "" {
+		return exchangeCode(ctx, t, st, withParam(form, "client_id", clientId), expectedStatus)
+	}
+
+	resp, err := st.HTTPPostFormBasicAuth(ctx, tokenPath, form, clientId, secret)
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var tokens tokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	require.Equal(t, expectedStatus, resp.StatusCode, tokens.ErrorDescription)
+
+	return tokens
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	myjwt "github.com/puregrade-group/sso/pkg/jwt"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	userInfoPath  = "/userinfo"
+)
+
+type userInfoResponse struct {
+	Sub        string `json:"sub"`
+	Email      string `json:"email"`
+	GivenName  string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+	Birthdate  string `json:"birthdate"`
+}
+
+func TestOIDC_Discovery(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	resp, err := st.HTTPGet(ctx, discoveryPath)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+
+	issuer := strings.TrimSuffix(st.Cfg.JWT.Issuer, "/")
+	assert.Equal(t, st.Cfg.JWT.Issuer, doc["issuer"])
+	assert.Equal(t, issuer+authorizePath, doc["authorization_endpoint"])
+	assert.Equal(t, issuer+tokenPath, doc["token_endpoint"])
+	assert.Equal(t, issuer+userInfoPath, doc["userinfo_endpoint"])
+	assert.Equal(t, issuer+jwksPath, doc["jwks_uri"])
+	assert.Equal(t, issuer+deviceAuthorizationPath, doc["device_authorization_endpoint"])
+	assert.ElementsMatch(t, []interface{}{"openid", "profile", "email"}, doc["scopes_supported"])
+	assert.Equal(t, []interface{}{"code"}, doc["response_types_supported"])
+	assert.Equal(t, []interface{}{"S256"}, doc["code_challenge_methods_supported"])
+	assert.Equal(t, []interface{}{st.Cfg.JWT.Algorithm}, doc["id_token_signing_alg_values_supported"])
+}
+
+func TestOIDC_IDTokenAndUserInfo(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	profile := &auth.BriefProfile{
+		FirstName:   gofakeit.FirstName(),
+		LastName:    gofakeit.LastName(),
+		DateOfBirth: timestamppb.New(time.Date(1990, time.March, 15, 0, 0, 0, 0, time.UTC)),
+	}
+	userId, creds := registerWithProfile(ctx, t, st, profile)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code", "refresh_token"},
+		firstParty: true,
+	})
+	verifier, challenge := pkcePair(t)
+	nonce := gofakeit.UUID()
+
+	// The unsupported scopes are ignored
+	params := authorizeParams(appId, challenge)
+	params.Set("scope", "openid email unknown profile")
+	params.Set("nonce", nonce)
+
+	code := authorize(ctx, t, st, params, creds)
+	tokens := exchangeCode(
+		ctx, t, st,
+		exchangeForm(strconv.Itoa(int(appId)), secret, code, redirectURI, verifier),
+		http.StatusOK,
+	)
+	assert.ElementsMatch(t, []string{"openid", "profile", "email"}, strings.Fields(tokens.Scope))
+	require.NotEmpty(t, tokens.IDToken)
+
+	var claims myjwt.IDClaims
+	_, err := jwt.ParseWithClaims(
+		tokens.IDToken, &claims, verificationKey(ctx, t, st),
+		jwt.WithIssuer(st.Cfg.JWT.Issuer),
+		jwt.WithAudience(strconv.Itoa(int(appId))),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatUint(userId, 10), claims.Subject)
+	assert.Equal(t, nonce, claims.Nonce)
+	assert.Equal(t, creds.GetEmail(), claims.Email)
+	assert.Equal(t, profile.GetFirstName(), claims.GivenName)
+	assert.Equal(t, profile.GetLastName(), claims.FamilyName)
+	assert.Equal(t, "1990-03-15", claims.Birthdate)
+
+	info := userInfo(ctx, t, st, tokens.AccessToken)
+	assert.Equal(t, userInfoResponse{
+		Sub:        strconv.FormatUint(userId, 10),
+		Email:      creds.GetEmail(),
+		GivenName:  profile.GetFirstName(),
+		FamilyName: profile.GetLastName(),
+		Birthdate:  "1990-03-15",
+	}, info)
+
+	// The scopes stay the same after the refresh
+	refreshed := exchangeCode(ctx, t, st, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {strconv.Itoa(int(appId))},
+		"client_secret": {secret},
+		"refresh_token": {tokens.RefreshToken},
+	}, http.StatusOK)
+
+	introspection := introspect(ctx, t, st, refreshed.AccessToken)
+	assert.ElementsMatch(t, []string{"openid", "profile", "email"}, introspection.GetScopes())
+
+	assert.Equal(t, info, userInfo(ctx, t, st, refreshed.AccessToken))
+}
+
+func TestOIDC_ScopesLimitClaims(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes: []string{"authorization_code"},
+		firstParty: true,
+	})
+	verifier, challenge := pkcePair(t)
+
+	params := authorizeParams(appId, challenge)
+	params.Set("scope", "openid")
+
+	code := authorize(ctx, t, st, params, creds)
+	tokens := exchangeCode(
+		ctx, t, st,
+		exchangeForm(strconv.Itoa(int(appId)), secret, code, redirectURI, verifier),
+		http.StatusOK,
+	)
+
+	var claims myjwt.IDClaims
+	_, err := jwt.ParseWithClaims(tokens.IDToken, &claims, verificationKey(ctx, t, st))
+	require.NoError(t, err)
+	assert.Empty(t, claims.Nonce)
+	assert.Empty(t, claims.Email)
+	assert.Empty(t, claims.GivenName)
+
+	assert.Equal(t, userInfoResponse{Sub: strconv.FormatUint(userId, 10)}, userInfo(ctx, t, st, tokens.AccessToken))
+
+	// Without the openid scope neither the ID token is issued nor the userinfo is available
+	verifier, challenge = pkcePair(t)
+	code = authorize(ctx, t, st, authorizeParams(appId, challenge), creds)
+	tokens = exchangeCode(
+		ctx, t, st,
+		exchangeForm(strconv.Itoa(int(appId)), secret, code, redirectURI, verifier),
+		http.StatusOK,
+	)
+	assert.Empty(t, tokens.IDToken)
+	assert.Empty(t, tokens.Scope)
+
+	resp, err := st.HTTPGetBearer(ctx, userInfoPath, tokens.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="insufficient_scope"`)
+}
+
+func TestOIDC_UserInfoFailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	resp, err := st.HTTPGet(ctx, userInfoPath)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+
+	resp, err = st.HTTPGetBearer(ctx, userInfoPath, gofakeit.UUID())
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+
+	// The tokens of Login are granted no scopes
+	resp, err = st.HTTPGetBearer(ctx, userInfoPath, loginResp.GetAccessToken())
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+// registerWithProfile registers new user with the profile and returns its id and credentials.
+func registerWithProfile(
+	ctx context.Context,
+	t *testing.T,
+	st *suite.Suite,
+	profile *auth.BriefProfile,
+) (uint64, *auth.Credentials) {
+	t.Helper()
+
+	creds := &auth.Credentials{
+		Email:    gofakeit.Email(),
+		Password: gofakeit.Password(true, true, true, true, false, passDefaultLen),
+	}
+
+	regResp, err := st.AuthClient.Register(ctx, &auth.RegisterRequest{Creds: creds, Profile: profile})
+	require.NoError(t, err)
+
+	return regResp.GetUserId(), creds
+}
+
+// userInfo requests the claims about the user with the access token.
+func userInfo(ctx context.Context, t *testing.T, st *suite.Suite, accessToken string) userInfoResponse {
+	t.Helper()
+
+	resp, err := st.HTTPGetBearer(ctx, userInfoPath, accessToken)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info userInfoResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+
+	return info
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/profile"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestProfile_CreateUpdateGetDelete_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	token := adminToken(ctx, t, st)
+
+	username := gofakeit.Username()
+	accountId := gofakeit.UUID()
+
+	respCreate, err := st.ProfilesClient.Create(
+		ctx, &profile.CreateProfileRequest{
+			RequesterToken: token,
+			Profile: &profile.Profile{
+				Username:         username,
+				AvatarHash:       gofakeit.UUID(),
+				IdentityProvider: profile.IdP_Steam,
+				AccountId:        accountId,
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.NotZero(t, respCreate.GetProfileId())
+
+	profileId := respCreate.GetProfileId()
+
+	// Only the avatar hash is updated, the username must stay the same
+	newAvatarHash := gofakeit.UUID()
+	_, err = st.ProfilesClient.Update(
+		ctx, &profile.UpdateProfileRequest{
+			Profile: &profile.Profile{
+				ProfileId:  profileId,
+				Username:   "ignored by the mask",
+				AvatarHash: newAvatarHash,
+			},
+			UpdateMask:     &fieldmaskpb.FieldMask{Paths: []string{"avatar_hash"}},
+			RequesterToken: token,
+		},
+	)
+	require.NoError(t, err)
+
+	respGet, err := st.ProfilesClient.Get(ctx, &profile.GetProfileRequest{ProfileId: profileId, RequesterToken: token})
+	require.NoError(t, err)
+
+	assert.Equal(t, profileId, respGet.GetProfile().GetProfileId())
+	assert.Equal(t, username, respGet.GetProfile().GetUsername())
+	assert.Equal(t, newAvatarHash, respGet.GetProfile().GetAvatarHash())
+	assert.Equal(t, profile.IdP_Steam, respGet.GetProfile().GetIdentityProvider())
+	assert.Equal(t, accountId, respGet.GetProfile().GetAccountId())
+
+	_, err = st.ProfilesClient.Delete(ctx, &profile.DeleteProfileRequest{ProfileId: profileId, RequesterToken: token})
+	require.NoError(t, err)
+
+	_, err = st.ProfilesClient.Get(ctx, &profile.GetProfileRequest{ProfileId: profileId, RequesterToken: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.ProfilesClient.Delete(ctx, &profile.DeleteProfileRequest{ProfileId: profileId, RequesterToken: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestProfile_Create_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	token := adminToken(ctx, t, st)
+
+	tests := []struct {
+		name        string
+		profile     *profile.Profile
+		expectedErr string
+	}{
+		{
+			name:        "Create with empty username",
+			profile:     &profile.Profile{IdentityProvider: profile.IdP_Internal},
+			expectedErr: "username is required",
+		},
+		{
+			name:        "Create with too short username",
+			profile:     &profile.Profile{Username: "ab"},
+			expectedErr: "username length is not within the allowed range",
+		},
+		{
+			name:        "Create with unknown identity provider",
+			profile:     &profile.Profile{Username: gofakeit.Username(), IdentityProvider: 42},
+			expectedErr: "identity_provider is unknown",
+		},
+		{
+			name:        "Create external profile without account id",
+			profile:     &profile.Profile{Username: gofakeit.Username(), IdentityProvider: profile.IdP_Google},
+			expectedErr: "account_id is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := st.ProfilesClient.Create(
+					ctx, &profile.CreateProfileRequest{Profile: tt.profile, RequesterToken: token},
+				)
+
+				require.Error(t, err)
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+				require.Contains(t, err.Error(), tt.expectedErr)
+			},
+		)
+	}
+}
+
+func TestProfile_Update_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	token := adminToken(ctx, t, st)
+
+	tests := []struct {
+		name         string
+		req          *profile.UpdateProfileRequest
+		expectedCode codes.Code
+		expectedErr  string
+	}{
+		{
+			name:         "Update with empty profile id",
+			req:          &profile.UpdateProfileRequest{Profile: &profile.Profile{Username: gofakeit.Username()}},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "profile_id is required",
+		},
+		{
+			name: "Update with nothing to update",
+			req: &profile.UpdateProfileRequest{
+				Profile: &profile.Profile{ProfileId: uint64(gofakeit.Uint32()) + 1},
+			},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "nothing to update",
+		},
+		{
+			name: "Update immutable field",
+			req: &profile.UpdateProfileRequest{
+				Profile:    &profile.Profile{ProfileId: uint64(gofakeit.Uint32()) + 1},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"account_id"}},
+			},
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "can not be updated",
+		},
+		{
+			name: "Update non-existent profile",
+			req: &profile.UpdateProfileRequest{
+				Profile: &profile.Profile{ProfileId: uint64(gofakeit.Uint32()) + 1, Username: gofakeit.Username()},
+			},
+			expectedCode: codes.NotFound,
+			expectedErr:  "profile not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tt.req.RequesterToken = token
+
+				_, err := st.ProfilesClient.Update(ctx, tt.req)
+
+				require.Error(t, err)
+				require.Equal(t, tt.expectedCode, status.Code(err))
+				require.Contains(t, err.Error(), tt.expectedErr)
+			},
+		)
+	}
+}
+
+func TestProfile_Requester(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respCreate, err := st.ProfilesClient.Create(
+		ctx, &profile.CreateProfileRequest{
+			Profile:        &profile.Profile{Username: gofakeit.Username()},
+			RequesterToken: adminToken(ctx, t, st),
+		},
+	)
+	require.NoError(t, err)
+
+	profileId := respCreate.GetProfileId()
+
+	// The service reads the profiles with the token of its app granted the permission
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes:    []string{"client_credentials"},
+		permissionIds: []int32{basePermissionId(ctx, t, st, "profile", "read")},
+	})
+	appTokens := postToken(ctx, t, st, strconv.Itoa(int(appId)), secret, clientCredentialsForm, http.StatusOK)
+
+	_, err = st.ProfilesClient.Get(
+		ctx, &profile.GetProfileRequest{ProfileId: profileId, RequesterToken: appTokens.AccessToken},
+	)
+	require.NoError(t, err)
+
+	_, creds := register(ctx, t, st)
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		requesterToken string
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "Without requester token",
+			requesterToken: "",
+			expectedCode:   codes.InvalidArgument,
+		},
+		{
+			name:           "Invalid requester token",
+			requesterToken: "not-a-token",
+			expectedCode:   codes.Unauthenticated,
+		},
+		{
+			name:           "User is not granted the permission",
+			requesterToken: loginResp.GetAccessToken(),
+			expectedCode:   codes.PermissionDenied,
+		},
+		{
+			name:           "App is not granted the permission",
+			requesterToken: appTokens.AccessToken,
+			expectedCode:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := st.ProfilesClient.Delete(
+					ctx, &profile.DeleteProfileRequest{ProfileId: profileId, RequesterToken: tt.requesterToken},
+				)
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+			},
+		)
+	}
+}
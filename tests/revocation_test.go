@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRevocation_LogoutDeniesAccessTokens(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	otherResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	// Every access token carries its id and the session it was issued for
+	info := introspect(ctx, t, st, loginResp.GetAccessToken())
+	require.True(t, info.GetActive())
+	assert.NotEmpty(t, info.GetTokenId())
+
+	sessionsResp, err := st.AuthClient.ListSessions(ctx, &auth.ListSessionsRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+	for _, session := range sessionsResp.GetSessions() {
+		if session.GetCurrent() {
+			assert.Equal(t, session.GetSessionId(), info.GetSessionId())
+		}
+	}
+
+	refreshResp, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Logout(ctx, &auth.LogoutRequest{RefreshToken: refreshResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	// Both access tokens of the session are denied before they expire
+	for _, token := range []string{loginResp.GetAccessToken(), refreshResp.GetAccessToken()} {
+		info = introspect(ctx, t, st, token)
+		assert.False(t, info.GetActive())
+
+		_, err = st.RolesClient.GetUserRoles(ctx, &acs.GetUserRolesRequest{RequesterToken: token, UserId: userId})
+		require.Error(t, err)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// The token of another session is still accepted
+	_, err = st.RolesClient.GetUserRoles(
+		ctx, &acs.GetUserRolesRequest{RequesterToken: otherResp.GetAccessToken(), UserId: userId},
+	)
+	require.NoError(t, err)
+}
+
+func TestRevocation_LogoutAll(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	tokens := make([]string, 0, 2)
+
+	var refreshToken string
+
+	for i := 0; i < 2; i++ {
+		loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+		require.NoError(t, err)
+
+		tokens = append(tokens, loginResp.GetAccessToken())
+		refreshToken = loginResp.GetRefreshToken()
+	}
+
+	_, err := st.AuthClient.LogoutAll(ctx, &auth.LogoutAllRequest{RefreshToken: refreshToken})
+	require.NoError(t, err)
+
+	for _, token := range tokens {
+		info := introspect(ctx, t, st, token)
+		assert.False(t, info.GetActive())
+	}
+}
+
+func TestRevocation_RevokeAccessToken(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	refreshResp, err := st.AuthClient.Refresh(ctx, &auth.RefreshRequest{RefreshToken: loginResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RevokeAccessToken(ctx, &auth.RevokeAccessTokenRequest{AccessToken: loginResp.GetAccessToken()})
+	require.NoError(t, err)
+
+	info := introspect(ctx, t, st, loginResp.GetAccessToken())
+	assert.False(t, info.GetActive())
+
+	// Only the single token is revoked, the session goes on
+	info = introspect(ctx, t, st, refreshResp.GetAccessToken())
+	assert.True(t, info.GetActive())
+
+	// There is nothing to revoke in the invalid token
+	_, err = st.AuthClient.RevokeAccessToken(ctx, &auth.RevokeAccessTokenRequest{AccessToken: "not.a.token"})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RevokeAccessToken(ctx, &auth.RevokeAccessTokenRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRevocation_Watch(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	userId, creds := register(ctx, t, st)
+
+	revokedResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	revokedInfo := introspect(ctx, t, st, revokedResp.GetAccessToken())
+
+	_, err = st.AuthClient.Logout(ctx, &auth.LogoutRequest{RefreshToken: revokedResp.GetRefreshToken()})
+	require.NoError(t, err)
+
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	loginInfo := introspect(ctx, t, st, loginResp.GetAccessToken())
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := st.AuthClient.WatchRevocations(
+		watchCtx, &auth.WatchRevocationsRequest{RequesterToken: adminToken(ctx, t, st)},
+	)
+	require.NoError(t, err)
+
+	// The revocations of other tests are streamed too, so the ones of this user are looked for
+	next := func() *auth.Revocation {
+		for {
+			r, err := stream.Recv()
+			require.NoError(t, err)
+
+			if r.GetUserId() == userId {
+				return r
+			}
+		}
+	}
+
+	// The active revocation is sent first
+	r := next()
+	assert.Equal(t, revokedInfo.GetSessionId(), r.GetSessionId())
+	assert.Empty(t, r.GetTokenId())
+	assert.True(t, r.GetExpiresAt().AsTime().After(r.GetRevokedAt().AsTime()))
+
+	// Then the new ones as they come
+	_, err = st.AuthClient.RevokeAccessToken(ctx, &auth.RevokeAccessTokenRequest{AccessToken: loginResp.GetAccessToken()})
+	require.NoError(t, err)
+
+	r = next()
+	assert.Equal(t, loginInfo.GetTokenId(), r.GetTokenId())
+	assert.Equal(t, loginInfo.GetSessionId(), r.GetSessionId())
+	assert.Equal(t, loginInfo.GetExpiresAt().GetSeconds(), r.GetExpiresAt().GetSeconds())
+}
+
+func TestRevocation_WatchRequester(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, creds := register(ctx, t, st)
+	loginResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	// The resource server watches the revocations with the token of its app granted the permission
+	appId, secret := createApp(ctx, t, st, testApp{
+		grantTypes:    []string{"client_credentials"},
+		permissionIds: []int32{basePermissionId(ctx, t, st, "revocation", "watch")},
+	})
+	appTokens := postToken(ctx, t, st, strconv.Itoa(int(appId)), secret, clientCredentialsForm, http.StatusOK)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := st.AuthClient.WatchRevocations(
+		watchCtx, &auth.WatchRevocationsRequest{RequesterToken: appTokens.AccessToken},
+	)
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RevokeAccessToken(ctx, &auth.RevokeAccessTokenRequest{AccessToken: loginResp.GetAccessToken()})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	userResp, err := st.AuthClient.Login(ctx, &auth.LoginRequest{Creds: creds})
+	require.NoError(t, err)
+
+	otherAppId, otherSecret := createApp(ctx, t, st, testApp{grantTypes: []string{"client_credentials"}})
+	otherTokens := postToken(
+		ctx, t, st, strconv.Itoa(int(otherAppId)), otherSecret, clientCredentialsForm, http.StatusOK,
+	)
+
+	tests := []struct {
+		name           string
+		requesterToken string
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "Without requester token",
+			requesterToken: "",
+			expectedCode:   codes.Unauthenticated,
+		},
+		{
+			name:           "Invalid requester token",
+			requesterToken: "not-a-token",
+			expectedCode:   codes.Unauthenticated,
+		},
+		{
+			name:           "Revoked requester token",
+			requesterToken: loginResp.GetAccessToken(),
+			expectedCode:   codes.Unauthenticated,
+		},
+		{
+			name:           "User is not granted the permission",
+			requesterToken: userResp.GetAccessToken(),
+			expectedCode:   codes.PermissionDenied,
+		},
+		{
+			name:           "App is not granted the permission",
+			requesterToken: otherTokens.AccessToken,
+			expectedCode:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				stream, err := st.AuthClient.WatchRevocations(
+					ctx, &auth.WatchRevocationsRequest{RequesterToken: tt.requesterToken},
+				)
+				require.NoError(t, err)
+
+				_, err = stream.Recv()
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+			},
+		)
+	}
+}
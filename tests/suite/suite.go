@@ -2,27 +2,68 @@ package suite
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
 	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/puregrade-group/sso/internal/app"
 	"github.com/puregrade-group/sso/internal/config"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/acs"
 	"github.com/puregrade-group/sso/pkg/protos/gen/go/auth"
+	"github.com/puregrade-group/sso/pkg/protos/gen/go/profile"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+const (
+	defaultConfigPath = "../config/local_tests.yaml"
+	memoryDriver      = "memory"
+)
+
+// inProcess makes sure that only one application is started per test binary.
+var inProcess sync.Once
+
+// application is the one started in-process with the memory storage
+var application *app.App
+
 type Suite struct {
 	*testing.T
-	Cfg        *config.Config
-	AuthClient auth.AuthClient
+	Cfg            *config.Config
+	AuthClient     auth.AuthClient
+	PermsClient    acs.PermissionsClient
+	RolesClient    acs.RolesClient
+	AppsClient     acs.AppsClient
+	ProfilesClient profile.ProfilesClient
+	// httpClient keeps the cookies of the suite like the browser of the user does
+	httpClient *http.Client
 }
 
+// New creates new test suite.
+//
+// The config is taken from CONFIG_PATH or from the default local_tests.yaml.
+// If the config selects the in-memory storage, the application is started
+// inside the test process, otherwise the suite connects to an already running one.
 func New(t *testing.T) (context.Context, *Suite) {
 	t.Helper()
 	t.Parallel()
 
-	cfg := config.MustLoadPath("../config/config.yaml")
+	cfg := config.MustLoadPath(configPath())
+
+	if cfg.Storage.Driver == memoryDriver {
+		inProcess.Do(func() { runApp(cfg) })
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.GRPC.Timeout)
 
@@ -37,16 +78,172 @@ func New(t *testing.T) (context.Context, *Suite) {
 		ctx,
 		grpcAddress(cfg),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
 	)
 	if err != nil {
 		t.Fatalf("grpc server connection failed: %v", err)
 	}
 
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookie jar creation failed: %v", err)
+	}
+
 	return ctx, &Suite{
-		T:          t,
-		Cfg:        cfg,
-		AuthClient: auth.NewAuthClient(cc),
+		T:              t,
+		Cfg:            cfg,
+		AuthClient:     auth.NewAuthClient(cc),
+		PermsClient:    acs.NewPermissionsClient(cc),
+		RolesClient:    acs.NewRolesClient(cc),
+		AppsClient:     acs.NewAppsClient(cc),
+		ProfilesClient: profile.NewProfilesClient(cc),
+		httpClient: &http.Client{
+			Jar: jar,
+			// The redirects are not followed, so the tests see where the server redirects to
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// GrantAdmin grants the admin role to the user the way the operators bootstrap the admins with --grant-admin.
+func (s *Suite) GrantAdmin(ctx context.Context, userId uint64) {
+	s.Helper()
+
+	if s.Cfg.Storage.Driver != memoryDriver {
+		app.MustGrantAdmin(
+			discardLogger(),
+			s.Cfg.Storage.Driver, s.Cfg.Storage.Path,
+			s.Cfg.Postgres.Host, s.Cfg.Postgres.Port, s.Cfg.Postgres.Database,
+			s.Cfg.Postgres.User, s.Cfg.Postgres.Password, s.Cfg.Postgres.SSLMode,
+			userId,
+		)
+
+		return
+	}
+
+	if err := application.GrantAdminRole(ctx, userId); err != nil {
+		s.Fatalf("admin role is not granted: %v", err)
+	}
+}
+
+func runApp(cfg *config.Config) {
+	log := discardLogger()
+
+	application = app.New(
+		log,
+		cfg.Storage.Driver, cfg.Storage.Path,
+		cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.Database,
+		cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.SSLMode,
+		cfg.GRPC.Host, cfg.GRPC.Port,
+		cfg.HTTP.Host, cfg.HTTP.Port,
+		cfg.JWT.Algorithm, cfg.JWT.KeyId, cfg.JWT.PrivateKeyPath, cfg.JWT.MasterKey,
+		cfg.JWT.RotationPeriod, cfg.JWT.PromotionDelay, cfg.JWT.RefreshInterval,
+		cfg.JWT.Issuer, cfg.JWT.Audience, cfg.JWT.Leeway,
+		cfg.App.NodeID,
+		cfg.AccessTokenTTL, []byte(cfg.AccessTokenSecret),
+		cfg.RefreshTokenTTL, cfg.RefreshTokenLength,
+		cfg.RefreshTokenReuseGrace,
+		cfg.RevocationRefreshInterval,
+		cfg.AuthorizationCodeTTL,
+		cfg.DeviceCodeTTL, cfg.DeviceCodeInterval,
+		cfg.DeviceAttemptsPerIP,
+		cfg.Notifier.Driver, cfg.Notifier.Path,
+		cfg.PasswordResetTTL,
+	)
+
+	go application.GRPCServer.MustRun()
+	go application.HTTPServer.MustRun()
+	go application.KeyRing.Run(context.Background())
+	go application.Denylist.Run(context.Background())
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// HTTPGet sends the GET request to the http server of the application.
+func (s *Suite) HTTPGet(ctx context.Context, path string) (*http.Response, error) {
+	return s.httpDo(ctx, http.MethodGet, path, nil, "")
+}
+
+// HTTPGetBearer sends the GET request with the bearer token to the http server of the application.
+func (s *Suite) HTTPGetBearer(ctx context.Context, path, token string) (*http.Response, error) {
+	return s.httpDo(ctx, http.MethodGet, path, http.Header{"Authorization": {"Bearer " + token}}, "")
+}
+
+// HTTPPostForm sends the POST request with the url-encoded form to the http server of the application.
+func (s *Suite) HTTPPostForm(ctx context.Context, path string, form url.Values) (*http.Response, error) {
+	return s.httpDo(ctx, http.MethodPost, path, formHeader(), form.Encode())
+}
+
+// HTTPPostFormBasicAuth sends the POST request with the url-encoded form and the HTTP Basic credentials
+// to the http server of the application.
+func (s *Suite) HTTPPostFormBasicAuth(ctx context.Context,
+	path string,
+	form url.Values,
+	username, password string,
+) (*http.Response, error) {
+	header := formHeader()
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+
+	return s.httpDo(ctx, http.MethodPost, path, header, form.Encode())
+}
+
+// HTTPPostFormBearer sends the POST request with the url-encoded form and the bearer token
+// to the http server of the application.
+func (s *Suite) HTTPPostFormBearer(ctx context.Context,
+	path string,
+	form url.Values,
+	token string,
+) (*http.Response, error) {
+	header := formHeader()
+	header.Set("Authorization", "Bearer "+token)
+
+	return s.httpDo(ctx, http.MethodPost, path, header, form.Encode())
+}
+
+func formHeader() http.Header {
+	return http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+}
+
+// httpDo sends the request to the http server of the application.
+// The in-process server may be not listening yet, so refused connections are retried until the context is done.
+func (s *Suite) httpDo(ctx context.Context, method, path string, header http.Header, body string) (*http.Response, error) {
+	const retryInterval = 50 * time.Millisecond
+
+	addr := "http://" + net.JoinHostPort(s.Cfg.HTTP.Host, strconv.Itoa(int(s.Cfg.HTTP.Port))) + path
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, method, addr, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		resp, err := s.httpClient.Do(req)
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			return resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func configPath() string {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path
 	}
+
+	return defaultConfigPath
 }
 
 func grpcAddress(cfg *config.Config) string {